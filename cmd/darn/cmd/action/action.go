@@ -7,11 +7,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/action"
 	"github.com/kusari-oss/darn/internal/core/config"
 	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/library"
 	"github.com/kusari-oss/darn/internal/core/schema"
 	. "github.com/kusari-oss/darn/internal/darn/resolver"
 	"github.com/spf13/cobra"
@@ -33,6 +35,7 @@ func NewActionCmd() *cobra.Command {
 	actionCmd.AddCommand(newActionValidateCmd())
 	actionCmd.AddCommand(newActionSchemaCmd())
 	actionCmd.AddCommand(newActionExampleCmd())
+	actionCmd.AddCommand(newActionMigrateCmd())
 
 	return actionCmd
 }
@@ -44,7 +47,12 @@ func newActionListCmd() *cobra.Command {
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "List available actions",
-		Long:  `List all available actions or filter by labels`,
+		Long: `List all available actions or filter by labels.
+
+Each action shows the library source it comes from. When several sources
+define an action with the same name, the first source in the search order
+wins and the others are listed as shadowed; use a qualified name such as
+corp/add-security-md to pick a specific one.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get the working directory
 			workingDir, err := os.Getwd()
@@ -79,13 +87,20 @@ func newActionListCmd() *cobra.Command {
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
 
-			// Get all available actions
-			actions, err := resolver.ListAvailableActions()
+			// Get all available actions, with every source that defines them
+			sources, err := resolver.ListActionSources()
 			if err != nil {
 				return fmt.Errorf("error listing actions: %w", err)
 			}
+			actions := make(map[string]action.Config, len(sources))
+			for name, definitions := range sources {
+				actions[name] = definitions[0].Config
+			}
 
 			// Parse label selectors
 			labelSelectors := make(map[string][]string)
@@ -110,11 +125,27 @@ func newActionListCmd() *cobra.Command {
 				actions = resolver.FilterActionsByLabels(actions, labelSelectors)
 			}
 
+			names := make([]string, 0, len(actions))
+			for name := range actions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
 			// Display the actions
 			fmt.Println("Available actions:")
 			fmt.Println("------------------")
-			for name, actionConfig := range actions {
+			for _, name := range names {
+				actionConfig := actions[name]
+				definitions := sources[name]
 				fmt.Printf("- %s: %s\n", name, actionConfig.Description)
+				fmt.Printf("  Source: %s (%s)\n", definitions[0].Namespace, definitions[0].Path)
+				if len(definitions) > 1 {
+					shadowed := make([]string, 0, len(definitions)-1)
+					for _, definition := range definitions[1:] {
+						shadowed = append(shadowed, definition.QualifiedName())
+					}
+					fmt.Printf("  Shadows: %s\n", strings.Join(shadowed, ", "))
+				}
 
 				// Display labels if they exist
 				if len(actionConfig.Labels) > 0 {
@@ -179,7 +210,10 @@ func newActionInfoCmd() *cobra.Command {
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
 
 			// Get action config
 			actionConfig, err := resolver.GetActionConfig(actionName)
@@ -203,6 +237,11 @@ func newActionInfoCmd() *cobra.Command {
 			// Type-specific information
 			switch actionConfig.Type {
 			case "file":
+				if actionConfig.TemplateDir != "" {
+					fmt.Printf("Template Dir: %s\n", actionConfig.TemplateDir)
+					fmt.Printf("Target Dir: %s\n", actionConfig.TargetDir)
+					break
+				}
 				fmt.Printf("Template Path: %s\n", actionConfig.TemplatePath)
 				fmt.Printf("Target Path: %s\n", actionConfig.TargetPath)
 				fmt.Printf("Create Directories: %t\n", actionConfig.CreateDirs)
@@ -364,7 +403,28 @@ Parameters can be provided in multiple ways:
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
+
+			// Only run signed actions when trusted keys are configured
+			verifier, err := cfg.Verifier()
+			if err != nil {
+				return err
+			}
+			if verifier != nil {
+				resolver.WithVerifier(verifier)
+			}
+
+			// Only run library actions that match the project's lock, if it has one
+			lock, err := library.LoadLock(workingDir, cfg.LibraryPath, config.ExpandPathWithTilde(config.DefaultCacheDir))
+			if err != nil {
+				return err
+			}
+			if lock != nil {
+				resolver.WithLock(lock)
+			}
 
 			// Get action config for validation
 			actionConfig, err := resolver.GetActionConfig(actionName)
@@ -399,20 +459,30 @@ Parameters can be provided in multiple ways:
 				fmt.Println(string(paramsJSON))
 			}
 
-			// Resolve and execute the action
-			if dryRunFlag {
-				fmt.Printf("Would execute action '%s' with parameters:\n", actionName)
-				paramsJSON, _ := json.MarshalIndent(params, "", "  ")
-				fmt.Println(string(paramsJSON))
-				return nil
-			}
-
 			// Resolve the action
 			act, err := resolver.ResolveAction(actionName)
 			if err != nil {
 				return fmt.Errorf("error resolving action: %w", err)
 			}
 
+			// In dry-run mode, show what the action would change
+			if dryRunFlag {
+				previewAction, ok := act.(action.PreviewAction)
+				if !ok {
+					fmt.Printf("Would execute action '%s' with parameters:\n", actionName)
+					paramsJSON, _ := json.MarshalIndent(params, "", "  ")
+					fmt.Println(string(paramsJSON))
+					return nil
+				}
+
+				preview, err := previewAction.Preview(params)
+				if err != nil {
+					return fmt.Errorf("error previewing action: %w", err)
+				}
+				fmt.Printf("Would execute action '%s':\n%s", actionName, preview)
+				return nil
+			}
+
 			// Execute the action
 			if verboseFlag {
 				fmt.Printf("Executing action: %s\n", actionName)
@@ -626,6 +696,8 @@ func createAction(actionName, actionType, description, command string, args []st
 
 	// Create action content
 	var actionContent strings.Builder
+	actionContent.WriteString(fmt.Sprintf("apiVersion: \"%s\"\n", action.APIVersion))
+	actionContent.WriteString(fmt.Sprintf("kind: \"%s\"\n", action.KindAction))
 	actionContent.WriteString(fmt.Sprintf("name: \"%s\"\n", actionName))
 	if description != "" {
 		actionContent.WriteString(fmt.Sprintf("description: \"%s\"\n", description))
@@ -652,13 +724,15 @@ func createAction(actionName, actionType, description, command string, args []st
 	}
 
 	// Add basic parameter schema
-	actionContent.WriteString("\nparameters:\n")
+	actionContent.WriteString("\nschema:\n")
+	actionContent.WriteString("  type: \"object\"\n")
 	actionContent.WriteString("  # Add your parameters here\n")
 	actionContent.WriteString("  # Example:\n")
-	actionContent.WriteString("  # - name: \"example_param\"\n")
-	actionContent.WriteString("  #   type: \"string\"\n")
-	actionContent.WriteString("  #   required: true\n")
-	actionContent.WriteString("  #   description: \"Example parameter\"\n")
+	actionContent.WriteString("  # required: [\"example_param\"]\n")
+	actionContent.WriteString("  # properties:\n")
+	actionContent.WriteString("  #   example_param:\n")
+	actionContent.WriteString("  #     type: \"string\"\n")
+	actionContent.WriteString("  #     description: \"Example parameter\"\n")
 
 	// Write action file
 	if err := os.WriteFile(actionFilePath, []byte(actionContent.String()), 0644); err != nil {
@@ -741,7 +815,10 @@ Examples:
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
 
 			// Get action config
 			actionConfig, err := resolver.GetActionConfig(actionName)
@@ -846,7 +923,10 @@ Examples:
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
 
 			// Get action config
 			actionConfig, err := resolver.GetActionConfig(actionName)
@@ -961,7 +1041,10 @@ Examples:
 				cfg.GlobalFirst,
 				cfg.ActionsDir,
 				cfg.LibraryPath,
-			)
+			).WithSources(cfg.LibrarySources)
+			if cfg.BuiltinDefaults() {
+				resolver.WithBuiltinDefaults()
+			}
 
 			// Get action config
 			actionConfig, err := resolver.GetActionConfig(actionName)
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/diff"
+	"github.com/spf13/cobra"
+)
+
+// newActionMigrateCmd creates a 'migrate' subcommand for converting action definitions
+func newActionMigrateCmd() *cobra.Command {
+	var dryRun bool
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [file-or-directory...]",
+		Short: "Convert action definitions to the current format",
+		Long: `Convert unversioned action definitions to apiVersion ` + action.APIVersion + `.
+
+Each file gains apiVersion and kind fields. A 'parameters' list is converted
+to the action's schema and defaults, and output parsers that file actions
+never read are removed. Comments and field order are kept. Fields that darn
+does not read at all cannot be migrated; the file is left alone and the
+field is reported so it can be fixed by hand.
+
+With no arguments, the actions of the configured library are migrated.
+
+Examples:
+  darn action migrate
+  darn action migrate ./my-library/actions --dry-run
+  darn action migrate .darn/actions/deploy.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths := args
+			if len(paths) == 0 {
+				cfg, err := config.LoadConfig("", "")
+				if err != nil {
+					return fmt.Errorf("error loading configuration: %w", err)
+				}
+				paths = []string{filepath.Join(cfg.LibraryPath, "actions")}
+			}
+
+			files, err := actionFiles(paths)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			failed := 0
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("error reading action file: %w", err)
+				}
+
+				migrated, changes, err := action.Migrate(data)
+				if err != nil {
+					fmt.Fprintf(out, "❌ %s: %v\n", file, err)
+					failed++
+					continue
+				}
+				if len(changes) == 0 {
+					fmt.Fprintf(out, "✅ %s: already %s\n", file, action.APIVersion)
+					continue
+				}
+
+				if dryRun {
+					fmt.Fprintf(out, "🔍 %s (dry run): %s\n", file, strings.Join(changes, "; "))
+					fmt.Fprint(out, diff.Unified(file, file, data, migrated))
+					continue
+				}
+				if err := os.WriteFile(file, migrated, 0644); err != nil {
+					return fmt.Errorf("error writing action file: %w", err)
+				}
+				fmt.Fprintf(out, "🔄 %s: %s\n", file, strings.Join(changes, "; "))
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d action files could not be migrated", failed, len(files))
+			}
+			return nil
+		},
+	}
+
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the changes as diffs without writing them")
+
+	return migrateCmd
+}
+
+// actionFiles returns the given action files and the .yaml files in the given directories
+func actionFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		path = config.ExpandPathWithTilde(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
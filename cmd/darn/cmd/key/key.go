@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package key
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/spf13/cobra"
+)
+
+// NewKeyCmd creates the key command
+func NewKeyCmd() *cobra.Command {
+	keyCmd := &cobra.Command{
+		Use:   "key",
+		Short: "Manage signing keys",
+		Long:  `Manage the ed25519 keys used to sign plans and libraries.`,
+	}
+
+	keyCmd.AddCommand(newKeyGenerateCmd())
+
+	return keyCmd
+}
+
+// newKeyGenerateCmd creates the 'generate' subcommand
+func newKeyGenerateCmd() *cobra.Command {
+	var keysDir string
+
+	generateCmd := &cobra.Command{
+		Use:   "generate [name]",
+		Short: "Generate a signing key pair",
+		Long: `Generate an ed25519 key pair in the keyring directory (default ~/.darn/keys).
+
+The private key is written to <name>.key and the public key to <name>.pub.
+Sign with the private key ('darn library sign', 'darnit plan sign') and add
+the public key to trusted_keys in the configuration of the machines that
+verify.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			privateKeyPath := filepath.Join(config.ExpandPathWithTilde(keysDir), args[0]+".key")
+
+			publicKey, err := signing.GenerateKey(privateKeyPath)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Generated key %s\n", signing.KeyID(publicKey))
+			fmt.Printf("Private key: %s\n", privateKeyPath)
+			fmt.Printf("Public key:  %s\n", signing.PublicKeyPath(privateKeyPath))
+			return nil
+		},
+	}
+
+	generateCmd.Flags().StringVar(&keysDir, "dir", config.DefaultKeysDir, "Keyring directory")
+
+	return generateCmd
+}
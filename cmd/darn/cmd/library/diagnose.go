@@ -8,6 +8,8 @@ import (
 	"os"
 
 	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/version"
 	"github.com/spf13/cobra"
 )
 
@@ -73,6 +75,43 @@ func runDiagnoseCommand(cmd *cobra.Command, args []string) error {
 		diagnostics["library_error"] = libraryErr.Error()
 	}
 
+	// Check the library manifest and the project's library lock
+	libraryPath := cfg.LibraryPath
+	if libraryInfo != nil {
+		libraryPath = libraryInfo.Path
+	}
+	manifest, manifestErr := library.LoadManifest(libraryPath)
+	if manifestErr == nil && manifest != nil {
+		manifestErr = manifest.CheckDarnVersion(version.Version)
+	}
+	manifestInfo := map[string]interface{}{"present": manifest != nil}
+	if manifest != nil {
+		manifestInfo["name"] = manifest.Name
+		manifestInfo["version"] = manifest.Version
+		manifestInfo["min_darn_version"] = manifest.MinDarnVersion
+	}
+	if manifestErr != nil {
+		manifestInfo["error"] = manifestErr.Error()
+	}
+	diagnostics["library_manifest"] = manifestInfo
+
+	var lock *library.Lock
+	var lockErr error
+	if cwd, err := os.Getwd(); err == nil {
+		lock, lockErr = library.LoadLock(cwd, libraryPath, config.ExpandPathWithTilde(config.DefaultCacheDir))
+		if lock != nil {
+			lockErr = lock.Verify()
+		}
+	}
+	lockInfo := map[string]interface{}{"present": lock != nil}
+	if lock != nil {
+		lockInfo["libraries"] = lock.Libraries
+	}
+	if lockErr != nil {
+		lockInfo["error"] = lockErr.Error()
+	}
+	diagnostics["library_lock"] = lockInfo
+
 	// Test command availability for common commands
 	commonCommands := []string{"sh", "bash", "cmd", "echo", "mkdir", "cp", "mv"}
 	commandTests := make(map[string]interface{})
@@ -134,6 +173,26 @@ func runDiagnoseCommand(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	// Library package and lock status
+	fmt.Println("Library Package:")
+	switch {
+	case manifestErr != nil:
+		fmt.Printf("  ✗ %s\n", manifestErr)
+	case manifest != nil:
+		fmt.Printf("  ✓ %s %s\n", manifest.Name, manifest.Version)
+	default:
+		fmt.Printf("  - No %s manifest\n", library.ManifestFile)
+	}
+	switch {
+	case lockErr != nil:
+		fmt.Printf("  ✗ %s\n", lockErr)
+	case lock != nil:
+		fmt.Printf("  ✓ Libraries match %s (%d locked)\n", library.LockFile, len(lock.Libraries))
+	default:
+		fmt.Printf("  - No %s in the current directory\n", library.LockFile)
+	}
+	fmt.Println()
+
 	// Configuration sources
 	fmt.Println("Configuration Sources:")
 	if cmdLineLib := diagnostics["cmdline_library_path"]; cmdLineLib != nil && cmdLineLib != "" {
@@ -178,6 +237,10 @@ func runDiagnoseCommand(cmd *cobra.Command, args []string) error {
 		fmt.Println("  2. Or set a custom library path with: darn library set-global <path>")
 	}
 	
+	if lockErr != nil {
+		fmt.Println("  Review the library changes, then update the lock with: darn library lock")
+	}
+
 	// Check for missing common shell commands
 	missingCommands := []string{}
 	for cmd, result := range commandTests {
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install <source>",
+	Short: "Install a library from git, an archive or a directory",
+	Long: `Install a library and register it as a library source.
+
+The source can be:
+  - a git repository: a URL ending in .git, git@host:repo, or any URL
+    prefixed with git+ (git+file:///srv/libs/corp). Append #<ref> or use
+    --ref to pick a branch, tag or commit.
+  - an archive: a .tar.gz, .tgz, .tar or .zip URL or path.
+  - a local directory.
+
+The library must have a library.yaml manifest. It is unpacked into
+~/.darn/cache/libraries/<name>/<version> and added to library_sources in the
+global configuration, under its name as namespace. Installing a new version
+of a library replaces the old version's source.
+
+--checksum verifies the archive's SHA-256, or for git and directory sources
+the library content hash recorded in library locks.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInstallCommand,
+}
+
+var (
+	installRef        string
+	installChecksum   string
+	installNamespace  string
+	installForce      bool
+	installNoRegister bool
+)
+
+func init() {
+	installCmd.Flags().StringVar(&installRef, "ref", "", "Git branch, tag or commit to install")
+	installCmd.Flags().StringVar(&installChecksum, "checksum", "", "Expected checksum (sha256:<hex>)")
+	installCmd.Flags().StringVar(&installNamespace, "namespace", "", "Namespace to register the library under (defaults to its name)")
+	installCmd.Flags().BoolVar(&installForce, "force", false, "Replace an installed copy of the same version")
+	installCmd.Flags().BoolVar(&installNoRegister, "no-register", false, "Install into the cache without adding a library source")
+}
+
+func runInstallCommand(cmd *cobra.Command, args []string) error {
+	installer := library.NewInstaller(config.ExpandPathWithTilde(config.DefaultCacheDir), version.Version)
+	result, err := installer.Install(args[0], library.InstallOptions{
+		Ref:      installRef,
+		Checksum: installChecksum,
+		Force:    installForce,
+	})
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if result.AlreadyInstalled {
+		fmt.Fprintf(out, "Library %s %s is already installed at %s\n", result.Manifest.Name, result.Manifest.Version, result.Path)
+	} else {
+		fmt.Fprintf(out, "Installed library %s %s at %s\n", result.Manifest.Name, result.Manifest.Version, result.Path)
+	}
+	fmt.Fprintf(out, "Content hash: %s\n", result.Hash)
+
+	if installNoRegister {
+		return nil
+	}
+
+	globalConfigPath, err := config.GlobalConfigFilePath()
+	if err != nil {
+		return fmt.Errorf("error determining global config path: %w", err)
+	}
+	cfg, err := config.LoadConfigFile(globalConfigPath)
+	if os.IsNotExist(err) {
+		cfg = config.NewDefaultConfig()
+	} else if err != nil {
+		return fmt.Errorf("error loading global config file '%s': %w", globalConfigPath, err)
+	}
+	if cfg.LibraryPath == "" {
+		cfg.LibraryPath = config.ExpandPathWithTilde(config.DefaultGlobalLibrary)
+	}
+
+	namespace := installNamespace
+	if namespace == "" {
+		namespace = result.Manifest.Name
+	}
+	cfg.AddLibrarySource(config.LibrarySource{Path: result.Path, Namespace: namespace})
+	cfg.UseGlobal = true
+
+	if err := config.SaveGlobalConfig(cfg); err != nil {
+		return fmt.Errorf("error saving global configuration: %w", err)
+	}
+	fmt.Fprintf(out, "Registered as library source '%s' in %s\n", namespace, globalConfigPath)
+	return nil
+}
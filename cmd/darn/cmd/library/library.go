@@ -39,6 +39,22 @@ func NewLibraryCommand() *cobra.Command {
 	// Add sync subcommand
 	libraryCmd.AddCommand(syncCmd)
 
+	// Add lock subcommand
+	libraryCmd.AddCommand(lockCmd)
+
+	// Add install subcommand
+	libraryCmd.AddCommand(installCmd)
+
+	// Add sign and verify subcommands
+	libraryCmd.AddCommand(signCmd)
+	libraryCmd.AddCommand(verifyCmd)
+
+	// Add lint subcommand
+	libraryCmd.AddCommand(lintCmd)
+
+	// Add test subcommand
+	libraryCmd.AddCommand(testCmd)
+
 	return libraryCmd
 }
 
@@ -223,6 +239,14 @@ func runInitCommand(cmd *cobra.Command, args []string) {
 		fmt.Printf("Error copying defaults to library at %s: %v\n", absTargetLibraryPath, err)
 		os.Exit(1)
 	}
+	if err := manager.CopyManifest(absTargetLibraryPath); err != nil {
+		fmt.Printf("Error writing library manifest to %s: %v\n", absTargetLibraryPath, err)
+		os.Exit(1)
+	}
+	if err := library.RecordInstalledFiles(absTargetLibraryPath); err != nil {
+		fmt.Printf("Error recording installed files in %s: %v\n", absTargetLibraryPath, err)
+		os.Exit(1)
+	}
 
 	fmt.Printf("\nLibrary content initialized successfully at: %s\n", absTargetLibraryPath)
 	fmt.Printf("  Templates directory: %s (subdirectory name: %s)\n", templatesDirPath, templatesDirName)
@@ -261,7 +285,10 @@ Examples:
     (Updates active global library, or ~/.darn/library, using ./my-library-source-files as source)
 
   darn library update --library-path /specific/darn-lib ./my-library-source-files
-    (Updates the library at /specific/darn-lib using ./my-library-source-files as source)`,
+    (Updates the library at /specific/darn-lib using ./my-library-source-files as source)
+
+  darn library update --prune --dry-run ./my-library-source-files
+    (Shows which files removed from the source would be deleted from the library)`,
 		Args: cobra.MaximumNArgs(1),
 		Run:  runUpdateCommand,
 	}
@@ -273,6 +300,7 @@ Examples:
 	updateCmd.Flags().BoolP("force", "f", false, "Force update even if files are identical.")
 	updateCmd.Flags().BoolP("dry-run", "d", false, "Show what would be updated without making changes.")
 	updateCmd.Flags().BoolP("verbose", "v", false, "Enable verbose output.")
+	updateCmd.Flags().Bool("prune", false, "Delete files that were removed from the source, unless they have local changes.")
 
 	return updateCmd
 }
@@ -288,6 +316,7 @@ func runUpdateCommand(cmd *cobra.Command, args []string) {
 	force, _ := cmd.Flags().GetBool("force")
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	prune, _ := cmd.Flags().GetBool("prune")
 
 	var finalLibraryPathToUpdate string
 
@@ -336,7 +365,7 @@ func runUpdateCommand(cmd *cobra.Command, args []string) {
 	finalLibraryPathToUpdate = absFinalLibraryPathToUpdate
 
 	// Create updater
-	updater := library.NewUpdater(finalLibraryPathToUpdate, sourceDir, force, dryRun, verbose)
+	updater := library.NewUpdater(finalLibraryPathToUpdate, sourceDir, force, dryRun, verbose).WithPrune(prune)
 
 	// Update library
 	if err := updater.UpdateLibrary(); err != nil {
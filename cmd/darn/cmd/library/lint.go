@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/darn/lint"
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [library-path]",
+	Short: "Check a library's actions, templates and mappings",
+	Long: `Check every action, template and mapping in a library (the active library
+if no path is given) and report problems darn would otherwise only hit when
+an action runs, or silently ignore:
+
+- Keys darn does not read in actions and mappings
+- Missing names, or names that do not match the action's file name
+- Fields an action's type requires, and fields it ignores
+- Templates that are missing or do not parse
+- Parameter schemas that are not valid JSON schemas
+- Output parsers that are invalid or never used
+- Mappings that refer to actions or mapping files that do not exist
+
+The command fails if any problem is an error; with --strict, warnings fail it too.
+
+Examples:
+  darn library lint
+  darn library lint ./my-library --format sarif > darn-lint.sarif`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLintCommand,
+}
+
+var (
+	lintFormat string
+	lintStrict bool
+)
+
+func init() {
+	lintCmd.Flags().StringVarP(&lintFormat, "format", "f", lint.FormatText, "Output format: text, json or sarif")
+	lintCmd.Flags().BoolVar(&lintStrict, "strict", false, "Fail on warnings as well as errors")
+}
+
+func runLintCommand(cmd *cobra.Command, args []string) error {
+	var libraryPath string
+	if len(args) > 0 {
+		libraryPath = config.ExpandPathWithTilde(args[0])
+	} else {
+		cfg, err := config.LoadConfig("", "")
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		libraryPath = cfg.LibraryPath
+	}
+
+	report, err := lint.LintLibrary(libraryPath)
+	if err != nil {
+		return fmt.Errorf("error linting library: %w", err)
+	}
+	if err := lint.WriteReport(cmd.OutOrStdout(), report, lintFormat); err != nil {
+		return err
+	}
+
+	if report.HasErrors() || (lintStrict && report.Count(lint.SeverityWarning) > 0) {
+		return fmt.Errorf("library %s has %d errors and %d warnings", report.Library, report.Count(lint.SeverityError), report.Count(lint.SeverityWarning))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock [library-path]",
+	Short: "Pin the project's library versions",
+	Long: `Resolve the library and its dependencies and write .darn/library.lock in the
+current directory, pinning each library's exact version and content hash.
+
+Every library needs a library.yaml manifest. Dependencies are looked up at
+their 'path', relative to the library that needs them, or in a sibling
+directory named after the dependency.
+
+The lock records names, versions and content hashes, not paths, so it can
+be committed. Wherever it is used, the project's library is the configured
+library, and dependencies are found where its manifests place them or in the
+library cache at <cache>/<name>/<version>.
+
+While the lock exists, darn and darnit refuse to load library actions if a
+locked library's version or content changes, and they also search the actions
+and templates of the locked dependencies. Re-run this command after
+updating a library.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLockCommand,
+}
+
+func runLockCommand(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	libraryPath := cfg.LibraryPath
+	if len(args) > 0 {
+		libraryPath = config.ExpandPathWithTilde(args[0])
+	}
+
+	lock, err := library.NewLock(libraryPath, version.Version, config.ExpandPathWithTilde(config.DefaultCacheDir))
+	if err != nil {
+		return fmt.Errorf("error locking library: %w", err)
+	}
+
+	projectDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("error getting working directory: %w", err)
+	}
+	lockPath, err := lock.Save(projectDir)
+	if err != nil {
+		return err
+	}
+
+	for _, locked := range lock.Libraries {
+		fmt.Fprintf(cmd.OutOrStdout(), "Locked %s %s (%s)\n", locked.Name, locked.Version, locked.Path)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Lock written to %s\n", lockPath)
+	return nil
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/spf13/cobra"
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign [library-path]",
+	Short: "Sign a library",
+	Long: `Sign every file in a library with an ed25519 private key.
+
+The signature is written to library.sig in the library root. When trusted_keys
+is set in the configuration, actions are only loaded from libraries signed by
+one of those keys, and any file added, changed or removed after signing is
+rejected. Re-run this command after editing the library.
+
+The library path defaults to the configured library, and the key to the
+signing_key configuration setting.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSignCommand,
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [library-path]",
+	Short: "Verify a library's signature",
+	Long: `Check a library's signature against the trusted keys in the configuration
+and report any file added, changed or removed since it was signed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerifyCommand,
+}
+
+var signKeyPath string
+
+func init() {
+	signCmd.Flags().StringVarP(&signKeyPath, "key", "k", "", "Private key to sign with (defaults to signing_key from the configuration)")
+}
+
+func runSignCommand(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	keyPath := signKeyPath
+	if keyPath == "" {
+		keyPath = cfg.SigningKey
+	}
+	if keyPath == "" {
+		return fmt.Errorf("no signing key: use --key or set signing_key in the configuration")
+	}
+
+	privateKey, err := signing.LoadPrivateKey(config.ExpandPathWithTilde(keyPath))
+	if err != nil {
+		return err
+	}
+
+	libraryPath := cfg.LibraryPath
+	if len(args) > 0 {
+		libraryPath = config.ExpandPathWithTilde(args[0])
+	}
+
+	signaturePath, err := signing.SignLibrary(libraryPath, privateKey)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed library %s\n", libraryPath)
+	fmt.Printf("Signature written to %s\n", signaturePath)
+	return nil
+}
+
+func runVerifyCommand(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	verifier, err := cfg.Verifier()
+	if err != nil {
+		return err
+	}
+	if verifier == nil {
+		return fmt.Errorf("no trusted keys configured: set trusted_keys in the configuration")
+	}
+
+	libraryPath := cfg.LibraryPath
+	if len(args) > 0 {
+		libraryPath = config.ExpandPathWithTilde(args[0])
+	}
+
+	if err := verifier.VerifyLibrary(libraryPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("Library %s has a valid signature\n", libraryPath)
+	return nil
+}
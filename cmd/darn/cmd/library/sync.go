@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
 	"github.com/kusari-oss/darn/internal/defaults"
 	"github.com/spf13/cobra"
 )
@@ -26,11 +27,15 @@ This is useful when:
 - Your library is missing some default components
 - You want to restore default functionality
 
+Files you have changed locally are kept or merged the same way as by
+'darn library update'; use --force to replace them with the defaults.
+
 Examples:
   darn library sync                    # Sync the currently configured global library
   darn library sync --library-path /custom/path  # Sync a specific library
   darn library sync --dry-run         # Show what would be synced without making changes
-  darn library sync --force           # Overwrite existing files even if they're newer`,
+  darn library sync --force           # Overwrite existing files, discarding local changes
+  darn library sync --prune --dry-run # Show which files no longer in the defaults would be deleted`,
 	RunE: runSyncCommand,
 }
 
@@ -40,14 +45,16 @@ var (
 	syncForce       bool
 	syncVerbose     bool
 	syncLocalOnly   bool
+	syncPrune       bool
 )
 
 func init() {
 	syncCmd.Flags().StringVar(&syncLibraryPath, "library-path", "", "Path to library to sync (defaults to global library)")
 	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would be synced without making changes")
-	syncCmd.Flags().BoolVar(&syncForce, "force", false, "Overwrite existing files even if they're newer")
+	syncCmd.Flags().BoolVar(&syncForce, "force", false, "Overwrite existing files, discarding local changes")
 	syncCmd.Flags().BoolVarP(&syncVerbose, "verbose", "v", false, "Verbose output")
 	syncCmd.Flags().BoolVar(&syncLocalOnly, "local-only", false, "Use only embedded defaults, don't attempt remote fetch")
+	syncCmd.Flags().BoolVar(&syncPrune, "prune", false, "Delete files that are no longer in the defaults, unless they have local changes")
 }
 
 func runSyncCommand(cmd *cobra.Command, args []string) error {
@@ -66,10 +73,6 @@ func runSyncCommand(cmd *cobra.Command, args []string) error {
 		}
 	} else {
 		// Use global library
-		if cfg.LibraryManager == nil {
-			cfg.LibraryManager = cfg.LibraryManager
-		}
-		
 		libraryInfo, err := cfg.GetLibraryInfo()
 		if err != nil {
 			return fmt.Errorf("failed to resolve global library path: %w", err)
@@ -99,49 +102,46 @@ func runSyncCommand(cmd *cobra.Command, args []string) error {
 	}
 	manager := defaults.NewManager(defaultsConfig)
 
-	// Define subdirectory paths
-	subdirs := map[string]string{
-		"actions":   filepath.Join(absTargetPath, "actions"),
-		"templates": filepath.Join(absTargetPath, "templates"),
-		"configs":   filepath.Join(absTargetPath, "configs"),
-		"mappings":  filepath.Join(absTargetPath, "mappings"),
+	// Fetch the defaults into a staging directory and update the library
+	// from it, so local changes are merged rather than overwritten
+	stagingDir, err := os.MkdirTemp("", "darn-sync-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	usedRemote, err := manager.CopyDefaults(
+		filepath.Join(stagingDir, "templates"),
+		filepath.Join(stagingDir, "actions"),
+		filepath.Join(stagingDir, "configs"),
+		filepath.Join(stagingDir, "mappings"),
+		!syncLocalOnly,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to sync defaults: %w", err)
 	}
 
 	if syncDryRun {
 		fmt.Printf("🔍 DRY RUN: Would sync to library at: %s\n", absTargetPath)
-		
-		// Check what exists
-		for name, path := range subdirs {
-			if _, err := os.Stat(path); os.IsNotExist(err) {
-				fmt.Printf("  Would create: %s/\n", name)
-			} else {
-				fmt.Printf("  Would update: %s/\n", name)
-			}
-		}
-		
-		fmt.Printf("\nTo perform the actual sync, run without --dry-run\n")
-		return nil
+	} else {
+		fmt.Printf("🔄 Syncing library at: %s\n", absTargetPath)
 	}
 
-	fmt.Printf("🔄 Syncing library at: %s\n", absTargetPath)
+	updater := library.NewUpdater(absTargetPath, stagingDir, syncForce, syncDryRun, syncVerbose).WithPrune(syncPrune)
+	if err := updater.UpdateLibrary(); err != nil {
+		return fmt.Errorf("failed to sync library: %w", err)
+	}
 
-	// Create target directories
-	for name, path := range subdirs {
-		if err := os.MkdirAll(path, 0755); err != nil {
-			return fmt.Errorf("failed to create %s directory: %w", name, err)
-		}
+	if syncDryRun {
+		fmt.Printf("\nTo perform the actual sync, run without --dry-run\n")
+		return nil
 	}
 
-	// Perform the sync using the defaults manager
-	usedRemote, err := manager.CopyDefaults(
-		subdirs["templates"],
-		subdirs["actions"],
-		subdirs["configs"],
-		subdirs["mappings"],
-		!syncLocalOnly,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to sync defaults: %w", err)
+	subdirs := map[string]string{
+		"actions":   filepath.Join(absTargetPath, "actions"),
+		"templates": filepath.Join(absTargetPath, "templates"),
+		"configs":   filepath.Join(absTargetPath, "configs"),
+		"mappings":  filepath.Join(absTargetPath, "mappings"),
 	}
 
 	// Success message
@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/darn/librarytest"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [library-path]",
+	Short: "Run a library's golden tests",
+	Long: `Run the test cases in a library's tests/ directory (the active library if
+no path is given). Each case is a directory with a test.yaml:
+
+  description: A missing security policy adds SECURITY.md
+  mapping: security-remediation.yaml  # In the library's mappings directory
+  report: report.json                 # Fixture report
+  params: params.json                 # Optional parameters file
+  parameters:                         # Optional inline parameters
+    project_name: demo
+  expected_plan: plan.golden.yaml     # Expected plan
+  expected_files: files               # Expected rendered files
+
+A case may name an action instead of a mapping and report, to test the
+files one action renders. File actions are rendered into a temporary
+directory and every file they write is compared with expected_files. Other
+actions, such as commands, are never run.
+
+With --update, the expected plans and files are rewritten from the results.
+
+Examples:
+  darn library test
+  darn library test ./my-library --run security -v
+  darn library test ./my-library --update`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTestCommand,
+}
+
+var (
+	testUpdate  bool
+	testRun     string
+	testVerbose bool
+)
+
+func init() {
+	testCmd.Flags().BoolVar(&testUpdate, "update", false, "Rewrite the expected plans and files from the results")
+	testCmd.Flags().StringVar(&testRun, "run", "", "Only run cases whose names match this regular expression")
+	testCmd.Flags().BoolVarP(&testVerbose, "verbose", "v", false, "List the steps each case did not run")
+}
+
+func runTestCommand(cmd *cobra.Command, args []string) error {
+	var libraryPath string
+	if len(args) > 0 {
+		libraryPath = config.ExpandPathWithTilde(args[0])
+	} else {
+		cfg, err := config.LoadConfig("", "")
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		libraryPath = cfg.LibraryPath
+	}
+
+	var filter *regexp.Regexp
+	if testRun != "" {
+		var err error
+		if filter, err = regexp.Compile(testRun); err != nil {
+			return fmt.Errorf("invalid --run pattern: %w", err)
+		}
+	}
+
+	cases, err := librarytest.Discover(libraryPath, filter)
+	if err != nil {
+		return err
+	}
+	if len(cases) == 0 {
+		fmt.Printf("No test cases found in %s\n", libraryPath)
+		return nil
+	}
+
+	counts := make(map[librarytest.Status]int)
+	for _, testCase := range cases {
+		result := librarytest.Run(libraryPath, testCase, testUpdate)
+		counts[result.Status]++
+
+		switch result.Status {
+		case librarytest.StatusPassed:
+			fmt.Printf("✅ %s\n", testCase.Name)
+		case librarytest.StatusUpdated:
+			fmt.Printf("🔄 %s (updated)\n", testCase.Name)
+		case librarytest.StatusFailed:
+			fmt.Printf("❌ %s\n", testCase.Name)
+			for _, failure := range result.Failures {
+				fmt.Printf("%s\n", failure)
+			}
+		case librarytest.StatusError:
+			fmt.Printf("❌ %s: %v\n", testCase.Name, result.Err)
+		}
+		if testVerbose {
+			for _, skipped := range result.Skipped {
+				fmt.Printf("   skipped %s\n", skipped)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d failed, %d errors", counts[librarytest.StatusPassed], counts[librarytest.StatusFailed], counts[librarytest.StatusError])
+	if testUpdate {
+		fmt.Printf(", %d updated", counts[librarytest.StatusUpdated])
+	}
+	fmt.Println()
+
+	if failed := counts[librarytest.StatusFailed] + counts[librarytest.StatusError]; failed > 0 {
+		return fmt.Errorf("%d of %d test cases failed", failed, len(cases))
+	}
+	return nil
+}
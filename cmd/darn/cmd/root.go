@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 
 	"github.com/kusari-oss/darn/cmd/darn/cmd/action"
+	"github.com/kusari-oss/darn/cmd/darn/cmd/key"
 	"github.com/kusari-oss/darn/cmd/darn/cmd/library"
+	"github.com/kusari-oss/darn/cmd/darn/cmd/schema"
 	"github.com/kusari-oss/darn/internal/core/config"
 	"github.com/kusari-oss/darn/internal/version"
 
@@ -80,6 +82,8 @@ func Execute() error {
 func init() {
 	rootCmd.AddCommand(library.NewLibraryCommand())
 	rootCmd.AddCommand(action.NewActionCmd())
+	rootCmd.AddCommand(key.NewKeyCmd())
+	rootCmd.AddCommand(schema.NewSchemaCmd())
 
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is .darn/config.yaml)")
 	rootCmd.PersistentFlags().StringVar(&projectDir, "project-dir", "", "project directory (default is current directory)")
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/kusari-oss/darn/internal/darn/schemas"
+	"github.com/spf13/cobra"
+)
+
+// NewSchemaCmd creates the schema command
+func NewSchemaCmd() *cobra.Command {
+	schemaCmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Export JSON schemas for darn files",
+		Long:  `Export JSON schemas for action, mapping, configuration and plan files, for editors to validate and complete them.`,
+		// Schemas do not depend on the configuration, and loading it prints
+		// to stdout, where the schema goes
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	schemaCmd.AddCommand(newSchemaExportCmd())
+
+	return schemaCmd
+}
+
+// newSchemaExportCmd creates the 'export' subcommand
+func newSchemaExportCmd() *cobra.Command {
+	var outputDir string
+
+	exportCmd := &cobra.Command{
+		Use:   "export [action|mapping|config|plan...]",
+		Short: "Export JSON schemas",
+		Long: `Export the JSON schemas of darn's file formats:
+
+  action   Action definitions (actions/*.yaml)
+  mapping  Mapping rules (mappings/*.yaml)
+  config   Configuration (.darn/config.yaml)
+  plan     Remediation plans
+
+A single schema is written to stdout. With --output-dir, the named schemas
+(default: all) are written to <name>.schema.json in the directory, followed by
+settings that point the YAML language server at them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			selected := schemas.All
+			if len(args) > 0 {
+				selected = make([]schemas.Schema, 0, len(args))
+				for _, name := range args {
+					s, err := schemas.Lookup(name)
+					if err != nil {
+						return err
+					}
+					selected = append(selected, s)
+				}
+			}
+
+			if outputDir == "" {
+				if len(selected) != 1 {
+					return fmt.Errorf("name one schema to write to stdout, or use --output-dir")
+				}
+				data, err := marshalSchema(selected[0])
+				if err != nil {
+					return err
+				}
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return fmt.Errorf("error creating output directory: %w", err)
+			}
+			settings := make(map[string][]string)
+			for _, s := range selected {
+				data, err := marshalSchema(s)
+				if err != nil {
+					return err
+				}
+				path := filepath.Join(outputDir, s.FileName())
+				if err := os.WriteFile(path, data, 0644); err != nil {
+					return fmt.Errorf("error writing schema: %w", err)
+				}
+				fmt.Printf("Wrote %s\n", path)
+
+				absPath, err := filepath.Abs(path)
+				if err != nil {
+					return fmt.Errorf("error resolving schema path: %w", err)
+				}
+				settings[absPath] = s.FileMatch
+			}
+
+			data, err := json.MarshalIndent(map[string]interface{}{"yaml.schemas": settings}, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error marshaling editor settings: %w", err)
+			}
+			fmt.Printf("\nEditor settings for the YAML language server (e.g. .vscode/settings.json):\n%s\n", data)
+			fmt.Println("A single file can also name its schema in a first-line comment:")
+			fmt.Println("  # yaml-language-server: $schema=<path to schema>")
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&outputDir, "output-dir", "o", "", "Directory to write <name>.schema.json files to")
+
+	return exportCmd
+}
+
+// marshalSchema generates a schema as indented JSON
+func marshalSchema(s schemas.Schema) ([]byte, error) {
+	generated, err := s.Generate()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(generated, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling %s schema: %w", s.Name, err)
+	}
+	return append(data, '\n'), nil
+}
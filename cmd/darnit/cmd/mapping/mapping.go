@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/darnit"
 	"github.com/kusari-oss/darn/internal/darnit/plan"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +26,7 @@ func GetMappingCmd() *cobra.Command {
 	mappingCmd.AddCommand(getListCmd())
 	mappingCmd.AddCommand(getValidateCmd())
 	mappingCmd.AddCommand(getAddCmd())
+	mappingCmd.AddCommand(getRenderCmd())
 
 	return mappingCmd
 }
@@ -69,7 +72,7 @@ func getListCmd() *cobra.Command {
 				if !file.IsDir() && (filepath.Ext(file.Name()) == ".yaml" || filepath.Ext(file.Name()) == ".yml") {
 					// Load mapping to show description
 					mappingPath := filepath.Join(mappingsDir, file.Name())
-					mapping, err := plan.LoadMappingConfig(mappingPath)
+					mapping, err := plan.ResolveMappingConfig(mappingPath, mappingsDir, builtinMappings())
 					if err != nil {
 						fmt.Printf("  %s (Error: %v)\n", file.Name(), err)
 						continue
@@ -88,6 +91,8 @@ func getListCmd() *cobra.Command {
 }
 
 func getValidateCmd() *cobra.Command {
+	var mappingsDir string
+
 	validateCmd := &cobra.Command{
 		Use:   "validate [mapping-file]",
 		Short: "Validate a mapping file",
@@ -95,8 +100,8 @@ func getValidateCmd() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			mappingPath := args[0]
 
-			// Load the mapping file
-			mapping, err := plan.LoadMappingConfig(mappingPath)
+			// Load the mapping file, including its imports
+			mapping, err := plan.ResolveMappingConfig(mappingPath, libraryMappingsDir(mappingsDir), builtinMappings())
 			if err != nil {
 				fmt.Printf("Error loading mapping file: %v\n", err)
 				os.Exit(1)
@@ -109,9 +114,81 @@ func getValidateCmd() *cobra.Command {
 		},
 	}
 
+	validateCmd.Flags().StringVarP(&mappingsDir, "mappings-dir", "d", "", "Directory to search for imported mappings (defaults to global library mappings)")
+
 	return validateCmd
 }
 
+func getRenderCmd() *cobra.Command {
+	var mappingsDir string
+	var outputFile string
+
+	renderCmd := &cobra.Command{
+		Use:   "render [mapping-file]",
+		Short: "Show a mapping file with its imports and overrides applied",
+		Long: `Resolve the imports and overrides of a mapping file and print the resulting rules.
+
+Examples:
+  # Print the merged mapping as YAML
+  darnit mapping render team-mappings.yaml
+
+  # Save the merged mapping to a file
+  darnit mapping render team-mappings.yaml -o rendered.yaml`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			mapping, err := plan.ResolveMappingConfig(args[0], libraryMappingsDir(mappingsDir), builtinMappings())
+			if err != nil {
+				fmt.Printf("Error resolving mapping file: %v\n", err)
+				os.Exit(1)
+			}
+
+			if outputFile != "" {
+				if err := format.WriteFile(outputFile, mapping); err != nil {
+					fmt.Printf("Error writing output file: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Rendered mapping saved to %s\n", outputFile)
+				return
+			}
+
+			output, err := format.FormatData(mapping, true)
+			if err != nil {
+				fmt.Printf("Error formatting mapping: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(output)
+		},
+	}
+
+	renderCmd.Flags().StringVarP(&mappingsDir, "mappings-dir", "d", "", "Directory to search for imported mappings (defaults to global library mappings)")
+	renderCmd.Flags().StringVarP(&outputFile, "output", "o", "", "File to write the rendered mapping to (defaults to stdout)")
+
+	return renderCmd
+}
+
+// libraryMappingsDir returns dir, or the global library mappings directory when dir is empty
+func libraryMappingsDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cfg.LibraryPath, "mappings")
+}
+
+// builtinMappings reports whether mappings fall back to the defaults embedded
+// in darn, which is when the action resolver would load the embedded actions
+func builtinMappings() bool {
+	_, resolver, err := darnit.CreateActionResolver("")
+	if err != nil {
+		fmt.Printf("Error creating action resolver: %v\n", err)
+		os.Exit(1)
+	}
+	return resolver.UsesBuiltinDefaults()
+}
+
 func getAddCmd() *cobra.Command {
 	var interactive bool
 	var condition string
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/spf13/cobra"
+)
+
+func getApproveCmd() *cobra.Command {
+	approveCmd := &cobra.Command{
+		Use:   "approve [plan-file]",
+		Short: "Approve a remediation plan",
+		Long: `Record a signed approval of a remediation plan in the plan file.
+
+The approval stores the approver, the time and a hash of the plan's steps,
+signed with the approver's ed25519 key. 'darnit plan execute --require-approval N'
+only counts approvals that are signed by one of the trusted_keys and whose
+hash matches the steps, so changing the plan after approval invalidates it.
+
+Approving rewrites the plan file, which invalidates a detached signature
+written by 'darnit plan sign'. Record every approval first, then sign.
+
+The approver defaults to the git user.email setting, then to the current user.
+The key defaults to the signing_key configuration setting.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			planFile := args[0]
+			approver, _ := cmd.Flags().GetString("approver")
+			comment, _ := cmd.Flags().GetString("comment")
+			keyPath, _ := cmd.Flags().GetString("key")
+
+			if approver == "" {
+				approver = defaultApprover()
+			}
+			if approver == "" {
+				fmt.Println("Error: could not determine the approver; use --approver")
+				os.Exit(1)
+			}
+
+			cfg, err := config.LoadConfig("", "")
+			if err != nil {
+				fmt.Printf("Error loading configuration: %v\n", err)
+				os.Exit(1)
+			}
+			if keyPath == "" {
+				keyPath = cfg.SigningKey
+			}
+			if keyPath == "" {
+				fmt.Println("Error: no signing key; use --key or set signing_key in the configuration")
+				os.Exit(1)
+			}
+
+			privateKey, err := signing.LoadPrivateKey(config.ExpandPathWithTilde(keyPath))
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			plan, err := darnit.LoadPlanFile(planFile)
+			if err != nil {
+				fmt.Printf("Error loading plan: %v\n", err)
+				os.Exit(1)
+			}
+
+			approval, err := darnit.ApprovePlan(plan, approver, comment, time.Now(), privateKey)
+			if err != nil {
+				fmt.Printf("Error approving plan: %v\n", err)
+				os.Exit(1)
+			}
+
+			if err := darnit.SavePlanToFile(plan, planFile); err != nil {
+				fmt.Printf("Error saving plan: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Plan approved by %s at %s\n", approval.Approver, approval.ApprovedAt)
+			fmt.Printf("Steps hash: %s\n", approval.StepsHash)
+			fmt.Printf("Signed with key %s\n", approval.KeyID)
+
+			if _, err := os.Stat(planFile + signing.SignatureSuffix); err == nil {
+				fmt.Printf("Note: %s%s no longer matches the plan; sign it again once all approvals are recorded\n", planFile, signing.SignatureSuffix)
+			}
+		},
+	}
+
+	approveCmd.Flags().String("approver", "", "Approver identity (defaults to git user.email or the current user)")
+	approveCmd.Flags().StringP("comment", "c", "", "Comment to record with the approval")
+	approveCmd.Flags().StringP("key", "k", "", "Private key to sign the approval with (defaults to signing_key from the configuration)")
+
+	return approveCmd
+}
+
+// defaultApprover returns the git user.email setting, or the current user's name
+func defaultApprover() string {
+	if output, err := exec.Command("git", "config", "--get", "user.email").Output(); err == nil {
+		if email := strings.TrimSpace(string(output)); email != "" {
+			return email
+		}
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/darnit"
+	. "github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/spf13/cobra"
+)
+
+func getBatchCmd() *cobra.Command {
+	batchCmd := &cobra.Command{
+		Use:   "batch [manifest-file]",
+		Short: "Generate remediation plans for many repositories",
+		Long: `Generate one remediation plan per repository listed in a batch manifest.
+
+The manifest is a YAML or JSON file of the form:
+
+  mappings: mappings.yaml        # default mapping file (optional)
+  params:                        # parameters shared by every repository (optional)
+    security_email: security@example.com
+  repositories:
+    - name: api                  # optional, defaults to the repo directory name
+      repo: ../api
+      report: reports/api.json
+      params:
+        project_name: API
+
+Plans are generated concurrently and written to the output directory along
+with an index.json describing every generated plan. Default parameters are
+read from each repository's params.yaml or .darn/params.yaml (falling back to
+~/.darn/params.yaml), not from the current directory.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			manifestFile := args[0]
+			outputDir, _ := cmd.Flags().GetString("output-dir")
+			workers, _ := cmd.Flags().GetInt("workers")
+			mappingFile, _ := cmd.Flags().GetString("mappings")
+			mappingsDir, _ := cmd.Flags().GetString("mappings-dir")
+			paramsFile, _ := cmd.Flags().GetString("params")
+			paramsJSON, _ := cmd.Flags().GetString("params-json")
+
+			cfg, err := config.LoadConfig("", "")
+			if err != nil {
+				fmt.Printf("Error loading configuration: %v\n", err)
+				os.Exit(1)
+			}
+
+			actualMappingsDir := filepath.Join(cfg.LibraryPath, "mappings")
+			if mappingsDir != "" {
+				actualMappingsDir = mappingsDir
+			}
+
+			manifest, err := LoadBatchManifest(manifestFile)
+			if err != nil {
+				fmt.Printf("Error loading batch manifest: %v\n", err)
+				os.Exit(1)
+			}
+
+			extraParams, err := loadParameters(paramsFile, paramsJSON)
+			if err != nil {
+				fmt.Printf("Error loading parameters: %v\n", err)
+				os.Exit(1)
+			}
+
+			options := BatchOptions{
+				OutputDir:   outputDir,
+				MappingFile: mappingFile,
+				Workers:     workers,
+				Generate: darnit.GenerateOptions{
+					MappingsDir: actualMappingsDir,
+					ExtraParams: extraParams,
+				},
+			}
+
+			fmt.Printf("Generating plans for %d repositories...\n", len(manifest.Repositories))
+			index, err := GenerateBatch(manifest, options)
+			if err != nil {
+				fmt.Printf("Error generating batch plans: %v\n", err)
+				os.Exit(1)
+			}
+
+			failed := 0
+			fmt.Println("\nRepositories:")
+			for _, entry := range index.Entries {
+				if entry.Error != "" {
+					failed++
+					fmt.Printf("  ✗ %s: %s\n", entry.Name, entry.Error)
+					continue
+				}
+				fmt.Printf("  ✓ %s: %d steps (%s)\n", entry.Name, entry.StepCount, entry.PlanFile)
+			}
+
+			summary := SummarizeBatchActions(index)
+			if len(summary) > 0 {
+				actionNames := make([]string, 0, len(summary))
+				for actionName := range summary {
+					actionNames = append(actionNames, actionName)
+				}
+				sort.Strings(actionNames)
+
+				fmt.Println("\nActions:")
+				for _, actionName := range actionNames {
+					repos := summary[actionName]
+					fmt.Printf("  %s (%d): %s\n", actionName, len(repos), strings.Join(repos, ", "))
+				}
+			}
+
+			fmt.Printf("\nIndex written to %s\n", filepath.Join(outputDir, DefaultBatchIndexFile))
+			if failed > 0 {
+				fmt.Printf("%d of %d plans failed to generate\n", failed, len(index.Entries))
+				os.Exit(1)
+			}
+		},
+	}
+
+	batchCmd.Flags().StringP("output-dir", "o", "plans", "Directory to write plans and the index to")
+	batchCmd.Flags().IntP("workers", "w", 4, "Number of plans to generate concurrently")
+	batchCmd.Flags().StringP("mappings", "m", "", "Mapping file for repositories that do not set their own (overrides the manifest-level mappings)")
+	batchCmd.Flags().StringP("mappings-dir", "d", "", "Directory to search for mapping references (defaults to global library mappings)")
+	batchCmd.Flags().StringP("params", "p", "", "JSON or YAML file with parameters shared by every repository")
+	batchCmd.Flags().String("params-json", "", "JSON string with parameters shared by every repository")
+
+	return batchCmd
+}
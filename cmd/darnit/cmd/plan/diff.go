@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kusari-oss/darn/internal/darnit"
+	. "github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes for plan diff, so it can be used as a CI gate
+const (
+	diffExitNoChanges = 0
+	diffExitChanges   = 1
+	diffExitError     = 2
+)
+
+// diffExit exits plan diff with one of the exit codes above
+var diffExit = os.Exit
+
+// diffUsageError reports a usage error and exits with diffExitError, so a
+// broken invocation is not mistaken for plans that differ
+func diffUsageError(cmd *cobra.Command, err error) error {
+	fmt.Fprintf(os.Stderr, "Error: %v\nRun '%s --help' for usage.\n", err, cmd.CommandPath())
+	diffExit(diffExitError)
+	return err
+}
+
+func getDiffCmd() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff [old-plan] [new-plan]",
+		Short: "Show what changed between two remediation plans",
+		Long: `Compare two remediation plans step by step, matching steps by ID.
+
+Reports added and removed steps, and for steps present in both plans any
+changed action, reason, parameters or dependencies.
+
+Exit codes:
+  0  the plans are identical
+  1  the plans differ
+  2  an error occurred`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(2)(cmd, args); err != nil {
+				return diffUsageError(cmd, err)
+			}
+			return nil
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			outputFormat, _ := cmd.Flags().GetString("format")
+
+			oldPlan, err := darnit.LoadPlanFile(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading old plan: %v\n", err)
+				diffExit(diffExitError)
+				return
+			}
+
+			newPlan, err := darnit.LoadPlanFile(args[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading new plan: %v\n", err)
+				diffExit(diffExitError)
+				return
+			}
+
+			diff := DiffPlans(oldPlan, newPlan)
+			if err := WritePlanDiff(os.Stdout, diff, outputFormat); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing diff: %v\n", err)
+				diffExit(diffExitError)
+				return
+			}
+
+			if diff.HasChanges() {
+				diffExit(diffExitChanges)
+				return
+			}
+			diffExit(diffExitNoChanges)
+		},
+	}
+
+	diffCmd.Flags().StringP("format", "f", DiffFormatText, "Output format: text, json or markdown")
+	diffCmd.SetFlagErrorFunc(diffUsageError)
+
+	return diffCmd
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exitCode is the code plan diff exited with, raised to stop the command
+type exitCode int
+
+// runDiff runs plan diff with args and returns its exit code
+func runDiff(t *testing.T, args ...string) (code int) {
+	t.Helper()
+	originalExit := diffExit
+	t.Cleanup(func() { diffExit = originalExit })
+	diffExit = func(code int) { panic(exitCode(code)) }
+
+	// Keep the diff and usage errors out of the test output
+	originalStdout, originalStderr := os.Stdout, os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	os.Stdout, os.Stderr = devNull, devNull
+	defer func() {
+		os.Stdout, os.Stderr = originalStdout, originalStderr
+		devNull.Close()
+
+		exited, ok := recover().(exitCode)
+		require.True(t, ok, "plan diff did not exit")
+		code = int(exited)
+	}()
+
+	cmd := getDiffCmd()
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	_ = cmd.Execute()
+	return -1
+}
+
+func TestDiffExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	writePlan := func(name, action string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, format.WriteFile(path, &models.RemediationPlan{
+			Steps: []models.RemediationStep{{ID: "step", ActionName: action}},
+		}))
+		return path
+	}
+	oldPlan := writePlan("old.json", "add-readme")
+	samePlan := writePlan("same.json", "add-readme")
+	newPlan := writePlan("new.json", "add-license")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"identical", []string{oldPlan, samePlan}, diffExitNoChanges},
+		{"different", []string{oldPlan, newPlan}, diffExitChanges},
+		{"missing plan", []string{oldPlan, filepath.Join(dir, "missing.json")}, diffExitError},
+		{"one argument", []string{oldPlan}, diffExitError},
+		{"three arguments", []string{oldPlan, newPlan, samePlan}, diffExitError},
+		{"unknown flag", []string{oldPlan, newPlan, "--bogus"}, diffExitError},
+		{"flag without value", []string{oldPlan, newPlan, "--format"}, diffExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, runDiff(t, tt.args...))
+		})
+	}
+}
@@ -13,18 +13,36 @@ import (
 
 func getExecuteCmd() *cobra.Command {
 	executeCmd := &cobra.Command{
-		Use:   "execute [plan-file]",
+		Use:   "execute [plan-file | batch-index]",
 		Short: "Execute a remediation plan",
-		Args:  cobra.ExactArgs(1),
+		Long: `Execute a remediation plan.
+
+When given the index.json written by 'darnit plan batch', every plan in the
+index is executed inside its own repository directory. Output for each
+repository goes to its own log file and a repository × step status matrix is
+printed at the end.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			planFile := args[0]
 			dryRun, _ := cmd.Flags().GetBool("dry-run")
 			verbose, _ := cmd.Flags().GetBool("verbose")
+			requiredApprovals, _ := cmd.Flags().GetInt("require-approval")
+
+			if darnit.IsBatchIndexFile(planFile) {
+				workers, _ := cmd.Flags().GetInt("workers")
+				logDir, _ := cmd.Flags().GetString("log-dir")
+				executeBatch(planFile, workers, logDir, dryRun, verbose, requiredApprovals)
+				return
+			}
 
 			// Load the plan
 			if verbose {
 				fmt.Printf("Loading remediation plan from: %s\n", planFile)
 			}
+			if err := darnit.VerifyPlanSignature(planFile); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 			plan, err := darnit.LoadPlanFile(planFile)
 			if err != nil {
 				fmt.Printf("Error loading plan: %v\n", err)
@@ -33,8 +51,9 @@ func getExecuteCmd() *cobra.Command {
 
 			// Execute the plan
 			executionOpts := models.ExecutionOptions{
-				DryRun:         dryRun,
-				VerboseLogging: verbose,
+				DryRun:            dryRun,
+				VerboseLogging:    verbose,
+				RequiredApprovals: requiredApprovals,
 			}
 
 			if verbose {
@@ -58,6 +77,52 @@ func getExecuteCmd() *cobra.Command {
 	// Configure flags
 	executeCmd.Flags().BoolP("dry-run", "d", false, "Show what would be done without executing actions")
 	executeCmd.Flags().BoolP("verbose", "v", false, "Enable verbose output")
+	executeCmd.Flags().Int("require-approval", 0, "Refuse to run plans without this many approvals of their current steps")
+	executeCmd.Flags().IntP("workers", "w", 4, "Number of plans to execute concurrently (batch index only)")
+	executeCmd.Flags().String("log-dir", "", "Directory for per-repository logs (batch index only, defaults to logs/ next to the index)")
 
 	return executeCmd
 }
+
+// executeBatch executes every plan in a batch index and prints the status matrix
+func executeBatch(indexFile string, workers int, logDir string, dryRun, verbose bool, requiredApprovals int) {
+	options := darnit.BatchExecutionOptions{
+		Execution: models.ExecutionOptions{
+			DryRun:            dryRun,
+			VerboseLogging:    verbose,
+			RequiredApprovals: requiredApprovals,
+		},
+		Workers: workers,
+		LogDir:  logDir,
+	}
+
+	if dryRun {
+		fmt.Println("Running in dry-run mode - no actions will be executed")
+	}
+
+	result, err := darnit.ExecuteBatch(indexFile, options)
+	if err != nil {
+		fmt.Printf("Error executing batch: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Executed %d plans\n\n", len(result.Repos))
+	if err := darnit.WriteStatusMatrix(os.Stdout, result); err != nil {
+		fmt.Printf("Error writing status matrix: %v\n", err)
+		os.Exit(1)
+	}
+
+	if verbose {
+		fmt.Println("\nLogs:")
+		for _, repo := range result.Repos {
+			if repo.LogFile != "" {
+				fmt.Printf("  %s: %s\n", repo.Name, repo.LogFile)
+			}
+		}
+	}
+
+	if failed := result.Failed(); failed > 0 {
+		fmt.Printf("\n%d of %d plans failed\n", failed, len(result.Repos))
+		os.Exit(1)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/kusari-oss/darn/internal/core/config"
 	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
 	"github.com/kusari-oss/darn/internal/darnit"
 	. "github.com/kusari-oss/darn/internal/darnit/plan"
 	"github.com/spf13/cobra"
@@ -32,6 +33,15 @@ func getGenerateCmd() *cobra.Command {
 			repoPath, _ := cmd.Flags().GetString("repo")
 			nonInteractive, _ := cmd.Flags().GetBool("non-interactive")
 			verbose, _ := cmd.Flags().GetBool("verbose")
+			explain, _ := cmd.Flags().GetBool("explain")
+			explainFormat, _ := cmd.Flags().GetString("explain-format")
+			explainOutput, _ := cmd.Flags().GetString("explain-output")
+
+			// Reject an unknown explanation format before generating anything
+			if err := ValidateExplainFormat(explainFormat); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 
 			// Get working directory
 			_, err := os.Getwd()
@@ -90,7 +100,18 @@ func getGenerateCmd() *cobra.Command {
 			if verbose {
 				fmt.Printf("Generating remediation plan using mapping file: %s\n", mappingFile)
 			}
-			plan, err := GenerateRemediationPlan(report, mappingFile, options)
+			var plan *models.RemediationPlan
+			if explain {
+				var explanation *PlanExplanation
+				plan, explanation, err = GenerateRemediationPlanWithExplanation(report, mappingFile, options)
+				// Write the explanation even if generation failed, it shows which rule broke
+				if writeErr := writeExplanation(explanation, explainFormat, explainOutput); writeErr != nil {
+					fmt.Printf("Error writing explanation: %v\n", writeErr)
+					os.Exit(1)
+				}
+			} else {
+				plan, err = GenerateRemediationPlan(report, mappingFile, options)
+			}
 			if err != nil {
 				fmt.Printf("Error generating remediation plan: %v\n", err)
 				os.Exit(1)
@@ -116,6 +137,9 @@ func getGenerateCmd() *cobra.Command {
 					os.Exit(1)
 				}
 				fmt.Printf("Remediation plan saved to %s\n", outputFile)
+				for _, conflict := range plan.Conflicts {
+					fmt.Printf("  Dropped step %s in favor of %s (%s)\n", conflict.StepID, conflict.WinnerID, conflict.Reason)
+				}
 			}
 		},
 	}
@@ -129,10 +153,28 @@ func getGenerateCmd() *cobra.Command {
 	generateCmd.Flags().StringP("repo", "r", "", "Path to repository (for parameter inference)")
 	generateCmd.Flags().BoolP("non-interactive", "n", false, "Do not prompt for missing parameters")
 	generateCmd.Flags().BoolP("verbose", "v", false, "Enable verbose output")
+	generateCmd.Flags().Bool("explain", false, "Print a trace of why each mapping rule matched or was skipped")
+	generateCmd.Flags().String("explain-format", ExplainFormatTree, "Explanation format: tree or json")
+	generateCmd.Flags().String("explain-output", "", "File to write the explanation to (defaults to stderr)")
 
 	return generateCmd
 }
 
+// writeExplanation writes a plan explanation to a file, or to stderr when no file is given
+func writeExplanation(explanation *PlanExplanation, format, outputFile string) error {
+	if outputFile == "" {
+		return WriteExplanation(os.Stderr, explanation, format)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("error creating explanation file: %w", err)
+	}
+	defer file.Close()
+
+	return WriteExplanation(file, explanation, format)
+}
+
 // loadParameters loads parameters from a file or JSON string
 func loadParameters(paramsFile, paramsJSON string) (map[string]interface{}, error) {
 	params := make(map[string]interface{})
@@ -19,4 +19,8 @@ func GetPlanCmd() *cobra.Command {
 func init() {
 	planCmd.AddCommand(getGenerateCmd())
 	planCmd.AddCommand(getExecuteCmd())
+	planCmd.AddCommand(getBatchCmd())
+	planCmd.AddCommand(getDiffCmd())
+	planCmd.AddCommand(getApproveCmd())
+	planCmd.AddCommand(getSignCmd())
 }
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/spf13/cobra"
+)
+
+func getSignCmd() *cobra.Command {
+	signCmd := &cobra.Command{
+		Use:   "sign [plan-file]",
+		Short: "Sign a remediation plan",
+		Long: `Write a detached ed25519 signature for a plan file to <plan-file>.sig.
+
+When trusted_keys is set in the configuration, 'darnit plan execute' refuses
+plans without a valid signature from one of those keys. The signature covers
+the whole file, so sign after recording approvals.
+
+The key defaults to the signing_key configuration setting.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			planFile := args[0]
+			keyPath, _ := cmd.Flags().GetString("key")
+
+			cfg, err := config.LoadConfig("", "")
+			if err != nil {
+				fmt.Printf("Error loading configuration: %v\n", err)
+				os.Exit(1)
+			}
+			if keyPath == "" {
+				keyPath = cfg.SigningKey
+			}
+			if keyPath == "" {
+				fmt.Println("Error: no signing key; use --key or set signing_key in the configuration")
+				os.Exit(1)
+			}
+
+			privateKey, err := signing.LoadPrivateKey(config.ExpandPathWithTilde(keyPath))
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			signaturePath, err := signing.SignFile(planFile, privateKey)
+			if err != nil {
+				fmt.Printf("Error signing plan: %v\n", err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("Signature written to %s\n", signaturePath)
+		},
+	}
+
+	signCmd.Flags().StringP("key", "k", "", "Private key to sign with (defaults to signing_key from the configuration)")
+
+	return signCmd
+}
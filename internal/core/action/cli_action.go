@@ -5,6 +5,8 @@ package action
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,6 +18,8 @@ import (
 type CLIAction struct {
 	config        Config
 	outputParsers map[string]func([]byte) (interface{}, error)
+	workingDir    string    // Directory commands run in (defaults to the process working directory)
+	output        io.Writer // Destination for progress output (defaults to os.Stdout)
 }
 
 // NewCLIAction creates a new CLI action
@@ -72,7 +76,7 @@ func NewOutputCLIAction(config Config) (Action, error) {
 // Execute runs the CLI action
 func (a *CLIAction) Execute(params map[string]interface{}) error {
 	// Create command com_executor
-	com_executor := executor.NewCommandExecutor(a.config.Command, a.config.Args)
+	com_executor := a.newCommandExecutor()
 
 	// Get verbose setting from params (default to false)
 	verbose, _ := params["verbose"].(bool)
@@ -95,7 +99,7 @@ func (a *CLIAction) Execute(params map[string]interface{}) error {
 // ExecuteWithOutput runs the CLI action and captures outputs
 func (a *CLIAction) ExecuteWithOutput(params map[string]interface{}) (map[string]interface{}, error) {
 	// Create command com_executor
-	com_executor := executor.NewCommandExecutor(a.config.Command, a.config.Args)
+	com_executor := a.newCommandExecutor()
 
 	// Get verbose setting from params (default to false)
 	verbose, _ := params["verbose"].(bool)
@@ -123,7 +127,7 @@ func (a *CLIAction) ExecuteWithOutput(params map[string]interface{}) (map[string
 		for outputName, parser := range a.outputParsers {
 			value, err := parser(outputBytes)
 			if err != nil {
-				fmt.Printf("Warning: Failed to parse output %s: %v\n", outputName, err)
+				fmt.Fprintf(writerOrStdout(a.output), "Warning: Failed to parse output %s: %v\n", outputName, err)
 				continue
 			}
 			outputs[outputName] = value
@@ -133,6 +137,32 @@ func (a *CLIAction) ExecuteWithOutput(params map[string]interface{}) (map[string
 	return outputs, nil
 }
 
+// Preview templates the command and describes what Execute would run, without running it
+func (a *CLIAction) Preview(params map[string]interface{}) (string, error) {
+	com_executor := a.newCommandExecutor()
+	if err := com_executor.ProcessParameters(params); err != nil {
+		return "", err
+	}
+
+	workingDir := com_executor.WorkingDir()
+	if workingDir == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("error getting working directory: %w", err)
+		}
+		workingDir = dir
+	}
+
+	return fmt.Sprintf("Would run: %s\nWorking directory: %s\n", com_executor.CommandLine(), workingDir), nil
+}
+
+// newCommandExecutor creates a command executor bound to the action's working directory and output
+func (a *CLIAction) newCommandExecutor() *executor.CommandExecutor {
+	return executor.NewCommandExecutor(a.config.Command, a.config.Args).
+		WithWorkingDir(a.workingDir).
+		WithOutput(a.output)
+}
+
 // Description returns the action description
 func (a *CLIAction) Description() string {
 	if a.config.Description != "" {
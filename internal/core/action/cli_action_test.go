@@ -139,3 +139,29 @@ func (m *MockExecutor) Execute(command string, args []string, params map[string]
 }
 
 // TODO: Implement a test for the CLI action using a mock executor
+
+func TestCLIActionPreview(t *testing.T) {
+	tempDir := t.TempDir()
+	factory := action.NewFactory(action.ActionContext{WorkingDir: tempDir})
+	factory.RegisterDefaultTypes()
+
+	act, err := factory.Create(action.Config{
+		Name:    "enable-mfa",
+		Type:    "cli",
+		Command: "gh",
+		Args:    []string{"api", "orgs/{{.organization}}", "--field", "message={{.message}}"},
+	})
+	require.NoError(t, err)
+
+	previewAct, ok := act.(action.PreviewAction)
+	require.True(t, ok, "Expected CLIAction to implement PreviewAction")
+
+	preview, err := previewAct.Preview(map[string]interface{}{
+		"organization": "kusari-oss",
+		"message":      "Require MFA",
+		"working_dir":  "repo",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Would run: gh api orgs/kusari-oss --field \"message=Require MFA\"\n"+
+		"Working directory: "+filepath.Join(tempDir, "repo")+"\n", preview)
+}
@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Versioned action definitions start with these apiVersion and kind values.
+// Definitions without an apiVersion use the original, unversioned format,
+// which 'darn action migrate' converts.
+const (
+	APIVersion = "darn/v1"
+	KindAction = "Action"
+)
+
+// fileOnlyFields are set only on file actions
+var fileOnlyFields = []string{"template_path", "target_path", "create_dirs", "on_exists", "template_dir", "target_dir", "file_conditions"}
+
+// outputParserFields are the fields of a cli action's output parser
+var outputParserFields = map[string]bool{"format": true, "path": true, "pattern": true}
+
+// unknownFieldRegex matches the yaml.v3 error for a field no struct field reads
+var unknownFieldRegex = regexp.MustCompile(`^line (\d+): field (\S+) not found in type \S+$`)
+
+// ParseConfig decodes an action definition. Fields that no action reads are
+// errors, as are fields that do not apply to the action's type, so a typo
+// cannot silently change what an action does.
+func ParseConfig(data []byte) (Config, error) {
+	var config Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return Config{}, fmt.Errorf("error parsing action: %w", err)
+		}
+
+		// The field may be unknown because the definition predates the versioned format
+		hint := ""
+		if !bytes.Contains(data, []byte("apiVersion:")) {
+			hint = " (run 'darn action migrate' to convert it to " + APIVersion + ")"
+		}
+		messages := make([]string, 0, len(typeErr.Errors))
+		for _, message := range typeErr.Errors {
+			if match := unknownFieldRegex.FindStringSubmatch(message); match != nil {
+				message = fmt.Sprintf("line %s: unknown field '%s'", match[1], match[2])
+			}
+			messages = append(messages, message)
+		}
+		return Config{}, fmt.Errorf("error parsing action: %s%s", strings.Join(messages, "; "), hint)
+	}
+
+	switch {
+	case config.APIVersion == "" && config.Kind == "":
+		// Unversioned definition
+	case config.APIVersion != APIVersion:
+		return Config{}, fmt.Errorf("unsupported action apiVersion '%s' (expected %s)", config.APIVersion, APIVersion)
+	case config.Kind != KindAction:
+		return Config{}, fmt.Errorf("unsupported kind '%s' for %s (expected %s)", config.Kind, APIVersion, KindAction)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate checks that the fields the action's type needs are set and that
+// no fields belonging to another type are. Types other than the built-in
+// file and cli types are checked when the factory creates the action.
+func (c Config) Validate() error {
+	switch c.Type {
+	case "":
+		return fmt.Errorf("action '%s' has no type (file or cli)", c.Name)
+	case "file":
+		return c.validateFile()
+	case "cli":
+		return c.validateCLI()
+	}
+	return nil
+}
+
+// validateFile checks a file action's fields
+func (c Config) validateFile() error {
+	if !validOnExists(c.OnExists) {
+		return fmt.Errorf("unknown on_exists policy '%s' for file actions", c.OnExists)
+	}
+
+	if c.TemplateDir != "" {
+		if c.TargetDir == "" {
+			return fmt.Errorf("target_dir is required for file actions with template_dir")
+		}
+		if c.TemplatePath != "" || c.TargetPath != "" {
+			return fmt.Errorf("template_path and target_path cannot be combined with template_dir")
+		}
+	} else {
+		if c.TemplatePath == "" {
+			return fmt.Errorf("template_path is required for file actions")
+		}
+		if c.TargetPath == "" {
+			return fmt.Errorf("target_path is required for file actions")
+		}
+		if c.TargetDir != "" || len(c.FileConditions) > 0 {
+			return fmt.Errorf("target_dir and file_conditions require template_dir")
+		}
+	}
+
+	if c.Command != "" || len(c.Args) > 0 {
+		return fmt.Errorf("command and args are only valid for cli actions")
+	}
+	if c.Outputs != nil {
+		return fmt.Errorf("file actions have fixed outputs; remove 'outputs'")
+	}
+	return nil
+}
+
+// validateCLI checks a cli action's fields and output parsers
+func (c Config) validateCLI() error {
+	if c.Command == "" {
+		return fmt.Errorf("command is required for CLI actions")
+	}
+
+	set := map[string]bool{
+		"template_path":   c.TemplatePath != "",
+		"target_path":     c.TargetPath != "",
+		"create_dirs":     c.CreateDirs,
+		"on_exists":       c.OnExists != "",
+		"template_dir":    c.TemplateDir != "",
+		"target_dir":      c.TargetDir != "",
+		"file_conditions": len(c.FileConditions) > 0,
+	}
+	for _, field := range fileOnlyFields {
+		if set[field] {
+			return fmt.Errorf("%s is only valid for file actions", field)
+		}
+	}
+
+	if c.Outputs == nil {
+		return nil
+	}
+	outputs, ok := c.Outputs.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("outputs must map output names to parsers")
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		parser, ok := outputs[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("output '%s' must be a parser with a format", name)
+		}
+		for field := range parser {
+			if !outputParserFields[field] {
+				return fmt.Errorf("output '%s' has unknown field '%s'", name, field)
+			}
+		}
+
+		switch format, _ := parser["format"].(string); format {
+		case "json":
+		case "text":
+			if pattern, ok := parser["pattern"].(string); ok {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("output '%s' has an invalid pattern: %w", name, err)
+				}
+			}
+		case "":
+			return fmt.Errorf("output '%s' has no format (json or text)", name)
+		default:
+			return fmt.Errorf("output '%s' has unknown format '%s' (expected json or text)", name, format)
+		}
+	}
+	return nil
+}
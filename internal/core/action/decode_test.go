@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action_test
+
+import (
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		errContains string
+	}{
+		{
+			name: "versioned file action",
+			data: `apiVersion: darn/v1
+kind: Action
+name: readme
+type: file
+template_path: readme.tmpl
+target_path: README.md
+`,
+		},
+		{
+			name: "unversioned cli action",
+			data: "name: run\ntype: cli\ncommand: echo\n",
+		},
+		{
+			name:        "unknown field in unversioned action",
+			data:        "name: run\ntype: cli\ncommand: echo\nparameters: []\n",
+			errContains: "line 4: unknown field 'parameters' (run 'darn action migrate'",
+		},
+		{
+			name:        "unknown field in versioned action",
+			data:        "apiVersion: darn/v1\nkind: Action\nname: run\ntype: cli\ncommand: echo\ncomand: echo\n",
+			errContains: "line 6: unknown field 'comand'",
+		},
+		{
+			name:        "unsupported apiVersion",
+			data:        "apiVersion: darn/v9\nkind: Action\nname: run\ntype: cli\ncommand: echo\n",
+			errContains: "unsupported action apiVersion 'darn/v9'",
+		},
+		{
+			name:        "unsupported kind",
+			data:        "apiVersion: darn/v1\nkind: Mapping\nname: run\ntype: cli\ncommand: echo\n",
+			errContains: "unsupported kind 'Mapping'",
+		},
+		{
+			name:        "missing type",
+			data:        "name: run\ncommand: echo\n",
+			errContains: "has no type",
+		},
+		{
+			name:        "file action with outputs",
+			data:        "name: f\ntype: file\ntemplate_path: a.tmpl\ntarget_path: A\noutputs:\n  x: {format: text}\n",
+			errContains: "file actions have fixed outputs",
+		},
+		{
+			name:        "file action with command",
+			data:        "name: f\ntype: file\ntemplate_path: a.tmpl\ntarget_path: A\ncommand: echo\n",
+			errContains: "only valid for cli actions",
+		},
+		{
+			name:        "cli action with file field",
+			data:        "name: run\ntype: cli\ncommand: echo\ntarget_path: A\n",
+			errContains: "target_path is only valid for file actions",
+		},
+		{
+			name:        "output parser with unknown format",
+			data:        "name: run\ntype: cli\ncommand: echo\noutputs:\n  x:\n    format: xml\n",
+			errContains: "unknown format 'xml'",
+		},
+		{
+			name:        "output parser with unknown field",
+			data:        "name: run\ntype: cli\ncommand: echo\noutputs:\n  x:\n    format: text\n    value: y\n",
+			errContains: "unknown field 'value'",
+		},
+		{
+			name:        "output parser with invalid pattern",
+			data:        "name: run\ntype: cli\ncommand: echo\noutputs:\n  x:\n    format: text\n    pattern: \"([a-z\"\n",
+			errContains: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := action.ParseConfig([]byte(tt.data))
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	data := []byte(`# Writes a file
+name: create-file
+type: file
+template_path: file.tmpl
+target_path: "{{.directory}}/FILE"
+parameters:
+  - name: directory
+    type: string
+    required: true
+    default: "."
+    description: "Where to write"
+outputs:
+  file_path:
+    value: "{{.directory}}/FILE"
+`)
+
+	migrated, changes, err := action.Migrate(data)
+	require.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	expected := `# Writes a file
+apiVersion: darn/v1
+kind: Action
+name: create-file
+type: file
+template_path: file.tmpl
+target_path: "{{.directory}}/FILE"
+schema:
+  type: "object"
+  properties:
+    directory:
+      type: string
+      description: "Where to write"
+  required: ["directory"]
+defaults:
+  directory: "."
+`
+	assert.Equal(t, expected, string(migrated))
+
+	config, err := action.ParseConfig(migrated)
+	require.NoError(t, err)
+	assert.Equal(t, ".", config.Defaults["directory"])
+
+	// Migrating again changes nothing
+	again, changes, err := action.Migrate(migrated)
+	require.NoError(t, err)
+	assert.Nil(t, changes)
+	assert.Equal(t, migrated, again)
+}
+
+func TestMigrate_CLIOutputs(t *testing.T) {
+	data := []byte(`name: hash
+type: cli
+command: git
+args: ["rev-parse", "HEAD"]
+outputs:
+  hash:
+    format: text
+    value: ignored
+`)
+
+	migrated, changes, err := action.Migrate(data)
+	require.NoError(t, err)
+	assert.Contains(t, changes, "removed unused 'value' from output 'hash'")
+	assert.NotContains(t, string(migrated), "value:")
+	assert.Contains(t, string(migrated), "format: text")
+}
+
+func TestMigrate_UnknownField(t *testing.T) {
+	_, _, err := action.Migrate([]byte("name: run\ntype: cli\ncommand: echo\ncomand: echo\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 4: cannot migrate unknown field 'comand'")
+}
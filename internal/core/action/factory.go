@@ -4,6 +4,7 @@ package action
 
 import (
 	"fmt"
+	"io"
 )
 
 // ActionCreator is a function that creates a darn action from a configuration
@@ -18,6 +19,11 @@ type ActionContext struct {
 	UseLocal           bool
 	UseGlobal          bool
 	GlobalFirst        bool
+	Output             io.Writer // Destination for action progress output (nil means os.Stdout)
+
+	// Template directories searched after the local and global ones, such
+	// as those of library dependencies
+	ExtraTemplatesDirs []string
 }
 
 // Factory creates actions of different types
@@ -53,12 +59,22 @@ func (f *Factory) Create(config Config) (Action, error) {
 func (f *Factory) RegisterDefaultTypes() {
 	// File action creator
 	f.Register("file", func(config Config, context ActionContext) (Action, error) {
-		if config.TemplatePath == "" {
-			return nil, fmt.Errorf("template_path is required for file actions")
+		if !validOnExists(config.OnExists) {
+			return nil, fmt.Errorf("unknown on_exists policy %q for file actions", config.OnExists)
 		}
 
-		if config.TargetPath == "" {
-			return nil, fmt.Errorf("target_path is required for file actions")
+		if config.TemplateDir != "" {
+			if config.TargetDir == "" {
+				return nil, fmt.Errorf("target_dir is required for file actions with template_dir")
+			}
+		} else {
+			if config.TemplatePath == "" {
+				return nil, fmt.Errorf("template_path is required for file actions")
+			}
+
+			if config.TargetPath == "" {
+				return nil, fmt.Errorf("target_path is required for file actions")
+			}
 		}
 
 		// Create a FileAction that knows about both template locations
@@ -69,19 +85,47 @@ func (f *Factory) RegisterDefaultTypes() {
 			useLocal:           context.UseLocal,
 			useGlobal:          context.UseGlobal,
 			globalFirst:        context.GlobalFirst,
+			workingDir:         context.WorkingDir,
+			output:             context.Output,
+
+			additionalTemplateDirs: context.ExtraTemplatesDirs,
 		}, nil
 	})
 
 	// CLI action creator
 	f.Register("cli", func(config Config, context ActionContext) (Action, error) {
 		// Handle both regular and output CLI actions based on config
+		var act Action
+		var err error
 		if config.Outputs != nil {
-			return NewOutputCLIAction(config)
+			act, err = NewOutputCLIAction(config)
+		} else {
+			act, err = NewCLIAction(config)
 		}
-		return NewCLIAction(config)
+		if err != nil {
+			return nil, err
+		}
+
+		cliAction := act.(*CLIAction)
+		cliAction.workingDir = context.WorkingDir
+		cliAction.output = context.Output
+		return cliAction, nil
 	})
 }
 
+// Context returns the factory's context
+func (f *Factory) Context() ActionContext {
+	return f.context
+}
+
+// WithContext returns a factory with the same action types and a different context
+func (f *Factory) WithContext(context ActionContext) *Factory {
+	return &Factory{
+		actionCreators: f.actionCreators,
+		context:        context,
+	}
+}
+
 // UpdateContext updates the factory's context
 func (f *Factory) UpdateContext(context ActionContext) {
 	f.context = context
@@ -7,17 +7,23 @@ package action
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/schema"
 	"github.com/kusari-oss/darn/internal/core/template"
+	"github.com/kusari-oss/darn/internal/core/vfs"
 )
 
 // FileProcessor handles file operations
 type FileProcessor struct {
 	templatePath string
+	workingDir   string    // Base directory for relative target paths
+	output       io.Writer // Destination for progress output (defaults to os.Stdout)
+	includeDirs  []string  // Directories partials are included from (defaults to the template's directory)
+	onExists     string    // Policy for an existing target file (defaults to overwrite)
 }
 
 // NewFileProcessor creates a new file processor
@@ -27,38 +33,103 @@ func NewFileProcessor(templatePath string) *FileProcessor {
 	}
 }
 
+// WithWorkingDir sets the directory relative target paths are resolved against
+func (p *FileProcessor) WithWorkingDir(dir string) *FileProcessor {
+	p.workingDir = dir
+	return p
+}
+
+// WithOutput sets where progress output is written
+func (p *FileProcessor) WithOutput(output io.Writer) *FileProcessor {
+	p.output = output
+	return p
+}
+
+// WithIncludeDirs sets the directories {{include}} looks up partials in
+func (p *FileProcessor) WithIncludeDirs(dirs []string) *FileProcessor {
+	p.includeDirs = dirs
+	return p
+}
+
+// WithOnExists sets the policy for a target file that already exists
+func (p *FileProcessor) WithOnExists(policy string) *FileProcessor {
+	p.onExists = policy
+	return p
+}
+
 // ProcessAndWriteFile processes a template and writes it to the target path
 func (p *FileProcessor) ProcessAndWriteFile(targetPath string, params map[string]interface{}, createDirs bool) error {
-	// Process the target path (it might contain template variables)
-	processedTargetPath, err := template.ProcessString(targetPath, params)
+	_, err := p.ProcessAndWrite(targetPath, params, createDirs)
+	return err
+}
+
+// ProcessAndWrite processes a template, writes it to the target path
+// according to the on_exists policy and reports what it did
+func (p *FileProcessor) ProcessAndWrite(targetPath string, params map[string]interface{}, createDirs bool) (*WriteResult, error) {
+	targetPathStr, content, err := p.Render(targetPath, params)
 	if err != nil {
-		return fmt.Errorf("error processing target path: %w", err)
+		return nil, err
 	}
 
-	targetPathStr := string(processedTargetPath)
-
-	// Create directories if needed
-	if createDirs {
+	// Create directories if needed; a diff-only run writes nothing
+	if createDirs && p.onExists != OnExistsDiffOnly {
 		dir := filepath.Dir(targetPathStr)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("error creating directories: %w", err)
+			return nil, fmt.Errorf("error creating directories: %w", err)
 		}
 	}
 
-	// Process the template file
-	processedContent, err := template.ProcessFile(p.templatePath, params)
+	return writeRendered(targetPathStr, content, p.onExists, p.output)
+}
+
+// Preview processes a template and describes what ProcessAndWrite would do,
+// including a unified diff against the current target, without writing anything
+func (p *FileProcessor) Preview(targetPath string, params map[string]interface{}) (string, error) {
+	targetPathStr, content, err := p.Render(targetPath, params)
 	if err != nil {
-		return fmt.Errorf("error processing template: %w", err)
+		return "", err
 	}
+	return previewRendered(targetPathStr, content, p.onExists)
+}
 
-	// Write the file
-	err = os.WriteFile(targetPathStr, processedContent, 0644)
+// Render processes the target path and the template, returning the resolved
+// target path and the rendered content
+func (p *FileProcessor) Render(targetPath string, params map[string]interface{}) (string, []byte, error) {
+	// Process the target path (it might contain template variables)
+	processedTargetPath, err := template.ProcessString(targetPath, params)
 	if err != nil {
-		return fmt.Errorf("error writing file: %w", err)
+		return "", nil, fmt.Errorf("error processing target path: %w", err)
 	}
 
-	fmt.Printf("Created file: %s\n", targetPathStr)
-	return nil
+	targetPathStr := resolveTargetPath(p.workingDir, string(processedTargetPath))
+
+	// Process the template file
+	includeDirs := p.includeDirs
+	if len(includeDirs) == 0 {
+		includeDirs = []string{filepath.Dir(p.templatePath)}
+	}
+	processedContent, err := template.ProcessFileWithIncludes(p.templatePath, params, includeDirs)
+	if err != nil {
+		return "", nil, fmt.Errorf("error processing template: %w", err)
+	}
+
+	return targetPathStr, processedContent, nil
+}
+
+// resolveTargetPath resolves a relative target path against workingDir
+func resolveTargetPath(workingDir, targetPath string) string {
+	if workingDir == "" || filepath.IsAbs(targetPath) {
+		return targetPath
+	}
+	return filepath.Join(workingDir, targetPath)
+}
+
+// writerOrStdout returns w, or os.Stdout when w is nil
+func writerOrStdout(w io.Writer) io.Writer {
+	if w == nil {
+		return os.Stdout
+	}
+	return w
 }
 
 // FileAction creates a file from a template
@@ -70,57 +141,53 @@ type FileAction struct {
 	useLocal               bool
 	useGlobal              bool
 	globalFirst            bool
+	workingDir             string    // Base directory for relative target paths
+	output                 io.Writer // Destination for progress output (defaults to os.Stdout)
 }
 
 // Execute runs the file action with enhanced parameter validation
 func (a *FileAction) Execute(params map[string]interface{}) error {
 	// First, validate parameters against the schema if available
-	if a.config.Schema != nil {
-		// Custom validation for common issues
-		if err := a.validateCommonIssues(params); err != nil {
-			return err
-		}
-
-		// Then do standard schema validation
-		if err := schema.ValidateParams(a.config.Schema, params); err != nil {
-			return fmt.Errorf("parameter validation failed: %w", err)
-		}
+	if err := a.validateParams(params); err != nil {
+		return err
 	}
 
-	// Resolve template path
-	// Build a list of paths to check in order
-	var pathsToCheck []string
+	templateDirs, err := a.templateDirs()
+	if err != nil {
+		return err
+	}
 
-	// Add local and global paths according to configuration
-	if a.useLocal && a.useGlobal {
-		if a.globalFirst {
-			// Global first, then local
-			pathsToCheck = append(pathsToCheck, filepath.Join(a.globalTemplatesDir, a.config.TemplatePath))
-			pathsToCheck = append(pathsToCheck, filepath.Join(a.templatesDir, a.config.TemplatePath))
-		} else {
-			// Local first, then global
-			pathsToCheck = append(pathsToCheck, filepath.Join(a.templatesDir, a.config.TemplatePath))
-			pathsToCheck = append(pathsToCheck, filepath.Join(a.globalTemplatesDir, a.config.TemplatePath))
-		}
-	} else if a.useLocal {
-		// Only local
-		pathsToCheck = append(pathsToCheck, filepath.Join(a.templatesDir, a.config.TemplatePath))
-	} else if a.useGlobal {
-		// Only global
-		pathsToCheck = append(pathsToCheck, filepath.Join(a.globalTemplatesDir, a.config.TemplatePath))
-	} else {
-		return fmt.Errorf("neither local nor global templates enabled")
+	// Directory mode renders a whole template tree
+	if a.config.TemplateDir != "" {
+		_, _, err := a.executeDir(params, templateDirs)
+		return err
 	}
 
-	// Add additional template directories
-	for _, additionalDir := range a.additionalTemplateDirs {
-		pathsToCheck = append(pathsToCheck, filepath.Join(additionalDir, a.config.TemplatePath))
+	_, err = a.executeFile(params, templateDirs)
+	return err
+}
+
+// executeFile renders the action's template to its target path
+func (a *FileAction) executeFile(params map[string]interface{}, templateDirs []string) (*WriteResult, error) {
+	processor, err := a.fileProcessor(params, templateDirs)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check each path in order
+	return processor.ProcessAndWrite(
+		a.config.TargetPath,
+		params,
+		a.config.CreateDirs,
+	)
+}
+
+// fileProcessor finds the action's template and returns a processor for it
+func (a *FileAction) fileProcessor(params map[string]interface{}, templateDirs []string) (*FileProcessor, error) {
+	// Check each directory in order
 	var templatePath string
-	for _, path := range pathsToCheck {
-		if _, err := os.Stat(path); err == nil {
+	for _, dir := range templateDirs {
+		path := filepath.Join(dir, a.config.TemplatePath)
+		if _, err := vfs.Stat(path); err == nil {
 			templatePath = path
 			break
 		}
@@ -128,22 +195,88 @@ func (a *FileAction) Execute(params map[string]interface{}) error {
 
 	// If no template was found
 	if templatePath == "" {
-		return fmt.Errorf("template '%s' not found in any configured location", a.config.TemplatePath)
+		return nil, fmt.Errorf("template '%s' not found in any configured location", a.config.TemplatePath)
 	}
 
-	// Use the found template path
-	processor := NewFileProcessor(templatePath)
-
 	// Log the path if verbose mode is enabled
 	if verbose, ok := params["verbose"].(bool); ok && verbose {
-		fmt.Printf("Using template: %s\n", templatePath)
+		fmt.Fprintf(writerOrStdout(a.output), "Using template: %s\n", templatePath)
 	}
 
-	return processor.ProcessAndWriteFile(
-		a.config.TargetPath,
-		params,
-		a.config.CreateDirs,
-	)
+	// Use the found template path; partials are included from the same directories
+	return NewFileProcessor(templatePath).
+		WithWorkingDir(a.workingDir).
+		WithOutput(a.output).
+		WithIncludeDirs(templateDirs).
+		WithOnExists(a.config.OnExists), nil
+}
+
+// Preview renders the action's templates and describes the changes Execute
+// would make, as unified diffs against the current files
+func (a *FileAction) Preview(params map[string]interface{}) (string, error) {
+	if err := a.validateParams(params); err != nil {
+		return "", err
+	}
+
+	templateDirs, err := a.templateDirs()
+	if err != nil {
+		return "", err
+	}
+
+	if a.config.TemplateDir != "" {
+		return a.previewDir(params, templateDirs)
+	}
+
+	processor, err := a.fileProcessor(params, templateDirs)
+	if err != nil {
+		return "", err
+	}
+	return processor.Preview(a.config.TargetPath, params)
+}
+
+// validateParams validates parameters against the action's schema, if it has one
+func (a *FileAction) validateParams(params map[string]interface{}) error {
+	if a.config.Schema == nil {
+		return nil
+	}
+
+	// Custom validation for common issues
+	if err := a.validateCommonIssues(params); err != nil {
+		return err
+	}
+
+	// Then do standard schema validation
+	if err := schema.ValidateParams(a.config.Schema, params); err != nil {
+		return fmt.Errorf("parameter validation failed: %w", err)
+	}
+	return nil
+}
+
+// templateDirs returns the template directories to search, in order
+func (a *FileAction) templateDirs() ([]string, error) {
+	var templateDirs []string
+
+	// Add local and global directories according to configuration
+	if a.useLocal && a.useGlobal {
+		if a.globalFirst {
+			// Global first, then local
+			templateDirs = append(templateDirs, a.globalTemplatesDir, a.templatesDir)
+		} else {
+			// Local first, then global
+			templateDirs = append(templateDirs, a.templatesDir, a.globalTemplatesDir)
+		}
+	} else if a.useLocal {
+		// Only local
+		templateDirs = append(templateDirs, a.templatesDir)
+	} else if a.useGlobal {
+		// Only global
+		templateDirs = append(templateDirs, a.globalTemplatesDir)
+	} else {
+		return nil, fmt.Errorf("neither local nor global templates enabled")
+	}
+
+	// Add additional template directories
+	return append(templateDirs, a.additionalTemplateDirs...), nil
 }
 
 // validateCommonIssues checks for common parameter issues and provides clear error messages
@@ -212,21 +345,32 @@ func (a *FileAction) validateCommonIssues(params map[string]interface{}) error {
 
 // ExecuteWithOutput runs the file action and returns outputs
 func (a *FileAction) ExecuteWithOutput(params map[string]interface{}) (map[string]interface{}, error) {
-	// Use the same template resolution logic as Execute
-	err := a.Execute(params)
+	if a.config.TemplateDir != "" {
+		return a.executeDirWithOutput(params)
+	}
+
+	if err := a.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	templateDirs, err := a.templateDirs()
 	if err != nil {
 		return nil, err
 	}
 
-	// Process the target path for output
-	processedTargetPath, err := template.ProcessString(a.config.TargetPath, params)
+	result, err := a.executeFile(params, templateDirs)
 	if err != nil {
-		return nil, fmt.Errorf("error processing target path for output: %w", err)
+		return nil, err
 	}
 
-	// Return output with the processed path
+	// Return output with the processed path and what happened to it
 	outputs := make(map[string]interface{})
-	outputs["file_path"] = string(processedTargetPath)
+	outputs["file_path"] = result.Path
+	outputs["status"] = result.Status
+	outputs["content_hash"] = result.ContentHash
+	if result.BackupPath != "" {
+		outputs["backup_path"] = result.BackupPath
+	}
 
 	return outputs, nil
 }
@@ -3,6 +3,7 @@
 package action_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -269,3 +270,332 @@ func createTestFileAction(t *testing.T, config action.Config, templatesDir, work
 
 	return fileAction
 }
+
+func TestFileActionRelativeTargetUsesWorkingDir(t *testing.T) {
+	tempDir := t.TempDir()
+	templatesDir := filepath.Join(tempDir, "templates")
+	workingDir := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(templatesDir, 0755))
+	require.NoError(t, os.MkdirAll(workingDir, 0755))
+	createTestTemplate(t, templatesDir, "readme.tmpl", "# {{.name}}")
+
+	config := action.Config{
+		Name:         "add-readme",
+		Type:         "file",
+		TemplatePath: "readme.tmpl",
+		TargetPath:   "docs/README.md",
+		CreateDirs:   true,
+	}
+
+	var output bytes.Buffer
+	factory := action.NewFactory(action.ActionContext{
+		TemplatesDir: templatesDir,
+		WorkingDir:   workingDir,
+		UseLocal:     true,
+		Output:       &output,
+	})
+	factory.RegisterDefaultTypes()
+
+	act, err := factory.Create(config)
+	require.NoError(t, err)
+
+	outputAct, ok := act.(action.OutputAction)
+	require.True(t, ok, "Expected FileAction to implement OutputAction")
+
+	outputs, err := outputAct.ExecuteWithOutput(map[string]interface{}{"name": "Repo"})
+	require.NoError(t, err)
+
+	// The file is written inside the working directory, not the process cwd
+	targetPath := filepath.Join(workingDir, "docs", "README.md")
+	assert.FileExists(t, targetPath)
+	assert.NoFileExists(t, filepath.Join("docs", "README.md"))
+	assert.Equal(t, targetPath, outputs["file_path"])
+	assert.Contains(t, output.String(), "Created file: "+targetPath)
+}
+
+func TestFileActionIncludesPartialsFromTemplateDirs(t *testing.T) {
+	tempDir := t.TempDir()
+	localDir := filepath.Join(tempDir, "local")
+	globalDir := filepath.Join(tempDir, "global")
+	require.NoError(t, os.MkdirAll(filepath.Join(globalDir, "partials"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(localDir, "docs"), 0755))
+
+	// The template lives in a subdirectory of the local library, the partial in the global one
+	createTestTemplate(t, filepath.Join(localDir, "docs"), "readme.tmpl", `# {{.name}}{{include "partials/footer.tmpl" .}}`)
+	createTestTemplate(t, filepath.Join(globalDir, "partials"), "footer.tmpl", "\nMaintained by {{.owner | default \"the team\"}}")
+
+	factory := action.NewFactory(action.ActionContext{
+		TemplatesDir:       localDir,
+		GlobalTemplatesDir: globalDir,
+		WorkingDir:         tempDir,
+		UseLocal:           true,
+		UseGlobal:          true,
+		Output:             &bytes.Buffer{},
+	})
+	factory.RegisterDefaultTypes()
+
+	act, err := factory.Create(action.Config{
+		Name:         "add-readme",
+		Type:         "file",
+		TemplatePath: "docs/readme.tmpl",
+		TargetPath:   "README.md",
+	})
+	require.NoError(t, err)
+	require.NoError(t, act.Execute(map[string]interface{}{"name": "Repo"}))
+
+	content, err := os.ReadFile(filepath.Join(tempDir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Repo\nMaintained by the team", string(content))
+}
+
+func TestFileActionTemplateDir(t *testing.T) {
+	tempDir := t.TempDir()
+	templatesDir := filepath.Join(tempDir, "templates")
+	workingDir := filepath.Join(tempDir, "repo")
+	sourceDir := filepath.Join(templatesDir, "service")
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "_layouts"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "{{.name}}"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "{{if .ci}}.github{{end}}"), 0755))
+	require.NoError(t, os.MkdirAll(workingDir, 0755))
+
+	createTestTemplate(t, filepath.Join(sourceDir, "_layouts"), "doc.tmpl", `# {{block "title" .}}Untitled{{end}}
+{{block "body" .}}{{end}}`)
+	createTestTemplate(t, sourceDir, "README.md.tmpl", `{{/* extends "_layouts/doc.tmpl" */}}
+{{define "title"}}{{.name}}{{end}}
+{{define "body"}}Owned by {{.owner}}{{end}}`)
+	createTestTemplate(t, sourceDir, "CHANGELOG.md.tmpl", "# Changes in {{.name}}")
+	createTestTemplate(t, sourceDir, "logo.txt", "{{.name}} is copied verbatim")
+	createTestTemplate(t, filepath.Join(sourceDir, "{{.name}}"), "main.go.tmpl", "package {{.name}}")
+	createTestTemplate(t, filepath.Join(sourceDir, "{{if .ci}}.github{{end}}"), "ci.yml", "on: push")
+
+	config := action.Config{
+		Name:        "scaffold-service",
+		Type:        "file",
+		TemplateDir: "service",
+		TargetDir:   "services/{{.name}}",
+		FileConditions: map[string]string{
+			"CHANGELOG.md.tmpl": "{{.changelog}}",
+		},
+	}
+
+	var output bytes.Buffer
+	factory := action.NewFactory(action.ActionContext{
+		TemplatesDir: templatesDir,
+		WorkingDir:   workingDir,
+		UseLocal:     true,
+		Output:       &output,
+	})
+	factory.RegisterDefaultTypes()
+
+	act, err := factory.Create(config)
+	require.NoError(t, err)
+	outputAct, ok := act.(action.OutputAction)
+	require.True(t, ok, "Expected FileAction to implement OutputAction")
+
+	outputs, err := outputAct.ExecuteWithOutput(map[string]interface{}{
+		"name":  "billing",
+		"owner": "payments",
+	})
+	require.NoError(t, err)
+
+	targetDir := filepath.Join(workingDir, "services", "billing")
+	assert.Equal(t, targetDir, outputs["target_dir"])
+	assert.ElementsMatch(t, []string{
+		filepath.Join(targetDir, "README.md"),
+		filepath.Join(targetDir, "billing", "main.go"),
+		filepath.Join(targetDir, "logo.txt"),
+	}, outputs["files"])
+
+	readme, err := os.ReadFile(filepath.Join(targetDir, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# billing\nOwned by payments", string(readme))
+
+	logo, err := os.ReadFile(filepath.Join(targetDir, "logo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "{{.name}} is copied verbatim", string(logo))
+
+	// Layouts, files with a false condition and empty path segments are skipped
+	assert.NoDirExists(t, filepath.Join(targetDir, "_layouts"))
+	assert.NoFileExists(t, filepath.Join(targetDir, "CHANGELOG.md"))
+	assert.NoDirExists(t, filepath.Join(targetDir, ".github"))
+	assert.Contains(t, output.String(), "Created file: "+filepath.Join(targetDir, "README.md"))
+
+	// A target_dir is required alongside template_dir
+	_, err = factory.Create(action.Config{Name: "broken", Type: "file", TemplateDir: "service"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "target_dir is required")
+}
+
+func TestFileActionOnExists(t *testing.T) {
+	const rendered = "# Security\n<!-- darn:begin -->\nContact: sec@example.com\n<!-- darn:end -->\n"
+	const edited = "# Our policy\n<!-- darn:begin -->\nContact: old@example.com\n<!-- darn:end -->\nHand-written notes\n"
+
+	tests := []struct {
+		name           string
+		onExists       string
+		existing       string // "" means the file does not exist
+		expectedStatus string
+		expectedFile   string
+		expectedOutput string
+		expectedError  string
+	}{
+		{name: "creates missing file", onExists: "skip", expectedStatus: action.FileCreated, expectedFile: rendered, expectedOutput: "Created file"},
+		{name: "same content is unchanged", onExists: "fail", existing: rendered, expectedStatus: action.FileUnchanged, expectedFile: rendered, expectedOutput: "File unchanged"},
+		{name: "overwrite by default", existing: edited, expectedStatus: action.FileUpdated, expectedFile: rendered, expectedOutput: "Updated file"},
+		{name: "skip", onExists: "skip", existing: edited, expectedStatus: action.FileSkipped, expectedFile: edited, expectedOutput: "Skipped existing file"},
+		{name: "fail", onExists: "fail", existing: edited, expectedError: "already exists with different content"},
+		{name: "backup", onExists: "backup", existing: edited, expectedStatus: action.FileUpdated, expectedFile: rendered, expectedOutput: "Backed up"},
+		{
+			name:           "merge markers",
+			onExists:       "merge-markers",
+			existing:       edited,
+			expectedStatus: action.FileUpdated,
+			expectedFile:   "# Our policy\n<!-- darn:begin -->\nContact: sec@example.com\n<!-- darn:end -->\nHand-written notes\n",
+		},
+		{
+			name:           "merge markers appends to a file without markers",
+			onExists:       "merge-markers",
+			existing:       "Notes",
+			expectedStatus: action.FileUpdated,
+			expectedFile:   "Notes\n<!-- darn:begin -->\nContact: sec@example.com\n<!-- darn:end -->\n",
+		},
+		{
+			name:           "diff only",
+			onExists:       "diff-only",
+			existing:       edited,
+			expectedStatus: action.FileDiffed,
+			expectedFile:   edited,
+			expectedOutput: "-Contact: old@example.com\n+Contact: sec@example.com\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			createTestTemplate(t, tempDir, "security.tmpl",
+				"# Security\n<!-- darn:begin -->\nContact: {{.email}}\n<!-- darn:end -->\n")
+			targetPath := filepath.Join(tempDir, "SECURITY.md")
+			if tt.existing != "" {
+				require.NoError(t, os.WriteFile(targetPath, []byte(tt.existing), 0644))
+			}
+
+			var output bytes.Buffer
+			factory := action.NewFactory(action.ActionContext{
+				TemplatesDir: tempDir,
+				WorkingDir:   tempDir,
+				UseLocal:     true,
+				Output:       &output,
+			})
+			factory.RegisterDefaultTypes()
+
+			act, err := factory.Create(action.Config{
+				Name:         "add-security-md",
+				Type:         "file",
+				TemplatePath: "security.tmpl",
+				TargetPath:   "SECURITY.md",
+				OnExists:     tt.onExists,
+			})
+			require.NoError(t, err)
+
+			outputs, err := act.(action.OutputAction).ExecuteWithOutput(map[string]interface{}{"email": "sec@example.com"})
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expectedStatus, outputs["status"])
+			content, err := os.ReadFile(targetPath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedFile, string(content))
+			if tt.expectedStatus == action.FileDiffed {
+				// Nothing was written, so the hash is of the rendered content
+				assert.Equal(t, action.ContentHash([]byte(rendered)), outputs["content_hash"])
+			} else {
+				assert.Equal(t, action.ContentHash(content), outputs["content_hash"])
+			}
+			assert.Contains(t, output.String(), tt.expectedOutput)
+
+			if tt.onExists == "backup" {
+				backup, err := os.ReadFile(targetPath + ".bak")
+				require.NoError(t, err)
+				assert.Equal(t, tt.existing, string(backup))
+				assert.Equal(t, targetPath+".bak", outputs["backup_path"])
+			}
+		})
+	}
+}
+
+func TestFileActionUnknownOnExists(t *testing.T) {
+	factory := action.NewFactory(action.ActionContext{UseLocal: true})
+	factory.RegisterDefaultTypes()
+
+	_, err := factory.Create(action.Config{
+		Name:         "add-security-md",
+		Type:         "file",
+		TemplatePath: "security.tmpl",
+		TargetPath:   "SECURITY.md",
+		OnExists:     "replace",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown on_exists policy "replace"`)
+}
+
+func TestFileActionPreview(t *testing.T) {
+	tempDir := t.TempDir()
+	createTestTemplate(t, tempDir, "security.tmpl", "# Security\nContact: {{.email}}\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "service"), 0755))
+	createTestTemplate(t, filepath.Join(tempDir, "service"), "README.md.tmpl", "# {{.email}}\n")
+
+	var output bytes.Buffer
+	factory := action.NewFactory(action.ActionContext{
+		TemplatesDir: tempDir,
+		WorkingDir:   tempDir,
+		UseLocal:     true,
+		Output:       &output,
+	})
+	factory.RegisterDefaultTypes()
+
+	act, err := factory.Create(action.Config{
+		Name:         "add-security-md",
+		Type:         "file",
+		TemplatePath: "security.tmpl",
+		TargetPath:   "SECURITY.md",
+	})
+	require.NoError(t, err)
+	previewAct, ok := act.(action.PreviewAction)
+	require.True(t, ok, "Expected FileAction to implement PreviewAction")
+
+	params := map[string]interface{}{"email": "sec@example.com"}
+	targetPath := filepath.Join(tempDir, "SECURITY.md")
+
+	// A new file is shown as a diff against /dev/null and is not written
+	preview, err := previewAct.Preview(params)
+	require.NoError(t, err)
+	assert.Equal(t, "--- /dev/null\n+++ "+targetPath+"\n@@ -0,0 +1,2 @@\n+# Security\n+Contact: sec@example.com\n", preview)
+	assert.NoFileExists(t, targetPath)
+
+	require.NoError(t, os.WriteFile(targetPath, []byte("# Security\nContact: old@example.com\n"), 0644))
+	preview, err = previewAct.Preview(params)
+	require.NoError(t, err)
+	assert.Contains(t, preview, "-Contact: old@example.com\n+Contact: sec@example.com\n")
+
+	require.NoError(t, act.Execute(params))
+	preview, err = previewAct.Preview(params)
+	require.NoError(t, err)
+	assert.Equal(t, "No changes to "+targetPath+"\n", preview)
+
+	// Directory templates preview every file they would write
+	dirAct, err := factory.Create(action.Config{
+		Name:        "scaffold",
+		Type:        "file",
+		TemplateDir: "service",
+		TargetDir:   "out",
+	})
+	require.NoError(t, err)
+	preview, err = dirAct.(action.PreviewAction).Preview(params)
+	require.NoError(t, err)
+	assert.Contains(t, preview, "+++ "+filepath.Join(tempDir, "out", "README.md")+"\n")
+	assert.NoDirExists(t, filepath.Join(tempDir, "out"))
+}
@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/template"
+	"github.com/kusari-oss/darn/internal/core/vfs"
+)
+
+// templateSuffix marks files in a template directory that are rendered; other
+// files are copied as they are
+const templateSuffix = ".tmpl"
+
+// executeDirWithOutput runs a directory-mode file action and returns the
+// target directory and the files written as outputs
+func (a *FileAction) executeDirWithOutput(params map[string]interface{}) (map[string]interface{}, error) {
+	if err := a.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	templateDirs, err := a.templateDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	targetDir, results, err := a.executeDir(params, templateDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(results))
+	statuses := make(map[string]interface{}, len(results))
+	for _, result := range results {
+		files = append(files, result.Path)
+		statuses[result.Path] = result.Status
+	}
+
+	return map[string]interface{}{
+		"target_dir": targetDir,
+		"files":      files,
+		"statuses":   statuses,
+	}, nil
+}
+
+// renderedFile is a file rendered from a template directory
+type renderedFile struct {
+	path    string
+	content []byte
+}
+
+// executeDir renders the action's template directory and writes each file
+// according to the action's on_exists policy
+func (a *FileAction) executeDir(params map[string]interface{}, templateDirs []string) (string, []*WriteResult, error) {
+	targetDir, files, err := a.renderDir(params, templateDirs)
+	if err != nil {
+		return "", nil, err
+	}
+
+	results := make([]*WriteResult, 0, len(files))
+	for _, file := range files {
+		if a.config.OnExists != OnExistsDiffOnly {
+			if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+				return "", nil, fmt.Errorf("error creating directories: %w", err)
+			}
+		}
+
+		result, err := writeRendered(file.path, file.content, a.config.OnExists, a.output)
+		if err != nil {
+			return "", nil, err
+		}
+		results = append(results, result)
+	}
+
+	return targetDir, results, nil
+}
+
+// previewDir renders the action's template directory and describes the
+// change to each file
+func (a *FileAction) previewDir(params map[string]interface{}, templateDirs []string) (string, error) {
+	_, files, err := a.renderDir(params, templateDirs)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		preview, err := previewRendered(file.path, file.content, a.config.OnExists)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(preview)
+	}
+	return b.String(), nil
+}
+
+// renderDir renders every file under the action's template directory for its
+// target directory, keeping the directory structure. Path segments are
+// templates too: a segment that renders empty skips the file, as does a false
+// entry in file_conditions. Files and directories whose names start with "_"
+// are partials and layouts for the other templates and are not rendered.
+func (a *FileAction) renderDir(params map[string]interface{}, templateDirs []string) (string, []renderedFile, error) {
+	var sourceDir string
+	for _, dir := range templateDirs {
+		candidate := filepath.Join(dir, a.config.TemplateDir)
+		if info, err := vfs.Stat(candidate); err == nil && info.IsDir() {
+			sourceDir = candidate
+			break
+		}
+	}
+	if sourceDir == "" {
+		return "", nil, fmt.Errorf("template directory '%s' not found in any configured location", a.config.TemplateDir)
+	}
+
+	processedTargetDir, err := template.ProcessString(a.config.TargetDir, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("error processing target directory: %w", err)
+	}
+	targetDir := resolveTargetPath(a.workingDir, string(processedTargetDir))
+
+	if verbose, ok := params["verbose"].(bool); ok && verbose {
+		fmt.Fprintf(writerOrStdout(a.output), "Using template directory: %s\n", sourceDir)
+	}
+
+	// Partials are looked up in the template tree first, then in the library
+	includeDirs := append([]string{sourceDir}, templateDirs...)
+
+	var files []renderedFile
+	err = vfs.WalkDir(sourceDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == sourceDir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), "_") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourceDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if condition, ok := a.config.FileConditions[relPath]; ok {
+			include, err := evaluateFileCondition(condition, params)
+			if err != nil {
+				return fmt.Errorf("error evaluating condition for %s: %w", relPath, err)
+			}
+			if !include {
+				return nil
+			}
+		}
+
+		targetRelPath, err := renderTargetPath(relPath, params)
+		if err != nil {
+			return fmt.Errorf("error processing path %s: %w", relPath, err)
+		}
+		if targetRelPath == "" {
+			return nil
+		}
+
+		var content []byte
+		if strings.HasSuffix(path, templateSuffix) {
+			content, err = template.ProcessFileWithIncludes(path, params, includeDirs)
+		} else {
+			content, err = vfs.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("error processing template %s: %w", relPath, err)
+		}
+
+		files = append(files, renderedFile{path: filepath.Join(targetDir, targetRelPath), content: content})
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return targetDir, files, nil
+}
+
+// evaluateFileCondition renders a file condition and reports whether it is
+// true. A condition that refers to a missing parameter is false.
+func evaluateFileCondition(condition string, params map[string]interface{}) (bool, error) {
+	value, err := template.RenderValue(condition, params)
+	if err != nil {
+		var missing *template.MissingValuesError
+		if errors.As(err, &missing) {
+			return false, nil
+		}
+		return false, err
+	}
+	return template.Truthy(value), nil
+}
+
+// renderTargetPath renders each segment of a template's relative path and
+// drops the template suffix. It returns "" when a segment renders empty.
+func renderTargetPath(relPath string, params map[string]interface{}) (string, error) {
+	segments := strings.Split(relPath, "/")
+	for i, segment := range segments {
+		rendered, err := template.ProcessString(segment, params)
+		if err != nil {
+			return "", err
+		}
+
+		segment = strings.TrimSpace(string(rendered))
+		if i == len(segments)-1 {
+			segment = strings.TrimSuffix(segment, templateSuffix)
+		}
+		if segment == "" {
+			return "", nil
+		}
+		if segment == "." || segment == ".." || strings.ContainsAny(segment, `/\`) {
+			return "", fmt.Errorf("path segment %q renders to %q, which is not a file name", segments[i], segment)
+		}
+		segments[i] = segment
+	}
+
+	return filepath.Join(segments...), nil
+}
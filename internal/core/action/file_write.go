@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/diff"
+)
+
+// Policies for writing a file action's target when it already exists
+const (
+	OnExistsOverwrite    = "overwrite"     // Replace the file (the default)
+	OnExistsSkip         = "skip"          // Leave the file alone
+	OnExistsBackup       = "backup"        // Copy the file to a .bak file, then replace it
+	OnExistsFail         = "fail"          // Return an error
+	OnExistsMergeMarkers = "merge-markers" // Replace only the lines between the darn:begin and darn:end markers
+	OnExistsDiffOnly     = "diff-only"     // Print a diff of the change without writing anything
+)
+
+// Statuses reported for each file a file action writes
+const (
+	FileCreated   = "created"
+	FileUpdated   = "updated"
+	FileUnchanged = "unchanged"
+	FileSkipped   = "skipped"
+	FileDiffed    = "diffed"
+)
+
+// Markers delimiting the managed section of a file for the merge-markers
+// policy. Each marker is matched anywhere in a line, so it can sit inside the
+// file type's comment syntax, such as <!-- darn:begin -->.
+const (
+	BeginMarker = "darn:begin"
+	EndMarker   = "darn:end"
+)
+
+// validOnExists reports whether policy is a known on_exists policy; empty means the default
+func validOnExists(policy string) bool {
+	switch policy {
+	case "", OnExistsOverwrite, OnExistsSkip, OnExistsBackup, OnExistsFail, OnExistsMergeMarkers, OnExistsDiffOnly:
+		return true
+	}
+	return false
+}
+
+// WriteResult describes what writing a rendered file did
+type WriteResult struct {
+	Path        string
+	Status      string // One of the File* statuses
+	ContentHash string // SHA-256 of the file's content after the write, or of the rendered content if nothing was written
+	BackupPath  string // Set when the backup policy saved the previous content
+}
+
+// ContentHash returns the hex SHA-256 of content
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeRendered writes rendered content to path according to the on_exists
+// policy. Content that is already in place is never rewritten, so running an
+// action again reports the file as unchanged.
+func writeRendered(path string, content []byte, policy string, output io.Writer) (*WriteResult, error) {
+	out := writerOrStdout(output)
+
+	existing, exists, content, err := prepareWrite(path, content, policy)
+	if err != nil {
+		return nil, err
+	}
+	result := &WriteResult{Path: path, ContentHash: ContentHash(content)}
+
+	if exists && bytes.Equal(existing, content) {
+		result.Status = FileUnchanged
+		fmt.Fprintf(out, "File unchanged: %s\n", path)
+		return result, nil
+	}
+
+	if policy == OnExistsDiffOnly {
+		result.Status = FileDiffed
+		fmt.Fprint(out, diffRendered(path, existing, exists, content))
+		return result, nil
+	}
+
+	if exists {
+		switch policy {
+		case OnExistsSkip:
+			result.Status = FileSkipped
+			result.ContentHash = ContentHash(existing)
+			fmt.Fprintf(out, "Skipped existing file: %s\n", path)
+			return result, nil
+		case OnExistsFail:
+			return nil, fmt.Errorf("file %s already exists with different content (on_exists: fail)", path)
+		case OnExistsBackup:
+			backupPath, err := backupFile(path, existing)
+			if err != nil {
+				return nil, err
+			}
+			result.BackupPath = backupPath
+			fmt.Fprintf(out, "Backed up %s to %s\n", path, backupPath)
+		}
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return nil, fmt.Errorf("error writing file: %w", err)
+	}
+
+	if exists {
+		result.Status = FileUpdated
+		fmt.Fprintf(out, "Updated file: %s\n", path)
+	} else {
+		result.Status = FileCreated
+		fmt.Fprintf(out, "Created file: %s\n", path)
+	}
+	return result, nil
+}
+
+// previewRendered describes what writeRendered would do with content,
+// showing a unified diff for any change it would make
+func previewRendered(path string, content []byte, policy string) (string, error) {
+	existing, exists, content, err := prepareWrite(path, content, policy)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case exists && bytes.Equal(existing, content):
+		return fmt.Sprintf("No changes to %s\n", path), nil
+	case exists && policy == OnExistsSkip:
+		return fmt.Sprintf("Would skip existing file: %s\n", path), nil
+	case exists && policy == OnExistsFail:
+		return fmt.Sprintf("Would fail: %s already exists with different content (on_exists: fail)\n", path), nil
+	}
+
+	var b strings.Builder
+	if exists && policy == OnExistsBackup {
+		fmt.Fprintf(&b, "Would back up %s\n", path)
+	}
+	b.WriteString(diffRendered(path, existing, exists, content))
+	return b.String(), nil
+}
+
+// prepareWrite reads the file at path and returns its content, whether it
+// exists and the content the policy would leave in it
+func prepareWrite(path string, content []byte, policy string) ([]byte, bool, []byte, error) {
+	existing, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, nil, fmt.Errorf("error reading existing file: %w", err)
+	}
+
+	if exists && policy == OnExistsMergeMarkers {
+		content, err = mergeMarkers(existing, content)
+		if err != nil {
+			return nil, false, nil, fmt.Errorf("error merging %s: %w", path, err)
+		}
+	}
+
+	return existing, exists, content, nil
+}
+
+// diffRendered returns a unified diff from the file's current content to content
+func diffRendered(path string, existing []byte, exists bool, content []byte) string {
+	fromName := path
+	if !exists {
+		fromName = "/dev/null"
+	}
+	return diff.Unified(fromName, path, existing, content)
+}
+
+// backupFile saves content next to path as path.bak, or path.bak.N if earlier backups exist
+func backupFile(path string, content []byte) (string, error) {
+	backupPath := path + ".bak"
+	for n := 1; ; n++ {
+		if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+			break
+		}
+		backupPath = fmt.Sprintf("%s.bak.%d", path, n)
+	}
+
+	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+		return "", fmt.Errorf("error writing backup: %w", err)
+	}
+	return backupPath, nil
+}
+
+// mergeMarkers replaces the managed section of existing with the managed
+// section of rendered. If existing has no markers, the rendered section is
+// appended to it.
+func mergeMarkers(existing, rendered []byte) ([]byte, error) {
+	section, ok := markedSection(string(rendered))
+	if !ok {
+		return nil, fmt.Errorf("template has no %s/%s section", BeginMarker, EndMarker)
+	}
+
+	text := string(existing)
+	lines := strings.SplitAfter(text, "\n")
+	begin, end := findMarkers(lines)
+	switch {
+	case begin < 0 && end < 0:
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		return []byte(text + section), nil
+	case begin < 0 || end < begin:
+		return nil, fmt.Errorf("existing file has unbalanced %s/%s markers", BeginMarker, EndMarker)
+	}
+
+	merged := strings.Join(lines[:begin], "") + section + strings.Join(lines[end+1:], "")
+	return []byte(merged), nil
+}
+
+// markedSection returns the lines of text from the begin marker to the end marker, inclusive
+func markedSection(text string) (string, bool) {
+	lines := strings.SplitAfter(text, "\n")
+	begin, end := findMarkers(lines)
+	if begin < 0 || end < begin {
+		return "", false
+	}
+
+	section := strings.Join(lines[begin:end+1], "")
+	if !strings.HasSuffix(section, "\n") {
+		section += "\n"
+	}
+	return section, true
+}
+
+// findMarkers returns the indexes of the first begin marker line and the
+// first end marker line after it, or -1 for markers that are missing
+func findMarkers(lines []string) (int, int) {
+	begin, end := -1, -1
+	for i, line := range lines {
+		if begin < 0 && strings.Contains(line, BeginMarker) {
+			begin = i
+		} else if strings.Contains(line, EndMarker) {
+			end = i
+			if begin >= 0 {
+				break
+			}
+		}
+	}
+	return begin, end
+}
@@ -0,0 +1,283 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFields are the fields of an action definition
+var configFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]; name != "" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// Migrate converts an unversioned action definition to the current
+// apiVersion and returns it with a description of each change. Comments and
+// key order are kept. The parameters list some older definitions carry is
+// converted to a schema and defaults, and output parsers no action reads are
+// dropped; other unknown fields cannot be migrated and are errors. A
+// definition that is already current is returned unchanged, with no changes.
+func Migrate(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("error parsing action: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("action definition must be a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	if mappingValue(root, "apiVersion") != nil {
+		if _, err := ParseConfig(data); err != nil {
+			return nil, nil, err
+		}
+		return data, nil, nil
+	}
+
+	var changes []string
+	typeName := ""
+	if typeNode := mappingValue(root, "type"); typeNode != nil {
+		typeName = typeNode.Value
+	}
+
+	if parameters := mappingValue(root, "parameters"); parameters != nil {
+		if err := migrateParameters(root, parameters); err != nil {
+			return nil, nil, err
+		}
+		removeMappingKey(root, "parameters")
+		changes = append(changes, "converted parameters to schema")
+	}
+
+	if outputs := mappingValue(root, "outputs"); outputs != nil {
+		switch typeName {
+		case "file":
+			removeMappingKey(root, "outputs")
+			changes = append(changes, "removed outputs, which file actions set themselves")
+		case "cli":
+			if outputs.Kind == yaml.MappingNode {
+				for i := 1; i < len(outputs.Content); i += 2 {
+					parser := outputs.Content[i]
+					if parser.Kind != yaml.MappingNode {
+						continue
+					}
+					for j := 0; j+1 < len(parser.Content); {
+						key := parser.Content[j].Value
+						if outputParserFields[key] {
+							j += 2
+							continue
+						}
+						parser.Content = append(parser.Content[:j], parser.Content[j+2:]...)
+						changes = append(changes, fmt.Sprintf("removed unused '%s' from output '%s'", key, outputs.Content[i-1].Value))
+					}
+				}
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i].Value; !configFields[key] {
+			return nil, nil, fmt.Errorf("line %d: cannot migrate unknown field '%s'; remove or rename it by hand", root.Content[i].Line, key)
+		}
+	}
+
+	// The version goes first, taking over the comment above the first field
+	apiVersionKey := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "apiVersion"}
+	if len(root.Content) > 0 {
+		apiVersionKey.HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+	root.Content = append([]*yaml.Node{
+		apiVersionKey,
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: APIVersion},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "kind"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: KindAction},
+	}, root.Content...)
+	changes = append(changes, fmt.Sprintf("added apiVersion %s and kind %s", APIVersion, KindAction))
+
+	// The encoder writes flow style on one line, so keep multi-line ones readable as blocks
+	blockMultilineFlows(root, false)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("error encoding action: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("error encoding action: %w", err)
+	}
+
+	// The result must load, or the file is better left for a person to fix
+	if _, err := ParseConfig(buf.Bytes()); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), changes, nil
+}
+
+// migrateParameters adds a parameters list, such as
+//
+//	parameters:
+//	  - name: directory
+//	    type: string
+//	    required: true
+//	    default: "."
+//	    description: Where to write
+//
+// to the action's schema and defaults. Existing schema properties and
+// defaults win.
+func migrateParameters(root, parameters *yaml.Node) error {
+	if parameters.Kind == yaml.ScalarNode && parameters.Tag == "!!null" {
+		return nil
+	}
+	if parameters.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: parameters must be a list", parameters.Line)
+	}
+
+	schemaNode := mappingValue(root, "schema")
+	if schemaNode == nil {
+		schemaNode = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(schemaNode, "type", scalar("object"))
+		setMappingValue(root, "schema", schemaNode)
+	}
+	properties := mappingValue(schemaNode, "properties")
+	if properties == nil {
+		properties = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(schemaNode, "properties", properties)
+	}
+
+	var required []*yaml.Node
+	for _, param := range parameters.Content {
+		if param.Kind != yaml.MappingNode {
+			return fmt.Errorf("line %d: each parameter must be a mapping with a name", param.Line)
+		}
+		nameNode := mappingValue(param, "name")
+		if nameNode == nil || nameNode.Value == "" {
+			return fmt.Errorf("line %d: parameter has no name", param.Line)
+		}
+		name := nameNode.Value
+
+		property := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for i := 0; i+1 < len(param.Content); i += 2 {
+			key, value := param.Content[i].Value, param.Content[i+1]
+			switch key {
+			case "name":
+			case "required":
+				if value.Value == "true" {
+					required = append(required, scalar(name))
+				}
+			case "default":
+				defaults := mappingValue(root, "defaults")
+				if defaults == nil {
+					defaults = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+					setMappingValue(root, "defaults", defaults)
+				}
+				if mappingValue(defaults, name) == nil {
+					setMappingValue(defaults, name, value)
+				}
+			default:
+				// type, description and other JSON schema keywords
+				setMappingValue(property, key, value)
+			}
+		}
+		if mappingValue(properties, name) == nil {
+			setMappingValue(properties, name, property)
+		}
+	}
+
+	if len(required) > 0 {
+		requiredNode := mappingValue(schemaNode, "required")
+		if requiredNode == nil {
+			requiredNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+			setMappingValue(schemaNode, "required", requiredNode)
+		}
+		for _, name := range required {
+			if !sequenceContains(requiredNode, name.Value) {
+				requiredNode.Content = append(requiredNode.Content, name)
+			}
+		}
+	}
+	return nil
+}
+
+// blockMultilineFlows switches flow-style collections written over several
+// lines to block style, unquoting the keys of JSON-style mappings
+func blockMultilineFlows(node *yaml.Node, converted bool) {
+	if node.Style&yaml.FlowStyle != 0 && len(node.Content) > 0 && node.Content[len(node.Content)-1].Line > node.Line {
+		node.Style &^= yaml.FlowStyle
+		converted = true
+	}
+	if converted && node.Kind == yaml.MappingNode {
+		for i := 0; i < len(node.Content); i += 2 {
+			if key := node.Content[i]; plainKeyRegex.MatchString(key.Value) {
+				key.Style = 0
+			}
+		}
+	}
+	for _, child := range node.Content {
+		blockMultilineFlows(child, converted)
+	}
+}
+
+// plainKeyRegex matches keys that need no quotes
+var plainKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets key in a mapping node, adding it at the end if it is new
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// removeMappingKey removes key from a mapping node, moving its comment to the next key
+func removeMappingKey(node *yaml.Node, key string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if comment := node.Content[i].HeadComment; comment != "" && i+2 < len(node.Content) && node.Content[i+2].HeadComment == "" {
+			node.Content[i+2].HeadComment = comment
+		}
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return
+	}
+}
+
+func sequenceContains(node *yaml.Node, value string) bool {
+	for _, item := range node.Content {
+		if item.Value == value {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/kusari-oss/darn/internal/core/action"
 	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/signing"
 	"gopkg.in/yaml.v3"
 )
 
@@ -25,6 +26,8 @@ const (
 	DefaultGlobalLibrary  = "~/.darn/library"
 	DefaultConfigFileName = "config.yaml"
 	DefaultStateFileName  = "state.yaml"
+	DefaultKeysDir        = "~/.darn/keys"
+	DefaultCacheDir       = "~/.darn/cache/libraries"
 )
 
 // Config holds the global application configuration
@@ -41,11 +44,33 @@ type Config struct {
 	UseGlobal          bool   `yaml:"use_global"`
 	UseLocal           bool   `yaml:"use_local"`
 	GlobalFirst        bool   `yaml:"global_first"`
+
+	// Search the defaults built into darn after the configured libraries.
+	// They are also searched when the global library has not been created.
+	UseBuiltin bool `yaml:"use_builtin,omitempty"`
+
+	// Ordered library sources. When set, they take the place of LibraryPath:
+	// the first source is the library and earlier sources shadow later ones.
+	LibrarySources []LibrarySource `yaml:"library_sources,omitempty"`
+
+	// Signing configuration. When TrustedKeys is set, plans and libraries must
+	// carry a valid signature from one of the keys before they are used.
+	TrustedKeys []string `yaml:"trusted_keys,omitempty"` // Paths to trusted ed25519 public keys
+	SigningKey  string   `yaml:"signing_key,omitempty"`  // Default private key for signing commands
 	
 	// Runtime library manager
 	LibraryManager *library.Manager `yaml:"-"`
 }
 
+// LibrarySource is one library in an ordered list of library sources
+type LibrarySource struct {
+	Path string `yaml:"path"`
+	// Prefix that selects this source's actions, as in corp/add-security-md.
+	// Defaults to the name in the library's manifest, then "default" for the
+	// first source and the directory name for the others.
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
 // State holds the runtime state of darn
 type State struct {
 	ProjectDir    string `yaml:"project_dir"`    // Current project directory
@@ -177,10 +202,19 @@ func LoadConfig(cmdLineLibraryPath string, globalConfigPathOverride string) (*Co
 		fmt.Printf("Warning: could not load global config file '%s': %v\n", globalConfigPath, err)
 	}
 
+	// Library sources take the place of the library path
+	if len(config.LibrarySources) > 0 {
+		if err := validateLibrarySources(config.LibrarySources); err != nil {
+			return nil, fmt.Errorf("invalid library_sources in %s: %w", globalConfigPath, err)
+		}
+		config.LibraryPath = config.LibrarySources[0].Path
+	}
+
 	// Override with command-line library path if provided
 	if cmdLineLibraryPath != "" {
 		config.LibraryPath = ExpandPathWithTilde(cmdLineLibraryPath)
 		config.CmdLineLibraryPath = ExpandPathWithTilde(cmdLineLibraryPath) // Also store the original cmd line path
+		config.LibrarySources = nil
 	}
 
 	// Post-condition: config.LibraryPath will be:
@@ -216,6 +250,53 @@ func LoadConfigFile(path string) (*Config, error) {
 	return config, nil
 }
 
+// Verifier returns a verifier for the configured trusted keys, or nil when no
+// keys are configured and signatures are not checked
+func (c *Config) Verifier() (*signing.Verifier, error) {
+	if len(c.TrustedKeys) == 0 {
+		return nil, nil
+	}
+	return signing.NewVerifier(c.TrustedKeys)
+}
+
+// AddLibrarySource adds source after the configured library sources,
+// replacing a source with the same namespace. Without sources, the library
+// path becomes the first source so that it keeps precedence.
+func (c *Config) AddLibrarySource(source LibrarySource) {
+	if len(c.LibrarySources) == 0 && c.LibraryPath != "" {
+		c.LibrarySources = []LibrarySource{{Path: c.LibraryPath}}
+	}
+
+	for i, existing := range c.LibrarySources {
+		if source.Namespace != "" && existing.Namespace == source.Namespace {
+			c.LibrarySources[i] = source
+			return
+		}
+	}
+	c.LibrarySources = append(c.LibrarySources, source)
+}
+
+// validateLibrarySources checks that every source has a path and that no
+// namespace is given twice
+func validateLibrarySources(sources []LibrarySource) error {
+	namespaces := make(map[string]bool)
+	for i, source := range sources {
+		if source.Path == "" {
+			return fmt.Errorf("source %d has no path", i+1)
+		}
+		if strings.Contains(source.Namespace, "/") {
+			return fmt.Errorf("namespace %q must not contain '/'", source.Namespace)
+		}
+		if source.Namespace != "" {
+			if namespaces[source.Namespace] {
+				return fmt.Errorf("namespace %q is used by more than one source", source.Namespace)
+			}
+			namespaces[source.Namespace] = true
+		}
+	}
+	return nil
+}
+
 // mergeConfigs merges source config into target config
 // Only non-zero values from source override target
 func mergeConfigs(target, source *Config) {
@@ -231,6 +312,22 @@ func mergeConfigs(target, source *Config) {
 	if source.LibraryPath != "" {
 		target.LibraryPath = ExpandPathWithTilde(source.LibraryPath)
 	}
+	if len(source.TrustedKeys) > 0 {
+		target.TrustedKeys = make([]string, len(source.TrustedKeys))
+		for i, key := range source.TrustedKeys {
+			target.TrustedKeys[i] = ExpandPathWithTilde(key)
+		}
+	}
+	if source.SigningKey != "" {
+		target.SigningKey = ExpandPathWithTilde(source.SigningKey)
+	}
+	if len(source.LibrarySources) > 0 {
+		target.LibrarySources = make([]LibrarySource, len(source.LibrarySources))
+		for i, librarySource := range source.LibrarySources {
+			librarySource.Path = ExpandPathWithTilde(librarySource.Path)
+			target.LibrarySources[i] = librarySource
+		}
+	}
 	// CmdLineLibraryPath is not merged here as it's handled in LoadConfig directly.
 	// Boolean fields - only override if they're explicitly set in the source
 	// This isn't perfect since there's no way to tell from the parsed struct if they were omitted,
@@ -238,6 +335,21 @@ func mergeConfigs(target, source *Config) {
 	target.UseGlobal = source.UseGlobal
 	target.UseLocal = source.UseLocal
 	target.GlobalFirst = source.GlobalFirst
+	target.UseBuiltin = source.UseBuiltin
+}
+
+// BuiltinDefaults reports whether the defaults built into darn are searched
+// after the configured libraries: when use_builtin is set, or when the
+// global library is used but does not exist, so darn works without one
+func (c *Config) BuiltinDefaults() bool {
+	if c.UseBuiltin {
+		return true
+	}
+	if !c.UseGlobal {
+		return false
+	}
+	_, err := os.Stat(c.LibraryPath)
+	return os.IsNotExist(err)
 }
 
 // SaveConfig saves the configuration to the specified directory (typically for project-local configs)
@@ -401,8 +513,8 @@ func loadActionsFromDir(actionsDir string) (map[string]action.Config, error) {
 		}
 
 		// Parse the YAML
-		var actionConfig action.Config
-		if err := yaml.Unmarshal(data, &actionConfig); err != nil {
+		actionConfig, err := action.ParseConfig(data)
+		if err != nil {
 			return fmt.Errorf("error parsing action file %s: %w", path, err)
 		}
 
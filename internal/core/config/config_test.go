@@ -361,3 +361,56 @@ func TestSaveLoadState(t *testing.T) {
 	assert.Equal(t, originalState.InitializedAt, loadedState.InitializedAt)
 	assert.Equal(t, originalState.Version, loadedState.Version)
 }
+
+func TestLoadConfig_LibrarySources(t *testing.T) {
+	darnHome := t.TempDir()
+	t.Setenv("DARN_HOME", darnHome)
+
+	configPath := createTempDarnConfig(t, darnHome, &Config{
+		LibraryPath: "~/ignored",
+		UseGlobal:   true,
+		LibrarySources: []LibrarySource{
+			{Path: "~/corp", Namespace: "corp"},
+			{Path: "/opt/darn/default"},
+		},
+	})
+
+	cfg, err := LoadConfig("", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []LibrarySource{
+		{Path: filepath.Join(darnHome, "corp"), Namespace: "corp"},
+		{Path: "/opt/darn/default"},
+	}, cfg.LibrarySources)
+	assert.Equal(t, filepath.Join(darnHome, "corp"), cfg.LibraryPath, "the first source is the library")
+
+	// A command-line library path replaces the sources
+	cfg, err = LoadConfig("/tmp/cmdline-lib", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/cmdline-lib", cfg.LibraryPath)
+	assert.Empty(t, cfg.LibrarySources)
+
+	data, err := yaml.Marshal(&Config{LibrarySources: []LibrarySource{{Path: "/a", Namespace: "x"}, {Path: "/b", Namespace: "x"}}})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(configPath, data, 0644))
+	_, err = LoadConfig("", "")
+	assert.ErrorContains(t, err, `namespace "x" is used by more than one source`)
+}
+
+func TestAddLibrarySource(t *testing.T) {
+	cfg := &Config{LibraryPath: "/libs/default"}
+
+	cfg.AddLibrarySource(LibrarySource{Path: "/cache/corp/1.0.0", Namespace: "corp"})
+	assert.Equal(t, []LibrarySource{
+		{Path: "/libs/default"},
+		{Path: "/cache/corp/1.0.0", Namespace: "corp"},
+	}, cfg.LibrarySources, "the library path keeps precedence")
+
+	// A new version replaces the old one in place
+	cfg.AddLibrarySource(LibrarySource{Path: "/cache/extras/0.1.0", Namespace: "extras"})
+	cfg.AddLibrarySource(LibrarySource{Path: "/cache/corp/1.1.0", Namespace: "corp"})
+	assert.Equal(t, []LibrarySource{
+		{Path: "/libs/default"},
+		{Path: "/cache/corp/1.1.0", Namespace: "corp"},
+		{Path: "/cache/extras/0.1.0", Namespace: "extras"},
+	}, cfg.LibrarySources)
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package diff produces line-based unified diffs for showing file changes
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around each change
+const contextLines = 3
+
+// opKind identifies a line in an edit script
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is one line of an edit script
+type op struct {
+	kind opKind
+	line string
+}
+
+// Unified returns a unified diff turning from into to, or "" if they are
+// equal. fromName and toName label the two sides in the diff header.
+func Unified(fromName, toName string, from, to []byte) string {
+	if string(from) == string(to) {
+		return ""
+	}
+
+	ops := editScript(splitLines(string(from)), splitLines(string(to)))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Walk the edit script, emitting each run of changes with its context
+	fromLine, toLine := 1, 1
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		start := max(i-contextLines, 0)
+		for start < i && ops[start].kind != opEqual {
+			start++
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == opEqual {
+				next++
+			}
+			if next == len(ops) || next-end > 2*contextLines {
+				end = min(end+contextLines, len(ops))
+				break
+			}
+			end = next
+		}
+
+		hunkFrom, hunkTo := fromLine-(i-start), toLine-(i-start)
+		var fromCount, toCount int
+		var body strings.Builder
+		for _, o := range ops[start:end] {
+			switch o.kind {
+			case opEqual:
+				fromCount++
+				toCount++
+				body.WriteString(" " + o.line + "\n")
+			case opDelete:
+				fromCount++
+				body.WriteString("-" + o.line + "\n")
+			case opInsert:
+				toCount++
+				body.WriteString("+" + o.line + "\n")
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n%s", hunkRange(hunkFrom, fromCount), hunkRange(hunkTo, toCount), body.String())
+
+		fromLine = hunkFrom + fromCount
+		toLine = hunkTo + toCount
+		i = end
+	}
+
+	return b.String()
+}
+
+// hunkRange formats a hunk's start line and length; an empty side starts
+// at the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// editScript computes the shortest edit script from a to b using the longest
+// common subsequence of lines. Files managed by actions are small, so the
+// quadratic table is fine.
+func editScript(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{opDelete, a[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{opDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{opInsert, b[j]})
+	}
+	return ops
+}
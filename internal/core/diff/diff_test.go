@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "new file",
+			from:     "",
+			to:       "a\nb\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "changed line",
+			from:     "a\nb\nc\n",
+			to:       "a\nB\nc\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			to:   "1\nX\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			expected: "--- old\n+++ new\n@@ -1,5 +1,5 @@\n 1\n-2\n+X\n 3\n 4\n 5\n" +
+				"@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, diff.Unified("old", "new", []byte(tt.from), []byte(tt.to)))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package diff
+
+import "strings"
+
+// Conflict markers written by Merge3 around lines changed differently on both sides
+const (
+	ConflictStart = "<<<<<<<"
+	ConflictSep   = "======="
+	ConflictEnd   = ">>>>>>>"
+)
+
+// Merge3 merges the changes from base to ours and from base to theirs,
+// line by line. Where both sides changed the same lines differently, the
+// result contains both versions between conflict markers labelled with
+// oursName and theirsName, and conflict is true.
+func Merge3(base, ours, theirs []byte, oursName, theirsName string) (merged []byte, conflict bool) {
+	o, a, b := splitLines(string(base)), splitLines(string(ours)), splitLines(string(theirs))
+	matchA, matchB := matchLines(o, a), matchLines(o, b)
+
+	var out []string
+	emit := func(baseChunk, aChunk, bChunk []string) {
+		switch {
+		case equalLines(aChunk, baseChunk):
+			out = append(out, bChunk...)
+		case equalLines(bChunk, baseChunk), equalLines(aChunk, bChunk):
+			out = append(out, aChunk...)
+		default:
+			conflict = true
+			out = append(out, ConflictStart+" "+oursName)
+			out = append(out, aChunk...)
+			out = append(out, ConflictSep)
+			out = append(out, bChunk...)
+			out = append(out, ConflictEnd+" "+theirsName)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for i < len(o) || j < len(a) || k < len(b) {
+		// Copy lines that are unchanged on both sides
+		n := 0
+		for i+n < len(o) && matchA[i+n] == j+n && matchB[i+n] == k+n {
+			n++
+		}
+		if n > 0 {
+			out = append(out, o[i:i+n]...)
+			i, j, k = i+n, j+n, k+n
+			continue
+		}
+
+		// Find the next base line both sides kept; everything before it is one changed chunk
+		next := i
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+		if next == len(o) {
+			emit(o[i:], a[j:], b[k:])
+			break
+		}
+		emit(o[i:next], a[j:matchA[next]], b[k:matchB[next]])
+		i, j, k = next, matchA[next], matchB[next]
+	}
+
+	if len(out) == 0 {
+		return nil, conflict
+	}
+	return []byte(strings.Join(out, "\n") + "\n"), conflict
+}
+
+// matchLines maps each line of base to the line of other it is kept as, or -1 if it was removed
+func matchLines(base, other []string) []int {
+	matches := make([]int, len(base))
+	i, j := 0, 0
+	for _, o := range editScript(base, other) {
+		switch o.kind {
+		case opEqual:
+			matches[i] = j
+			i++
+			j++
+		case opDelete:
+			matches[i] = -1
+			i++
+		case opInsert:
+			j++
+		}
+	}
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge3(t *testing.T) {
+	base := "one\ntwo\nthree\nfour\nfive\n"
+
+	tests := []struct {
+		name     string
+		ours     string
+		theirs   string
+		want     string
+		conflict bool
+	}{
+		{"no changes", base, base, base, false},
+		{"only ours", "one\nTWO\nthree\nfour\nfive\n", base, "one\nTWO\nthree\nfour\nfive\n", false},
+		{"only theirs", base, "one\ntwo\nthree\nfour\nfive\nsix\n", "one\ntwo\nthree\nfour\nfive\nsix\n", false},
+		{"separate changes", "zero\none\ntwo\nthree\nfour\nfive\n", "one\ntwo\nthree\nFOUR\nfive\n", "zero\none\ntwo\nthree\nFOUR\nfive\n", false},
+		{"same change", "one\n2\nthree\nfour\nfive\n", "one\n2\nthree\nfour\nfive\n", "one\n2\nthree\nfour\nfive\n", false},
+		{"deletion and edit", "one\nthree\nfour\nfive\n", "one\ntwo\nthree\nfour\n5\n", "one\nthree\nfour\n5\n", false},
+		{
+			"conflict",
+			"one\nmine\nthree\nfour\nfive\n",
+			"one\nyours\nthree\nfour\nfive\n",
+			"one\n<<<<<<< local\nmine\n=======\nyours\n>>>>>>> upstream\nthree\nfour\nfive\n",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflict := diff.Merge3([]byte(base), []byte(tt.ours), []byte(tt.theirs), "local", "upstream")
+			assert.Equal(t, tt.want, string(merged))
+			assert.Equal(t, tt.conflict, conflict)
+		})
+	}
+}
@@ -0,0 +1,459 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kusari-oss/darn/internal/core/signing"
+)
+
+// Kinds of library sources accepted by Installer.Install
+const (
+	SourceGit       = "git"
+	SourceArchive   = "archive"
+	SourceDirectory = "directory"
+)
+
+// archiveExtensions are the archive formats Installer can unpack
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+// Installer fetches libraries into a cache directory, keyed by name and version
+type Installer struct {
+	cacheDir    string
+	darnVersion string
+	client      *http.Client
+}
+
+// InstallOptions controls a library installation
+type InstallOptions struct {
+	Ref      string // Git branch, tag or commit; overrides a #ref suffix on the source
+	Checksum string // Expected sha256:<hex> of the archive, or of the library content for git and directory sources
+	Force    bool   // Replace an installed copy of the same version with different content
+}
+
+// InstallResult describes an installed library
+type InstallResult struct {
+	Manifest         *Manifest
+	Path             string
+	Hash             string // See signing.HashLibrary
+	AlreadyInstalled bool   // The same content was already in the cache
+}
+
+// NewInstaller creates an installer that unpacks libraries under cacheDir
+func NewInstaller(cacheDir, darnVersion string) *Installer {
+	return &Installer{
+		cacheDir:    cacheDir,
+		darnVersion: darnVersion,
+		client:      &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// SourceKind returns the kind of a library source and the location to fetch
+// it from. Git sources are URLs prefixed with git+, ending in .git or in scp
+// form (git@host:repo); archives are URLs or paths ending in .tar.gz, .tgz,
+// .tar or .zip; anything else is a local directory. A git source may end in
+// #ref to select a branch, tag or commit.
+func SourceKind(source string) (kind, location, ref string) {
+	location = source
+	if i := strings.LastIndex(location, "#"); i >= 0 {
+		location, ref = location[:i], location[i+1:]
+	}
+
+	switch {
+	case strings.HasPrefix(location, "git+"):
+		return SourceGit, strings.TrimPrefix(location, "git+"), ref
+	case strings.HasSuffix(location, ".git") || strings.HasPrefix(location, "git@"):
+		return SourceGit, location, ref
+	}
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(location), ext) {
+			return SourceArchive, location, ref
+		}
+	}
+	return SourceDirectory, strings.TrimPrefix(source, "file://"), ""
+}
+
+// Install fetches the library at source, checks its manifest and checksum
+// and unpacks it into <cache>/<name>/<version>
+func (i *Installer) Install(source string, options InstallOptions) (*InstallResult, error) {
+	if err := os.MkdirAll(i.cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating library cache: %w", err)
+	}
+	workDir, err := os.MkdirTemp(i.cacheDir, ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	kind, location, ref := SourceKind(source)
+	if options.Ref != "" {
+		ref = options.Ref
+	}
+	if ref != "" && kind != SourceGit {
+		return nil, fmt.Errorf("a ref can only be used with git sources")
+	}
+
+	fetched := filepath.Join(workDir, "library")
+	switch kind {
+	case SourceGit:
+		err = fetchGit(location, ref, fetched)
+	case SourceArchive:
+		err = i.fetchArchive(location, options.Checksum, workDir, fetched)
+	default:
+		err = copyDir(location, fetched)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := libraryRoot(fetched)
+	if err != nil {
+		return nil, fmt.Errorf("error installing %s: %w", source, err)
+	}
+	manifest, err := LoadManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("error installing %s: no %s found", source, ManifestFile)
+	}
+	if err := manifest.CheckDarnVersion(i.darnVersion); err != nil {
+		return nil, err
+	}
+
+	hash, err := signing.HashLibrary(root)
+	if err != nil {
+		return nil, err
+	}
+	if kind != SourceArchive && options.Checksum != "" {
+		if err := checkChecksum(hash, options.Checksum); err != nil {
+			return nil, fmt.Errorf("library %s: %w", source, err)
+		}
+	}
+
+	// The manifest comes from the source, so its name and version must not
+	// lead out of the cache (Validate rejects such names already)
+	relPath := filepath.Join(manifest.Name, manifest.Version)
+	if !filepath.IsLocal(relPath) {
+		return nil, fmt.Errorf("error installing %s: library %s %s is outside the library cache", source, manifest.Name, manifest.Version)
+	}
+	result := &InstallResult{
+		Manifest: manifest,
+		Path:     filepath.Join(i.cacheDir, relPath),
+		Hash:     hash,
+	}
+
+	if _, err := os.Stat(result.Path); err == nil {
+		installedHash, err := signing.HashLibrary(result.Path)
+		if err != nil {
+			return nil, err
+		}
+		if installedHash == hash {
+			result.AlreadyInstalled = true
+			return result, nil
+		}
+		if !options.Force {
+			return nil, fmt.Errorf("library %s %s is already installed at %s with different content (use --force to replace it)", manifest.Name, manifest.Version, result.Path)
+		}
+		if err := os.RemoveAll(result.Path); err != nil {
+			return nil, fmt.Errorf("error removing installed library: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(result.Path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating library cache: %w", err)
+	}
+	if err := os.Rename(root, result.Path); err != nil {
+		return nil, fmt.Errorf("error moving library into the cache: %w", err)
+	}
+	return result, nil
+}
+
+// libraryRoot returns dir, or the single directory inside it if dir has no
+// manifest, as is usual for archives of a repository
+func libraryRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), nil
+	}
+	return dir, nil
+}
+
+// checkChecksum compares a sha256:<hex> hash with an expected checksum,
+// with or without the sha256: prefix
+func checkChecksum(hash, expected string) error {
+	if !strings.HasPrefix(expected, "sha256:") {
+		expected = "sha256:" + expected
+	}
+	if !strings.EqualFold(hash, expected) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, hash)
+	}
+	return nil
+}
+
+// fetchGit clones a git repository at ref into dest, without its history.
+// A branch or tag is cloned shallowly; other refs, such as commit hashes,
+// need a full clone to check out. The URL and ref come from the user or a
+// manifest, so git must never read them as options.
+func fetchGit(url, ref, dest string) error {
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git ref %q", ref)
+	}
+
+	shallow := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		shallow = append(shallow, "--branch", ref)
+	}
+	output, err := exec.Command("git", append(shallow, "--", url, dest)...).CombinedOutput()
+	if err == nil {
+		return os.RemoveAll(filepath.Join(dest, ".git"))
+	}
+	if ref == "" {
+		return fmt.Errorf("error cloning %s: %w: %s", url, err, strings.TrimSpace(string(output)))
+	}
+
+	// The ref is not a branch or tag
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if output, err := exec.Command("git", "clone", "--quiet", "--", url, dest).CombinedOutput(); err != nil {
+		return fmt.Errorf("error cloning %s: %w: %s", url, err, strings.TrimSpace(string(output)))
+	}
+	if output, err := exec.Command("git", "-C", dest, "checkout", "--quiet", ref, "--").CombinedOutput(); err != nil {
+		return fmt.Errorf("error checking out %s: %w: %s", ref, err, strings.TrimSpace(string(output)))
+	}
+	return os.RemoveAll(filepath.Join(dest, ".git"))
+}
+
+// fetchArchive downloads or opens an archive, checks its checksum and unpacks it into dest
+func (i *Installer) fetchArchive(location, checksum, workDir, dest string) error {
+	archivePath := filepath.Join(workDir, "archive")
+	if err := i.download(location, archivePath); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %w", err)
+	}
+	if checksum != "" {
+		sum := sha256.Sum256(data)
+		if err := checkChecksum("sha256:"+hex.EncodeToString(sum[:]), checksum); err != nil {
+			return fmt.Errorf("archive %s: %w", location, err)
+		}
+	}
+
+	lower := strings.ToLower(location)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return unzip(archivePath, dest)
+	case strings.HasSuffix(lower, ".tar"):
+		return untar(archivePath, dest, false)
+	}
+	return untar(archivePath, dest, true)
+}
+
+// download copies a URL or local file to dest
+func (i *Installer) download(location, dest string) error {
+	var src io.ReadCloser
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		resp, err := i.client.Get(location)
+		if err != nil {
+			return fmt.Errorf("error downloading %s: %w", location, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("error downloading %s: status %d", location, resp.StatusCode)
+		}
+		src = resp.Body
+	} else {
+		file, err := os.Open(strings.TrimPrefix(location, "file://"))
+		if err != nil {
+			return fmt.Errorf("error opening archive: %w", err)
+		}
+		src = file
+	}
+	defer src.Close()
+
+	dst, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("error creating archive file: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return fmt.Errorf("error downloading %s: %w", location, err)
+	}
+	return nil
+}
+
+// archiveTarget returns where an archive entry is unpacked, refusing entries
+// that would land outside dest
+func archiveTarget(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if target != dest && !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q is outside the archive root", name)
+	}
+	return target, nil
+}
+
+// writeArchiveFile writes one unpacked file
+func writeArchiveFile(target string, src io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// untar unpacks a tar archive, optionally gzip-compressed. Only regular
+// files and directories are unpacked.
+func untar(archivePath, dest string, compressed bool) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("error opening archive: %w", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+
+		target, err := archiveTarget(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("error unpacking archive: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr); err != nil {
+				return fmt.Errorf("error unpacking archive: %w", err)
+			}
+		}
+	}
+}
+
+// unzip unpacks a zip archive. Only regular files and directories are unpacked.
+func unzip(archivePath, dest string) error {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("error reading archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		target, err := archiveTarget(dest, entry.Name)
+		if err != nil {
+			return err
+		}
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("error unpacking archive: %w", err)
+			}
+			continue
+		}
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		src, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+		err = writeArchiveFile(target, src)
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("error unpacking archive: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyDir copies the regular files of a directory tree, skipping hidden
+// files and directories in its root such as .git. Hidden files below the
+// root, such as templates/.github/, are library content and are copied, as
+// they are from git and archive sources (see signing.HashLibrary).
+func copyDir(src, dest string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("error reading library source: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("library source %s is not a directory, git repository or archive", src)
+	}
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != src && filepath.Dir(path) == filepath.Clean(src) && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeArchiveFile(target, file)
+	})
+}
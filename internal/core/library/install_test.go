@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// libraryFiles returns the files of a small library at the given version
+func libraryFiles(version string) map[string]string {
+	return map[string]string{
+		"library.yaml":          "name: corp\nversion: " + version + "\n",
+		"actions/notice.yaml":   "name: notice\ntype: file\ntemplate_path: notice.tmpl\ntarget_path: NOTICE\n",
+		"templates/notice.tmpl": "Copyright\n",
+	}
+}
+
+func tarGz(t *testing.T, prefix string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: prefix + name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestSourceKind(t *testing.T) {
+	tests := []struct {
+		source, kind, location, ref string
+	}{
+		{"https://example.com/corp.git#v1.2.0", library.SourceGit, "https://example.com/corp.git", "v1.2.0"},
+		{"git+file:///srv/corp#main", library.SourceGit, "file:///srv/corp", "main"},
+		{"git@example.com:org/corp", library.SourceGit, "git@example.com:org/corp", ""},
+		{"https://example.com/corp-1.0.0.tar.gz", library.SourceArchive, "https://example.com/corp-1.0.0.tar.gz", ""},
+		{"./corp.zip", library.SourceArchive, "./corp.zip", ""},
+		{"file:///srv/corp", library.SourceDirectory, "/srv/corp", ""},
+		{"../corp", library.SourceDirectory, "../corp", ""},
+	}
+	for _, tt := range tests {
+		kind, location, ref := library.SourceKind(tt.source)
+		assert.Equal(t, tt.kind, kind, tt.source)
+		assert.Equal(t, tt.location, location, tt.source)
+		assert.Equal(t, tt.ref, ref, tt.source)
+	}
+}
+
+func TestInstallDirectory(t *testing.T) {
+	source := writeLibrary(t, t.TempDir(), "corp", libraryFiles("1.0.0")["library.yaml"], libraryFiles("1.0.0"))
+	cache := t.TempDir()
+	installer := library.NewInstaller(cache, "dev")
+
+	result, err := installer.Install(source, library.InstallOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cache, "corp", "1.0.0"), result.Path)
+	assert.False(t, result.AlreadyInstalled)
+	assert.FileExists(t, filepath.Join(result.Path, "actions", "notice.yaml"))
+
+	again, err := installer.Install(source, library.InstallOptions{Checksum: result.Hash})
+	require.NoError(t, err)
+	assert.True(t, again.AlreadyInstalled)
+
+	_, err = installer.Install(source, library.InstallOptions{Checksum: "sha256:0000"})
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	// Same version, different content
+	require.NoError(t, os.WriteFile(filepath.Join(source, "templates", "notice.tmpl"), []byte("changed\n"), 0644))
+	_, err = installer.Install(source, library.InstallOptions{})
+	assert.ErrorContains(t, err, "already installed")
+	_, err = installer.Install(source, library.InstallOptions{Force: true})
+	require.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(cache, "corp", "1.0.0", "templates", "notice.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed\n", string(content))
+}
+
+func TestInstallDirectoryKeepsHiddenContent(t *testing.T) {
+	files := libraryFiles("1.0.0")
+	files["templates/.github/workflows/x.yml"] = "on: push\n"
+	files[".git/HEAD"] = "ref: refs/heads/main\n"
+	source := writeLibrary(t, t.TempDir(), "corp", files["library.yaml"], files)
+
+	result, err := library.NewInstaller(t.TempDir(), "dev").Install(source, library.InstallOptions{})
+	require.NoError(t, err)
+
+	// Hidden files below the root are content; hidden entries in the root are not
+	content, err := os.ReadFile(filepath.Join(result.Path, "templates", ".github", "workflows", "x.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "on: push\n", string(content))
+	assert.NoDirExists(t, filepath.Join(result.Path, ".git"))
+}
+
+func TestInstallArchiveOverHTTP(t *testing.T) {
+	archive := tarGz(t, "corp-1.1.0/", libraryFiles("1.1.0"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/corp-1.1.0.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	cache := t.TempDir()
+	installer := library.NewInstaller(cache, "dev")
+
+	_, err := installer.Install(server.URL+"/corp-1.1.0.tar.gz", library.InstallOptions{Checksum: "sha256:0000"})
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	result, err := installer.Install(server.URL+"/corp-1.1.0.tar.gz", library.InstallOptions{Checksum: sha256Hex(archive)})
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", result.Manifest.Version)
+	assert.FileExists(t, filepath.Join(cache, "corp", "1.1.0", "templates", "notice.tmpl"))
+
+	_, err = installer.Install(server.URL+"/missing.tar.gz", library.InstallOptions{})
+	assert.ErrorContains(t, err, "status 404")
+}
+
+func TestInstallZip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "corp.zip")
+	require.NoError(t, os.WriteFile(archivePath, zipArchive(t, libraryFiles("2.0.0")), 0644))
+
+	result, err := library.NewInstaller(t.TempDir(), "dev").Install(archivePath, library.InstallOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", result.Manifest.Version)
+
+	// Entries may not escape the library directory
+	evilPath := filepath.Join(dir, "evil.zip")
+	require.NoError(t, os.WriteFile(evilPath, zipArchive(t, map[string]string{"../../escape.txt": "x"}), 0644))
+	_, err = library.NewInstaller(t.TempDir(), "dev").Install(evilPath, library.InstallOptions{})
+	assert.ErrorContains(t, err, "outside the archive root")
+}
+
+func TestInstallRejectsUnsafeManifest(t *testing.T) {
+	tests := []struct {
+		manifest, err string
+	}{
+		{"name: ../../escape\nversion: 1.0.0\n", "invalid name"},
+		{"name: Corp\nversion: 1.0.0\n", "invalid name"},
+		{"name: corp\nversion: 1.0.0-x/../../escape\n", "invalid version"},
+		{"name: corp\nversion: 1.0.0\ndependencies:\n  - name: ../base\n", "invalid dependency name"},
+	}
+	for _, tt := range tests {
+		files := libraryFiles("1.0.0")
+		files["library.yaml"] = tt.manifest
+		source := writeLibrary(t, t.TempDir(), "corp", tt.manifest, files)
+		cache := filepath.Join(t.TempDir(), "cache")
+
+		_, err := library.NewInstaller(cache, "dev").Install(source, library.InstallOptions{Force: true})
+		assert.ErrorContains(t, err, tt.err, tt.manifest)
+		entries, _ := os.ReadDir(filepath.Dir(cache))
+		assert.Len(t, entries, 1, "nothing is written next to the cache")
+	}
+}
+
+func TestInstallChecksDarnVersion(t *testing.T) {
+	files := libraryFiles("1.0.0")
+	files["library.yaml"] = "name: corp\nversion: 1.0.0\nmin_darn_version: 9.0.0\n"
+	source := writeLibrary(t, t.TempDir(), "corp", files["library.yaml"], files)
+
+	_, err := library.NewInstaller(t.TempDir(), "1.0.0").Install(source, library.InstallOptions{})
+	assert.ErrorContains(t, err, "requires darn 9.0.0 or later")
+}
+
+func TestInstallGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+	writeRepo := func(version string) {
+		for name, content := range libraryFiles(version) {
+			path := filepath.Join(repo, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "Release "+version)
+		git("tag", "v"+version)
+	}
+	git("init", "--quiet")
+	writeRepo("1.0.0")
+	writeRepo("1.1.0")
+
+	cache := t.TempDir()
+	installer := library.NewInstaller(cache, "dev")
+
+	result, err := installer.Install("git+file://"+repo+"#v1.0.0", library.InstallOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", result.Manifest.Version)
+	assert.NoDirExists(t, filepath.Join(result.Path, ".git"))
+
+	result, err = installer.Install("git+file://"+repo, library.InstallOptions{Ref: "v1.1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cache, "corp", "1.1.0"), result.Path)
+
+	// A commit that is not a branch or tag needs a full clone
+	commit, err := exec.Command("git", "-C", repo, "rev-parse", "v1.0.0").Output()
+	require.NoError(t, err)
+	result, err = installer.Install("git+file://"+repo, library.InstallOptions{Ref: string(bytes.TrimSpace(commit))})
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", result.Manifest.Version)
+
+	_, err = installer.Install("git+file://"+repo+"#no-such-ref", library.InstallOptions{})
+	assert.ErrorContains(t, err, "error checking out no-such-ref")
+
+	// Sources and refs are never read as git options
+	_, err = installer.Install("git+file://"+repo, library.InstallOptions{Ref: "--orphan=evil"})
+	assert.ErrorContains(t, err, "invalid git ref")
+	_, err = installer.Install("git+--upload-pack=touch", library.InstallOptions{})
+	assert.ErrorContains(t, err, "error cloning --upload-pack=touch")
+}
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile is the path of a project's library lock, relative to the project directory
+const LockFile = ".darn/library.lock"
+
+// lockVersion is the format version written to new lock files
+const lockVersion = 1
+
+// Lock pins the exact version and content of the libraries a project uses.
+// It records no paths, so a committed lock holds on every machine: the
+// project's library is the configured one, and dependencies are found where
+// its manifests place them or in the library cache.
+type Lock struct {
+	LockVersion int             `yaml:"lock_version"`
+	Libraries   []LockedLibrary `yaml:"libraries"` // The project's library first, then its dependencies
+}
+
+// LockedLibrary is one pinned library
+type LockedLibrary struct {
+	Name     string   `yaml:"name" json:"name"`
+	Version  string   `yaml:"version" json:"version"`
+	Hash     string   `yaml:"hash" json:"hash"` // See signing.HashLibrary
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+	// Where the library was found on this machine; not stored in the lock
+	Path string `yaml:"-" json:"path,omitempty"`
+}
+
+// NewLock resolves the library at libraryPath and its dependencies, looking
+// in cacheDir for installed ones, and pins their current versions and content
+func NewLock(libraryPath, darnVersion, cacheDir string) (*Lock, error) {
+	libraries, err := ResolveLibraries(libraryPath, darnVersion, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{LockVersion: lockVersion}
+	for _, lib := range libraries {
+		hash, err := signing.HashLibrary(lib.Path)
+		if err != nil {
+			return nil, err
+		}
+		lock.Libraries = append(lock.Libraries, LockedLibrary{
+			Name:     lib.Manifest.Name,
+			Version:  lib.Manifest.Version,
+			Path:     lib.Path,
+			Hash:     hash,
+			Requires: lib.Requires,
+		})
+	}
+	return lock, nil
+}
+
+// LoadLock reads the library lock of the project at projectDir and finds the
+// locked libraries: the project's library at libraryPath, and each
+// dependency where the library's manifests place it or, failing that, at
+// <cacheDir>/<name>/<version>. It returns nil without an error when the
+// project has no lock.
+func LoadLock(projectDir, libraryPath, cacheDir string) (*Lock, error) {
+	path := filepath.Join(projectDir, LockFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading library lock: %w", err)
+	}
+
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("error parsing library lock %s: %w", path, err)
+	}
+	if lock.LockVersion != lockVersion {
+		return nil, fmt.Errorf("unsupported library lock version %d in %s", lock.LockVersion, path)
+	}
+	if len(lock.Libraries) == 0 {
+		return nil, fmt.Errorf("library lock %s has no libraries", path)
+	}
+	for _, locked := range lock.Libraries {
+		// Names and versions name cache directories, so they get the manifest's checks
+		manifest := Manifest{Name: locked.Name, Version: locked.Version}
+		if err := manifest.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid library lock %s: %w", path, err)
+		}
+	}
+
+	if err := lock.findLibraries(libraryPath, cacheDir); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// findLibraries sets the path of every locked library, as described in LoadLock
+func (l *Lock) findLibraries(libraryPath, cacheDir string) error {
+	absPath, err := filepath.Abs(libraryPath)
+	if err != nil {
+		return fmt.Errorf("error resolving library path %s: %w", libraryPath, err)
+	}
+	l.Libraries[0].Path = absPath
+
+	// A library whose dependencies no longer resolve falls back to the
+	// cache; Verify reports what does not match
+	resolved := make(map[string]ResolvedLibrary)
+	if libraries, err := ResolveLibraries(absPath, "", cacheDir); err == nil {
+		for _, lib := range libraries {
+			resolved[lib.Manifest.Name] = lib
+		}
+	}
+
+	for i := 1; i < len(l.Libraries); i++ {
+		locked := &l.Libraries[i]
+		locked.Path = filepath.Join(cacheDir, locked.Name, locked.Version)
+
+		lib, ok := resolved[locked.Name]
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(locked.Path); lib.Manifest.Version == locked.Version || err != nil {
+			locked.Path = lib.Path
+		}
+	}
+	return nil
+}
+
+// Save writes the lock to the project at projectDir
+func (l *Lock) Save(projectDir string) (string, error) {
+	data, err := yaml.Marshal(l)
+	if err != nil {
+		return "", fmt.Errorf("error encoding library lock: %w", err)
+	}
+
+	path := filepath.Join(projectDir, LockFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("error creating directory for library lock: %w", err)
+	}
+	header := "# Generated by 'darn library lock'. Do not edit.\n"
+	if err := os.WriteFile(path, append([]byte(header), data...), 0644); err != nil {
+		return "", fmt.Errorf("error writing library lock: %w", err)
+	}
+	return path, nil
+}
+
+// Root returns the project's library
+func (l *Lock) Root() LockedLibrary {
+	return l.Libraries[0]
+}
+
+// Dependencies returns the locked dependencies of the project's library
+func (l *Lock) Dependencies() []LockedLibrary {
+	return l.Libraries[1:]
+}
+
+// Verify checks that the project's library is the locked library and that
+// it and every locked dependency still have their locked version and content
+func (l *Lock) Verify() error {
+	root := l.Root()
+	if manifest, err := LoadManifest(root.Path); err == nil && manifest != nil && manifest.Name != root.Name {
+		return fmt.Errorf("library %s is not the locked library %s; run 'darn library lock' to update the lock", root.Path, root.Name)
+	}
+
+	var problems []string
+	for _, locked := range l.Libraries {
+		if err := locked.Verify(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("libraries do not match %s: %s", LockFile, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Verify checks that the library at the locked path has the locked name,
+// version and content. The content covers hidden files below the library's
+// root, such as actions/.hidden.yaml, but not hidden state in the root, such
+// as StateDir.
+func (ll LockedLibrary) Verify() error {
+	manifest, err := LoadManifest(ll.Path)
+	if err != nil {
+		return err
+	}
+	if manifest == nil {
+		return fmt.Errorf("%s: no %s at %s", ll.Name, ManifestFile, ll.Path)
+	}
+	if manifest.Name != ll.Name {
+		return fmt.Errorf("%s: found library %s at %s", ll.Name, manifest.Name, ll.Path)
+	}
+	if manifest.Version != ll.Version {
+		return fmt.Errorf("%s: version %s is locked but %s is installed", ll.Name, ll.Version, manifest.Version)
+	}
+
+	hash, err := signing.HashLibrary(ll.Path)
+	if err != nil {
+		return err
+	}
+	if hash != ll.Hash {
+		return fmt.Errorf("%s %s: content changed since it was locked", ll.Name, ll.Version)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLibrary creates a library with the given manifest and files under root
+func writeLibrary(t *testing.T, root, name, manifest string, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	files[library.ManifestFile] = manifest
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestResolveLibraries(t *testing.T) {
+	root := t.TempDir()
+	corp := writeLibrary(t, root, "corp", `name: corp
+version: 2.1.0
+dependencies:
+  - name: base
+    version: ^1.2.0
+  - name: extras
+    version: ">=0.1"
+    path: vendor/extras
+`, map[string]string{})
+	writeLibrary(t, root, "base", "name: base\nversion: 1.4.0\n", map[string]string{})
+	writeLibrary(t, corp, "vendor/extras", "name: extras\nversion: 0.3.0\ndependencies:\n  - name: base\n    path: ../../../base\n", map[string]string{})
+
+	libraries, err := library.ResolveLibraries(corp, "dev", "")
+	require.NoError(t, err)
+	require.Len(t, libraries, 3)
+	assert.Equal(t, "corp", libraries[0].Manifest.Name)
+	assert.Equal(t, []string{"base", "extras"}, libraries[0].Requires)
+	assert.Equal(t, "base", libraries[1].Manifest.Name)
+	assert.Equal(t, "extras", libraries[2].Manifest.Name)
+	assert.Equal(t, []string{"base"}, libraries[2].Requires)
+}
+
+func TestResolveLibrariesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifest  string
+		base      string
+		darn      string
+		wantError string
+	}{
+		{"no manifest", "", "", "dev", "has no library.yaml"},
+		{"invalid version", "name: app\nversion: latest\n", "", "dev", "invalid version"},
+		{"too old darn", "name: app\nversion: 1.0.0\nmin_darn_version: 0.9.0\n", "", "0.8.1", "requires darn 0.9.0 or later"},
+		{"missing dependency", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", "", "dev", "has no library.yaml"},
+		{"unsatisfied constraint", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n    version: ^2.0.0\n", "name: base\nversion: 1.4.0\n", "dev", "app requires base ^2.0.0"},
+		{"wrong name", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", "name: other\nversion: 1.0.0\n", "dev", "the library at"},
+		{"cycle", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", "name: base\nversion: 1.0.0\ndependencies:\n  - name: app\n", "dev", "dependency cycle: app -> base -> app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			app := filepath.Join(root, "app")
+			require.NoError(t, os.MkdirAll(app, 0755))
+			if tt.manifest != "" {
+				writeLibrary(t, root, "app", tt.manifest, map[string]string{})
+			}
+			if tt.base != "" {
+				writeLibrary(t, root, "base", tt.base, map[string]string{})
+			}
+
+			_, err := library.ResolveLibraries(app, tt.darn, "")
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+}
+
+func TestLock(t *testing.T) {
+	root := t.TempDir()
+	app := writeLibrary(t, root, "app", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", map[string]string{
+		"actions/a.yaml": "name: a\n",
+	})
+	base := writeLibrary(t, root, "base", "name: base\nversion: 1.1.0\n", map[string]string{
+		"actions/b.yaml": "name: b\n",
+	})
+
+	lock, err := library.NewLock(app, "dev", "")
+	require.NoError(t, err)
+	assert.Equal(t, "app", lock.Root().Name)
+	require.Len(t, lock.Dependencies(), 1)
+	assert.Equal(t, "base", lock.Dependencies()[0].Name)
+	assert.Equal(t, "1.1.0", lock.Dependencies()[0].Version)
+
+	project := t.TempDir()
+	cache := t.TempDir()
+	assert.Nil(t, mustLoadLock(t, project, app, cache))
+	lockPath, err := lock.Save(project)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(project, ".darn", "library.lock"), lockPath)
+
+	// The lock holds no paths from this machine
+	data, err := os.ReadFile(lockPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), root)
+
+	loaded := mustLoadLock(t, project, app, cache)
+	require.NotNil(t, loaded)
+	assert.Equal(t, lock, loaded)
+	require.NoError(t, loaded.Verify())
+
+	assert.ErrorContains(t, mustLoadLock(t, project, base, cache).Verify(), "is not the locked library app")
+
+	// Hidden files in library directories are content; hidden state in the root is not
+	hidden := filepath.Join(base, "actions", ".hidden.yaml")
+	require.NoError(t, os.WriteFile(hidden, []byte("name: hidden\n"), 0644))
+	assert.ErrorContains(t, loaded.Verify(), "base 1.1.0: content changed since it was locked")
+	require.NoError(t, os.Remove(hidden))
+	require.NoError(t, os.MkdirAll(filepath.Join(base, library.StateDir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(base, library.StateDir, "files.yaml"), []byte("files: {}\n"), 0644))
+	require.NoError(t, loaded.Verify())
+
+	// Changed content and versions are both reported
+	require.NoError(t, os.WriteFile(filepath.Join(base, "actions", "b.yaml"), []byte("name: evil\n"), 0644))
+	assert.ErrorContains(t, loaded.Verify(), "base 1.1.0: content changed since it was locked")
+
+	require.NoError(t, os.WriteFile(filepath.Join(app, library.ManifestFile), []byte("name: app\nversion: 1.1.0\ndependencies:\n  - name: base\n"), 0644))
+	assert.ErrorContains(t, loaded.Verify(), "app: version 1.0.0 is locked but 1.1.0 is installed")
+}
+
+func TestLockOnAnotherMachine(t *testing.T) {
+	root := t.TempDir()
+	app := writeLibrary(t, root, "app", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", map[string]string{
+		"actions/a.yaml": "name: a\n",
+	})
+	writeLibrary(t, root, "base", "name: base\nversion: 1.1.0\n", map[string]string{
+		"actions/b.yaml": "name: b\n",
+	})
+
+	lock, err := library.NewLock(app, "dev", "")
+	require.NoError(t, err)
+	project := t.TempDir()
+	_, err = lock.Save(project)
+	require.NoError(t, err)
+
+	// Elsewhere, the library is configured at another path and its
+	// dependency is installed in the cache
+	other := t.TempDir()
+	otherApp := writeLibrary(t, other, "library", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n", map[string]string{
+		"actions/a.yaml": "name: a\n",
+	})
+	cache := t.TempDir()
+	cachedBase := writeLibrary(t, cache, filepath.Join("base", "1.1.0"), "name: base\nversion: 1.1.0\n", map[string]string{
+		"actions/b.yaml": "name: b\n",
+	})
+
+	loaded := mustLoadLock(t, project, otherApp, cache)
+	assert.Equal(t, otherApp, loaded.Root().Path)
+	assert.Equal(t, cachedBase, loaded.Dependencies()[0].Path)
+	require.NoError(t, loaded.Verify())
+
+	// Without the dependency the lock is not satisfied
+	require.NoError(t, os.RemoveAll(cachedBase))
+	assert.ErrorContains(t, mustLoadLock(t, project, otherApp, cache).Verify(), "base: no library.yaml")
+}
+
+func TestLockInstalledLibrary(t *testing.T) {
+	sources := t.TempDir()
+	cache := t.TempDir()
+	installer := library.NewInstaller(cache, "dev")
+	for _, version := range []string{"1.1.0", "1.2.0", "2.0.0"} {
+		base := writeLibrary(t, sources, "base-"+version, "name: base\nversion: "+version+"\n", map[string]string{
+			"actions/b.yaml": "name: b\n",
+		})
+		_, err := installer.Install(base, library.InstallOptions{})
+		require.NoError(t, err)
+	}
+	app := writeLibrary(t, sources, "app", "name: app\nversion: 1.0.0\ndependencies:\n  - name: base\n    version: ^1.1.0\n", map[string]string{
+		"actions/a.yaml": "name: a\n",
+	})
+	installed, err := installer.Install(app, library.InstallOptions{})
+	require.NoError(t, err)
+
+	// The dependency is not next to the installed library, so the newest
+	// cached version that satisfies the constraint is used
+	lock, err := library.NewLock(installed.Path, "dev", cache)
+	require.NoError(t, err)
+	require.Len(t, lock.Dependencies(), 1)
+	assert.Equal(t, "1.2.0", lock.Dependencies()[0].Version)
+	assert.Equal(t, filepath.Join(cache, "base", "1.2.0"), lock.Dependencies()[0].Path)
+
+	project := t.TempDir()
+	_, err = lock.Save(project)
+	require.NoError(t, err)
+	require.NoError(t, mustLoadLock(t, project, installed.Path, cache).Verify())
+
+	// Without the cache the dependency cannot be found
+	_, err = library.NewLock(installed.Path, "dev", "")
+	assert.ErrorContains(t, err, "has no library.yaml")
+}
+
+func TestLoadLockRejectsUnsafeNames(t *testing.T) {
+	project := t.TempDir()
+	lockPath := filepath.Join(project, library.LockFile)
+	require.NoError(t, os.MkdirAll(filepath.Dir(lockPath), 0755))
+	lock := "lock_version: 1\nlibraries:\n  - name: app\n    version: 1.0.0\n  - name: ../../etc\n    version: 1.0.0\n"
+	require.NoError(t, os.WriteFile(lockPath, []byte(lock), 0644))
+
+	_, err := library.LoadLock(project, t.TempDir(), t.TempDir())
+	assert.ErrorContains(t, err, `invalid name "../../etc"`)
+}
+
+func mustLoadLock(t *testing.T, projectDir, libraryPath, cacheDir string) *library.Lock {
+	t.Helper()
+	lock, err := library.LoadLock(projectDir, libraryPath, cacheDir)
+	require.NoError(t, err)
+	return lock
+}
@@ -0,0 +1,249 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/semver"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the name of a library's manifest, in the library root
+const ManifestFile = "library.yaml"
+
+// nameRegex matches library names. Names and versions name directories in
+// the cache, so neither may hold path separators or "..".
+var nameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Manifest describes a library package
+type Manifest struct {
+	Name           string       `yaml:"name"`
+	Version        string       `yaml:"version"`
+	Description    string       `yaml:"description,omitempty"`
+	MinDarnVersion string       `yaml:"min_darn_version,omitempty"`
+	Dependencies   []Dependency `yaml:"dependencies,omitempty"`
+}
+
+// Dependency is another library a library needs
+type Dependency struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version,omitempty"` // Semver constraint, such as ^1.2.0
+	// Directory of the dependency, relative to the depending library.
+	// Defaults to a sibling directory named after the dependency or, when
+	// there is none, the newest satisfying version in the library cache.
+	Path string `yaml:"path,omitempty"`
+}
+
+// LoadManifest reads and validates the manifest of the library at dir. It
+// returns nil without an error when the library has no manifest.
+func LoadManifest(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, ManifestFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading library manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing library manifest %s: %w", path, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid library manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// Validate checks that the manifest has a valid name and valid versions
+func (m *Manifest) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !nameRegex.MatchString(m.Name) {
+		return fmt.Errorf("invalid name %q: use lowercase letters, digits, '.', '_' and '-'", m.Name)
+	}
+	if m.Version == "" {
+		return fmt.Errorf("version is required")
+	}
+	if _, err := semver.Parse(m.Version); err != nil {
+		return err
+	}
+	if strings.ContainsAny(m.Version, `/\`) || strings.Contains(m.Version, "..") {
+		return fmt.Errorf("invalid version %q", m.Version)
+	}
+	if m.MinDarnVersion != "" {
+		if _, err := semver.Parse(m.MinDarnVersion); err != nil {
+			return fmt.Errorf("min_darn_version: %w", err)
+		}
+	}
+	for _, dep := range m.Dependencies {
+		if dep.Name == "" {
+			return fmt.Errorf("dependency name is required")
+		}
+		if !nameRegex.MatchString(dep.Name) {
+			return fmt.Errorf("invalid dependency name %q", dep.Name)
+		}
+		if _, err := semver.ParseConstraint(dep.Version); err != nil {
+			return fmt.Errorf("dependency %s: %w", dep.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckDarnVersion returns an error if darnVersion is older than the
+// library's min_darn_version. Development builds, whose version is not a
+// semantic version, are always accepted.
+func (m *Manifest) CheckDarnVersion(darnVersion string) error {
+	if m.MinDarnVersion == "" {
+		return nil
+	}
+	current, err := semver.Parse(darnVersion)
+	if err != nil {
+		return nil
+	}
+	minimum, err := semver.Parse(m.MinDarnVersion)
+	if err != nil {
+		return err
+	}
+	if current.Compare(minimum) < 0 {
+		return fmt.Errorf("library %s %s requires darn %s or later (this is %s)", m.Name, m.Version, m.MinDarnVersion, darnVersion)
+	}
+	return nil
+}
+
+// ResolvedLibrary is a library found while resolving dependencies
+type ResolvedLibrary struct {
+	Manifest *Manifest
+	Path     string   // Absolute path of the library
+	Requires []string // Names of the libraries it depends on
+}
+
+// ResolveLibraries returns the library at dir followed by its dependencies,
+// transitively, each exactly once. Every library needs a manifest, a version
+// that satisfies all constraints on it and a min_darn_version no newer than
+// darnVersion. Dependencies without a path that are not next to the library
+// are looked up in cacheDir, laid out as <cacheDir>/<name>/<version>.
+func ResolveLibraries(dir, darnVersion, cacheDir string) ([]ResolvedLibrary, error) {
+	r := &dependencyResolver{darnVersion: darnVersion, cacheDir: cacheDir, byName: make(map[string]int)}
+	if _, err := r.resolve(dir, nil, nil); err != nil {
+		return nil, err
+	}
+	return r.libraries, nil
+}
+
+type dependencyResolver struct {
+	darnVersion string
+	cacheDir    string
+	libraries   []ResolvedLibrary
+	byName      map[string]int // Index into libraries
+}
+
+// resolve adds the library at dir, which is required as dep by the last
+// library on the path, and its dependencies
+func (r *dependencyResolver) resolve(dir string, dep *Dependency, path []string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("error resolving library path %s: %w", dir, err)
+	}
+
+	manifest, err := LoadManifest(absDir)
+	if err != nil {
+		return "", err
+	}
+	if manifest == nil {
+		return "", fmt.Errorf("library at %s has no %s", absDir, ManifestFile)
+	}
+
+	if dep != nil {
+		if manifest.Name != dep.Name {
+			return "", fmt.Errorf("%s depends on %s, but the library at %s is %s", path[len(path)-1], dep.Name, absDir, manifest.Name)
+		}
+		constraint, _ := semver.ParseConstraint(dep.Version)
+		version, _ := semver.Parse(manifest.Version)
+		if !constraint.Check(version) {
+			return "", fmt.Errorf("%s requires %s %s, but %s is version %s", path[len(path)-1], dep.Name, constraint, absDir, manifest.Version)
+		}
+	}
+	for _, name := range path {
+		if name == manifest.Name {
+			return "", fmt.Errorf("dependency cycle: %s -> %s", strings.Join(path, " -> "), manifest.Name)
+		}
+	}
+
+	if i, ok := r.byName[manifest.Name]; ok {
+		if existing := r.libraries[i]; existing.Path != absDir {
+			return "", fmt.Errorf("library %s is required from both %s and %s", manifest.Name, existing.Path, absDir)
+		}
+		return manifest.Name, nil
+	}
+
+	if err := manifest.CheckDarnVersion(r.darnVersion); err != nil {
+		return "", err
+	}
+
+	r.byName[manifest.Name] = len(r.libraries)
+	r.libraries = append(r.libraries, ResolvedLibrary{Manifest: manifest, Path: absDir})
+	index := len(r.libraries) - 1
+
+	path = append(path, manifest.Name)
+	var requires []string
+	for i := range manifest.Dependencies {
+		dependency := &manifest.Dependencies[i]
+		depDir := dependency.Path
+		if depDir == "" {
+			depDir = r.findDependency(absDir, dependency)
+		}
+		if !filepath.IsAbs(depDir) {
+			depDir = filepath.Join(absDir, depDir)
+		}
+
+		name, err := r.resolve(depDir, dependency, path)
+		if err != nil {
+			return "", err
+		}
+		requires = append(requires, name)
+	}
+	r.libraries[index].Requires = requires
+
+	return manifest.Name, nil
+}
+
+// findDependency returns the directory of a dependency without a path: the
+// sibling of the library at dir named after it or, when there is no such
+// directory, the newest cached version that satisfies its constraint. A
+// dependency found in neither place resolves to the sibling, which reports
+// the error.
+func (r *dependencyResolver) findDependency(dir string, dep *Dependency) string {
+	sibling := filepath.Join(dir, "..", dep.Name)
+	if _, err := os.Stat(sibling); err == nil || r.cacheDir == "" {
+		return sibling
+	}
+
+	entries, err := os.ReadDir(filepath.Join(r.cacheDir, dep.Name))
+	if err != nil {
+		return sibling
+	}
+	constraint, _ := semver.ParseConstraint(dep.Version)
+	var newest string
+	var newestVersion semver.Version
+	for _, entry := range entries {
+		version, err := semver.Parse(entry.Name())
+		if !entry.IsDir() || err != nil || !constraint.Check(version) {
+			continue
+		}
+		if newest == "" || version.Compare(newestVersion) > 0 {
+			newest, newestVersion = entry.Name(), version
+		}
+	}
+	if newest == "" {
+		return sibling
+	}
+	return filepath.Join(r.cacheDir, dep.Name, newest)
+}
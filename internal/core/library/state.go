@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateDir holds the updater's record of the files it installed, relative
+// to the library root. It is hidden, so it is not part of library
+// signatures or lock hashes.
+const StateDir = ".darn"
+
+// ConflictsDir holds the .new and .orig files of unresolved update
+// conflicts, under StateDir, by the conflicting file's path
+const ConflictsDir = "conflicts"
+
+const (
+	installedFilesName = "files.yaml" // Hash of every installed file
+	baseDirName        = "base"       // Copy of every installed file, for three-way merges
+)
+
+// managedDirs are the library directories the updater manages
+var managedDirs = []string{"templates", "actions", "configs", "mappings"}
+
+// installedFiles records the content of each file as the updater last installed it
+type installedFiles struct {
+	// SHA-256 by slash-separated path relative to the library root
+	Files map[string]string `yaml:"files"`
+	// SHA-256 of the upstream version of each file with an unresolved conflict
+	Conflicts map[string]string `yaml:"conflicts,omitempty"`
+}
+
+// hashContent returns the hex SHA-256 of content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadInstalledFiles reads the record of installed files of the library at
+// libraryPath; a library without one has an empty record
+func loadInstalledFiles(libraryPath string) (*installedFiles, error) {
+	installed := &installedFiles{Files: make(map[string]string), Conflicts: make(map[string]string)}
+
+	data, err := os.ReadFile(filepath.Join(libraryPath, StateDir, installedFilesName))
+	if os.IsNotExist(err) {
+		return installed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading installed files: %w", err)
+	}
+	if err := yaml.Unmarshal(data, installed); err != nil {
+		return nil, fmt.Errorf("error parsing installed files: %w", err)
+	}
+	if installed.Files == nil {
+		installed.Files = make(map[string]string)
+	}
+	if installed.Conflicts == nil {
+		installed.Conflicts = make(map[string]string)
+	}
+	return installed, nil
+}
+
+// save writes the record to the library at libraryPath
+func (f *installedFiles) save(libraryPath string) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("error encoding installed files: %w", err)
+	}
+
+	path := filepath.Join(libraryPath, StateDir, installedFilesName)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating library state directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing installed files: %w", err)
+	}
+	return nil
+}
+
+// record notes that content was installed at key and keeps a copy of it as the base for later merges
+func (f *installedFiles) record(libraryPath, key string, content []byte) error {
+	basePath := filepath.Join(libraryPath, StateDir, baseDirName, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(basePath), 0755); err != nil {
+		return fmt.Errorf("error creating base directory: %w", err)
+	}
+	if err := os.WriteFile(basePath, content, 0644); err != nil {
+		return fmt.Errorf("error writing base copy of %s: %w", key, err)
+	}
+
+	f.Files[key] = hashContent(content)
+	return nil
+}
+
+// forget removes key, its base copy and any conflict from the record
+func (f *installedFiles) forget(libraryPath, key string) error {
+	basePath := filepath.Join(libraryPath, StateDir, baseDirName, filepath.FromSlash(key))
+	if err := os.Remove(basePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing base copy of %s: %w", key, err)
+	}
+	removeEmptyDirs(filepath.Dir(basePath), filepath.Join(libraryPath, StateDir, baseDirName))
+
+	delete(f.Files, key)
+	return f.resolveConflict(libraryPath, key)
+}
+
+// ConflictPath returns the path of the conflict file with the given suffix
+// (NewSuffix or OrigSuffix) for key, a slash-separated path relative to the
+// library root
+func ConflictPath(libraryPath, key, suffix string) string {
+	return filepath.Join(libraryPath, StateDir, ConflictsDir, filepath.FromSlash(key)+suffix)
+}
+
+// recordConflict notes that key conflicts with the upstream content and
+// writes the upstream content and, when there is one, the base next to
+// each other under the conflicts directory
+func (f *installedFiles) recordConflict(libraryPath, key string, upstream, base []byte) error {
+	newPath := ConflictPath(libraryPath, key, NewSuffix)
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return fmt.Errorf("error creating conflicts directory: %w", err)
+	}
+	if err := os.WriteFile(newPath, upstream, 0644); err != nil {
+		return fmt.Errorf("error writing upstream version of %s: %w", key, err)
+	}
+	if base != nil {
+		if err := os.WriteFile(ConflictPath(libraryPath, key, OrigSuffix), base, 0644); err != nil {
+			return fmt.Errorf("error writing installed version of %s: %w", key, err)
+		}
+	}
+
+	f.Conflicts[key] = hashContent(upstream)
+	return nil
+}
+
+// resolveConflict removes key's conflict files and its conflict from the record
+func (f *installedFiles) resolveConflict(libraryPath, key string) error {
+	if _, ok := f.Conflicts[key]; !ok {
+		return nil
+	}
+	for _, suffix := range []string{NewSuffix, OrigSuffix} {
+		if err := os.Remove(ConflictPath(libraryPath, key, suffix)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing conflict file of %s: %w", key, err)
+		}
+	}
+	removeEmptyDirs(filepath.Dir(ConflictPath(libraryPath, key, NewSuffix)), filepath.Join(libraryPath, StateDir, ConflictsDir))
+
+	delete(f.Conflicts, key)
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents while they are empty. The
+// top-level directories under root, such as actions, are always kept.
+func removeEmptyDirs(dir, root string) {
+	for {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == "." || !strings.Contains(filepath.ToSlash(rel), "/") || strings.HasPrefix(rel, "..") {
+			return
+		}
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// base returns the content installed at key, if its copy is still intact
+func (f *installedFiles) base(libraryPath, key string) ([]byte, bool) {
+	hash, ok := f.Files[key]
+	if !ok {
+		return nil, false
+	}
+	content, err := os.ReadFile(filepath.Join(libraryPath, StateDir, baseDirName, filepath.FromSlash(key)))
+	if err != nil || hashContent(content) != hash {
+		return nil, false
+	}
+	return content, true
+}
+
+// RecordInstalledFiles records the current content of every file in the
+// library's managed directories as installed, so that later updates can
+// tell local changes from upstream ones. It is used after a library is
+// populated without the updater, such as by 'darn library init'.
+func RecordInstalledFiles(libraryPath string) error {
+	installed, err := loadInstalledFiles(libraryPath)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range managedDirs {
+		root := filepath.Join(libraryPath, dir)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			relPath, err := filepath.Rel(libraryPath, path)
+			if err != nil {
+				return err
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return installed.record(libraryPath, filepath.ToSlash(relPath), content)
+		})
+		if err != nil {
+			return fmt.Errorf("error recording installed files: %w", err)
+		}
+	}
+
+	return installed.save(libraryPath)
+}
@@ -7,8 +7,34 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
 	"time"
+
+	"github.com/kusari-oss/darn/internal/core/diff"
+	"github.com/kusari-oss/darn/internal/core/semver"
+	"github.com/kusari-oss/darn/internal/version"
+)
+
+// FileStatus describes what an update did to a library file
+type FileStatus string
+
+const (
+	FileCreated     FileStatus = "created"     // Not in the library yet
+	FileUpdated     FileStatus = "updated"     // Changed upstream only
+	FileOverwritten FileStatus = "overwritten" // Replaced by --force
+	FileMerged      FileStatus = "merged"      // Changed on both sides without conflicts
+	FileKept        FileStatus = "kept"        // Changed locally only
+	FileConflict    FileStatus = "conflict"    // Changed on both sides with conflicts
+	FileUnchanged   FileStatus = "unchanged"   // Same as upstream
+	FileStale       FileStatus = "stale"       // Removed upstream, kept until --prune
+	FileRemoved     FileStatus = "removed"     // Removed upstream and pruned
+)
+
+// Suffixes of the files written for a conflicting library file, under
+// StateDir/ConflictsDir (see ConflictPath)
+const (
+	NewSuffix  = ".new"  // The upstream version
+	OrigSuffix = ".orig" // The version last installed
 )
 
 // Updater handles the updating of library files
@@ -28,12 +54,26 @@ type Updater struct {
 	// Enable verbose output
 	verbose bool
 
+	// Delete files that were removed from the source
+	prune bool
+
+	// Content of the library's files as last installed
+	installed *installedFiles
+
+	// Files found in the source, by slash-separated path relative to the library root
+	seen map[string]bool
+
 	// Stats for tracking updates
 	stats struct {
-		Created  int
-		Updated  int
-		Skipped  int
-		Examined int
+		Examined  int
+		Created   int
+		Updated   int
+		Merged    int
+		Kept      int
+		Conflicts int
+		Unchanged int
+		Stale     int
+		Removed   int
 	}
 }
 
@@ -48,6 +88,14 @@ func NewUpdater(libraryPath, sourceDir string, force, dryRun, verbose bool) *Upd
 	}
 }
 
+// WithPrune makes the update delete library files that were removed from
+// the source. Only files the updater installed are ever deleted, and only
+// while they have no local changes.
+func (u *Updater) WithPrune(prune bool) *Updater {
+	u.prune = prune
+	return u
+}
+
 // UpdateLibrary updates the library with files from the source directory
 func (u *Updater) UpdateLibrary() error {
 	// Ensure the library path exists
@@ -55,6 +103,18 @@ func (u *Updater) UpdateLibrary() error {
 		return fmt.Errorf("error creating library directory: %w", err)
 	}
 
+	// Check the new version before changing anything
+	if err := u.updateManifest(); err != nil {
+		return err
+	}
+
+	installed, err := loadInstalledFiles(u.libraryPath)
+	if err != nil {
+		return err
+	}
+	u.installed = installed
+	u.seen = make(map[string]bool)
+
 	// Update templates
 	if err := u.updateDirectory("templates", "templates"); err != nil {
 		return err
@@ -75,19 +135,47 @@ func (u *Updater) UpdateLibrary() error {
 		return err
 	}
 
+	// Handle files removed upstream
+	if err := u.removeDeleted(); err != nil {
+		return err
+	}
+
 	// Print summary
 	if u.dryRun {
 		fmt.Println("\nDRY RUN SUMMARY:")
 		fmt.Printf("Would examine: %d files\n", u.stats.Examined)
 		fmt.Printf("Would create: %d files\n", u.stats.Created)
 		fmt.Printf("Would update: %d files\n", u.stats.Updated)
-		fmt.Printf("Would skip: %d files\n", u.stats.Skipped)
+		fmt.Printf("Would merge: %d files\n", u.stats.Merged)
+		fmt.Printf("Would keep local changes: %d files\n", u.stats.Kept)
+		fmt.Printf("Would conflict: %d files\n", u.stats.Conflicts)
+		fmt.Printf("Unchanged: %d files\n", u.stats.Unchanged)
+		fmt.Printf("Would remove: %d files\n", u.stats.Removed)
+		fmt.Printf("Removed upstream: %d files\n", u.stats.Stale)
 	} else {
 		fmt.Println("\nUPDATE SUMMARY:")
 		fmt.Printf("Examined: %d files\n", u.stats.Examined)
 		fmt.Printf("Created: %d files\n", u.stats.Created)
 		fmt.Printf("Updated: %d files\n", u.stats.Updated)
-		fmt.Printf("Skipped: %d files\n", u.stats.Skipped)
+		fmt.Printf("Merged: %d files\n", u.stats.Merged)
+		fmt.Printf("Kept local changes: %d files\n", u.stats.Kept)
+		fmt.Printf("Conflicts: %d files\n", u.stats.Conflicts)
+		fmt.Printf("Unchanged: %d files\n", u.stats.Unchanged)
+		fmt.Printf("Removed: %d files\n", u.stats.Removed)
+		fmt.Printf("Removed upstream: %d files\n", u.stats.Stale)
+	}
+	if u.stats.Conflicts > 0 && !u.dryRun {
+		fmt.Printf("\nConflicting files were left unchanged. Compare each with its %s (upstream) and %s (last installed) files in %s, resolve it by hand, then delete the %s file.\n",
+			NewSuffix, OrigSuffix, filepath.Join(u.libraryPath, StateDir, ConflictsDir), NewSuffix)
+	}
+	if u.stats.Stale > 0 && !u.prune {
+		fmt.Println("\nFiles removed upstream are still in the library; use --prune to delete them.")
+	}
+
+	if !u.dryRun {
+		if err := u.installed.save(u.libraryPath); err != nil {
+			return err
+		}
 	}
 
 	// Update the state file to record the last update time
@@ -113,13 +201,6 @@ func (u *Updater) updateDirectory(sourceSubdir, targetSubdir string) error {
 		return nil // Skip if source doesn't exist
 	}
 
-	// Ensure target directory exists
-	if !u.dryRun {
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			return fmt.Errorf("error creating target directory %s: %w", targetDir, err)
-		}
-	}
-
 	// Walk the source directory
 	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -138,85 +219,257 @@ func (u *Updater) updateDirectory(sourceSubdir, targetSubdir string) error {
 		if err != nil {
 			return fmt.Errorf("error computing relative path: %w", err)
 		}
+		key := filepath.ToSlash(filepath.Join(targetSubdir, relPath))
+		u.seen[key] = true
+
+		status, err := u.updateFile(path, filepath.Join(targetDir, relPath), key)
+		if err != nil {
+			return fmt.Errorf("error updating %s: %w", key, err)
+		}
+		u.report(status, key)
 
-		// Compute target path
-		targetPath := filepath.Join(targetDir, relPath)
+		return nil
+	})
+}
 
-		// Check if update is needed
-		needsUpdate, reason := u.needsUpdate(path, targetPath, info)
-		if !needsUpdate {
-			u.stats.Skipped++
-			if u.verbose {
-				fmt.Printf("Skipping %s: %s\n", relPath, reason)
+// updateFile brings the library file at targetPath up to date with the
+// source file at sourcePath and returns what it did. The content recorded
+// for key when the file was last installed tells local changes from
+// upstream ones: a file changed only upstream is replaced, a file changed
+// only locally is kept, and a file changed on both sides is merged, or left
+// alone with .new and .orig files under the conflicts directory when the
+// changes conflict. A conflict stays unresolved, and the last installed
+// content stays recorded, until its .new file is deleted.
+func (u *Updater) updateFile(sourcePath, targetPath, key string) (FileStatus, error) {
+	source, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	sourceHash := hashContent(source)
+
+	local, err := os.ReadFile(targetPath)
+	if os.IsNotExist(err) {
+		if err := u.resolveConflict(key); err != nil {
+			return "", err
+		}
+		return FileCreated, u.install(key, targetPath, source, source)
+	}
+	if err != nil {
+		return "", err
+	}
+	localHash := hashContent(local)
+
+	if upstreamHash, pending := u.installed.Conflicts[key]; pending {
+		_, err := os.Stat(ConflictPath(u.libraryPath, key, NewSuffix))
+		switch {
+		case localHash == sourceHash || u.force:
+			// Settled by taking the upstream version
+		case err == nil:
+			base, _ := u.installed.base(u.libraryPath, key)
+			return FileConflict, u.conflict(key, source, base)
+		case upstreamHash == sourceHash:
+			// Resolved by hand; the resolution includes the upstream version
+			if err := u.record(key, source); err != nil {
+				return "", err
 			}
-			return nil
 		}
+		if err := u.resolveConflict(key); err != nil {
+			return "", err
+		}
+	}
 
-		// Ensure target directory exists
-		targetDirPath := filepath.Dir(targetPath)
-		if !u.dryRun {
-			if err := os.MkdirAll(targetDirPath, 0755); err != nil {
-				return fmt.Errorf("error creating directory %s: %w", targetDirPath, err)
-			}
+	recorded, known := u.installed.Files[key]
+	switch {
+	case localHash == sourceHash:
+		// Already current; make sure the next update knows it
+		if recorded != sourceHash {
+			return FileUnchanged, u.record(key, source)
 		}
+		return FileUnchanged, nil
+	case u.force:
+		return FileOverwritten, u.install(key, targetPath, source, source)
+	case known && localHash == recorded:
+		return FileUpdated, u.install(key, targetPath, source, source)
+	case known && sourceHash == recorded:
+		return FileKept, nil
+	}
 
-		// Copy the file
-		if u.dryRun {
-			fmt.Printf("Would %s: %s -> %s\n", reason, relPath, targetPath)
-			if reason == "create" {
-				u.stats.Created++
-			} else {
-				u.stats.Updated++
-			}
-		} else {
-			if err := u.copyFile(path, targetPath); err != nil {
-				return fmt.Errorf("error copying %s: %w", relPath, err)
-			}
+	// Changed on both sides, or never recorded; merge against the installed copy when there is one
+	base, hasBase := u.installed.base(u.libraryPath, key)
+	if hasBase {
+		if merged, conflict := diff.Merge3(base, local, source, "local", "upstream"); !conflict {
+			return FileMerged, u.install(key, targetPath, merged, source)
+		}
+	}
+
+	return FileConflict, u.conflict(key, source, base)
+}
+
+// conflict writes the conflict files for key; it does nothing in dry run
+// mode. The recorded content is left as it is until the conflict is resolved.
+func (u *Updater) conflict(key string, source, base []byte) error {
+	if u.dryRun {
+		return nil
+	}
+	return u.installed.recordConflict(u.libraryPath, key, source, base)
+}
 
-			if u.verbose {
-				fmt.Printf("%s: %s\n", strings.Title(reason), relPath)
+// resolveConflict removes the conflict files for key; it does nothing in dry run mode
+func (u *Updater) resolveConflict(key string) error {
+	if u.dryRun {
+		return nil
+	}
+	return u.installed.resolveConflict(u.libraryPath, key)
+}
+
+// install writes content to targetPath and records base as its installed
+// content; it does nothing in dry run mode
+func (u *Updater) install(key, targetPath string, content, base []byte) error {
+	if u.dryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", filepath.Dir(targetPath), err)
+	}
+	if err := os.WriteFile(targetPath, content, 0644); err != nil {
+		return err
+	}
+	return u.record(key, base)
+}
+
+// record notes base as the installed content of key; it does nothing in dry run mode
+func (u *Updater) record(key string, base []byte) error {
+	if u.dryRun {
+		return nil
+	}
+	return u.installed.record(u.libraryPath, key, base)
+}
+
+// report prints what happened to a file and counts it
+func (u *Updater) report(status FileStatus, key string) {
+	switch status {
+	case FileCreated:
+		u.stats.Created++
+	case FileUpdated, FileOverwritten:
+		u.stats.Updated++
+	case FileMerged:
+		u.stats.Merged++
+	case FileKept:
+		u.stats.Kept++
+	case FileConflict:
+		u.stats.Conflicts++
+	case FileUnchanged:
+		u.stats.Unchanged++
+		if !u.verbose {
+			return
+		}
+	case FileStale:
+		u.stats.Stale++
+	case FileRemoved:
+		u.stats.Removed++
+	}
+	fmt.Printf("%-11s %s\n", status, key)
+}
+
+// removeDeleted handles the files the updater installed that are no longer
+// in the source. They are reported as stale, or deleted with prune unless
+// they were changed locally; a changed file is kept and no longer managed.
+// Files the updater never installed are not considered at all.
+func (u *Updater) removeDeleted() error {
+	keys := make([]string, 0, len(u.installed.Files))
+	for key := range u.installed.Files {
+		if !u.seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		targetPath := filepath.Join(u.libraryPath, filepath.FromSlash(key))
+		local, err := os.ReadFile(targetPath)
+		if os.IsNotExist(err) {
+			// Already deleted locally
+			if err := u.forget(key); err != nil {
+				return err
 			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", key, err)
+		}
 
-			if reason == "create" {
-				u.stats.Created++
-			} else {
-				u.stats.Updated++
+		if !u.prune {
+			u.report(FileStale, key)
+			continue
+		}
+		if hashContent(local) != u.installed.Files[key] {
+			u.report(FileKept, key)
+			if err := u.forget(key); err != nil {
+				return err
 			}
+			continue
 		}
 
+		u.report(FileRemoved, key)
+		if u.dryRun {
+			continue
+		}
+		if err := os.Remove(targetPath); err != nil {
+			return fmt.Errorf("error removing %s: %w", key, err)
+		}
+		removeEmptyDirs(filepath.Dir(targetPath), u.libraryPath)
+		if err := u.forget(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget stops managing key; it does nothing in dry run mode
+func (u *Updater) forget(key string) error {
+	if u.dryRun {
 		return nil
-	})
+	}
+	return u.installed.forget(u.libraryPath, key)
 }
 
-// needsUpdate checks if a file needs to be updated
-func (u *Updater) needsUpdate(sourcePath, targetPath string, sourceInfo os.FileInfo) (bool, string) {
-	// Check if target file exists
-	targetInfo, err := os.Stat(targetPath)
-	if os.IsNotExist(err) {
-		return true, "create" // File doesn't exist in target
+// updateManifest checks that the source's library manifest can replace the
+// library's and copies it. Updating to an older version or to a different
+// library requires force.
+func (u *Updater) updateManifest() error {
+	sourceManifest, err := LoadManifest(u.sourceDir)
+	if err != nil || sourceManifest == nil {
+		return err
 	}
-	if err != nil {
-		// Some other error occurred, better update
-		return true, "update (error checking target)"
+	if err := sourceManifest.CheckDarnVersion(version.Version); err != nil {
+		return err
 	}
 
-	// If force is true, always update
-	if u.force {
-		return true, "update (forced)"
+	current, err := LoadManifest(u.libraryPath)
+	if err != nil && !u.force {
+		return fmt.Errorf("error reading current library manifest (use --force to replace it): %w", err)
 	}
-
-	// Compare modification times
-	if sourceInfo.ModTime().After(targetInfo.ModTime()) {
-		return true, "update (newer)"
+	if current != nil {
+		if current.Name != sourceManifest.Name && !u.force {
+			return fmt.Errorf("cannot update library %s with library %s (use --force to replace it)", current.Name, sourceManifest.Name)
+		}
+		currentVersion, _ := semver.Parse(current.Version)
+		newVersion, _ := semver.Parse(sourceManifest.Version)
+		if newVersion.Compare(currentVersion) < 0 && !u.force {
+			return fmt.Errorf("library %s %s is older than the installed %s (use --force to downgrade)", sourceManifest.Name, sourceManifest.Version, current.Version)
+		}
+		fmt.Printf("Library %s: %s -> %s\n", sourceManifest.Name, current.Version, sourceManifest.Version)
+	} else {
+		fmt.Printf("Library %s: %s\n", sourceManifest.Name, sourceManifest.Version)
 	}
 
-	// Compare file sizes
-	if sourceInfo.Size() != targetInfo.Size() {
-		return true, "update (size different)"
+	if u.dryRun {
+		return nil
 	}
-
-	// Files appear identical
-	return false, "files identical"
+	if err := u.copyFile(filepath.Join(u.sourceDir, ManifestFile), filepath.Join(u.libraryPath, ManifestFile)); err != nil {
+		return fmt.Errorf("error copying library manifest: %w", err)
+	}
+	return nil
 }
 
 // copyFile copies a file from source to target
@@ -253,6 +506,6 @@ func (u *Updater) copyFile(sourcePath, targetPath string) error {
 func (u *Updater) updateStateFile() error {
 	stateFile := filepath.Join(u.libraryPath, ".last_updated")
 	timestamp := time.Now().Format(time.RFC3339)
-	
+
 	return os.WriteFile(stateFile, []byte(timestamp), 0644)
 }
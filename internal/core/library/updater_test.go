@@ -0,0 +1,185 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package library_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes files relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestUpdater_ThreeWay(t *testing.T) {
+	source := t.TempDir()
+	lib := t.TempDir()
+
+	writeFiles(t, source, map[string]string{
+		"actions/upstream.yaml":  "name: upstream\n",
+		"actions/local.yaml":     "name: local\n",
+		"actions/merged.yaml":    "a\nb\nc\nd\ne\n",
+		"actions/conflict.yaml":  "line: 1\n",
+		"templates/same.tmpl":    "same\n",
+		"templates/missing.tmpl": "new file\n",
+	})
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	require.NoError(t, os.Remove(filepath.Join(lib, "templates/missing.tmpl")))
+
+	// Local customizations
+	writeFiles(t, lib, map[string]string{
+		"actions/local.yaml":    "name: local\ncustom: true\n",
+		"actions/merged.yaml":   "a\nB\nc\nd\ne\n",
+		"actions/conflict.yaml": "line: local\n",
+	})
+	// Upstream changes
+	writeFiles(t, source, map[string]string{
+		"actions/upstream.yaml": "name: upstream\nversion: 2\n",
+		"actions/merged.yaml":   "a\nb\nc\nd\nE\n",
+		"actions/conflict.yaml": "line: upstream\n",
+	})
+
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+
+	assert.Equal(t, "name: upstream\nversion: 2\n", readFile(t, filepath.Join(lib, "actions/upstream.yaml")))
+	assert.Equal(t, "name: local\ncustom: true\n", readFile(t, filepath.Join(lib, "actions/local.yaml")))
+	assert.Equal(t, "a\nB\nc\nd\nE\n", readFile(t, filepath.Join(lib, "actions/merged.yaml")))
+	assert.Equal(t, "new file\n", readFile(t, filepath.Join(lib, "templates/missing.tmpl")))
+
+	// Conflicts keep the local file and leave both other versions in the
+	// state directory, outside signatures and lock hashes
+	conflict := filepath.Join(lib, "actions/conflict.yaml")
+	newFile := library.ConflictPath(lib, "actions/conflict.yaml", library.NewSuffix)
+	assert.Equal(t, "line: local\n", readFile(t, conflict))
+	assert.Equal(t, filepath.Join(lib, library.StateDir, library.ConflictsDir, "actions", "conflict.yaml.new"), newFile)
+	assert.Equal(t, "line: upstream\n", readFile(t, newFile))
+	assert.Equal(t, "line: 1\n", readFile(t, library.ConflictPath(lib, "actions/conflict.yaml", library.OrigSuffix)))
+	assert.NoFileExists(t, conflict+library.NewSuffix)
+
+	// Until the .new file is deleted, the conflict is reported again
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.Equal(t, "line: local\n", readFile(t, conflict))
+	assert.FileExists(t, newFile)
+	assert.Equal(t, "line: 1\n", readFile(t, filepath.Join(lib, library.StateDir, "base", "actions", "conflict.yaml")))
+
+	// Once resolved, the next update merges against the upstream version
+	writeFiles(t, lib, map[string]string{"actions/conflict.yaml": "line: resolved\n"})
+	require.NoError(t, os.Remove(newFile))
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.Equal(t, "line: resolved\n", readFile(t, conflict))
+	assert.NoFileExists(t, library.ConflictPath(lib, "actions/conflict.yaml", library.OrigSuffix))
+	assert.Equal(t, "line: upstream\n", readFile(t, filepath.Join(lib, library.StateDir, "base", "actions", "conflict.yaml")))
+
+	writeFiles(t, source, map[string]string{"actions/local.yaml": "name: local\ncustom: true\n"})
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.Equal(t, "line: resolved\n", readFile(t, conflict))
+	assert.NoFileExists(t, newFile)
+	assert.Equal(t, "name: local\ncustom: true\n", readFile(t, filepath.Join(lib, "actions/local.yaml")))
+
+	// Conflict files are not part of the library's content hash
+	hash, err := signing.HashLibrary(lib)
+	require.NoError(t, err)
+	writeFiles(t, lib, map[string]string{filepath.Join(library.StateDir, library.ConflictsDir, "actions", "x.yaml.new"): "x\n"})
+	withConflict, err := signing.HashLibrary(lib)
+	require.NoError(t, err)
+	assert.Equal(t, hash, withConflict)
+}
+
+func TestUpdater_ForceAndDryRun(t *testing.T) {
+	source := t.TempDir()
+	lib := t.TempDir()
+
+	writeFiles(t, source, map[string]string{"actions/a.yaml": "name: a\n"})
+	writeFiles(t, lib, map[string]string{"actions/a.yaml": "name: mine\n"})
+
+	// Without a record of what was installed, differing files conflict
+	require.NoError(t, library.NewUpdater(lib, source, false, true, false).UpdateLibrary())
+	assert.NoFileExists(t, filepath.Join(lib, "actions/a.yaml"+library.NewSuffix))
+	assert.NoDirExists(t, filepath.Join(lib, library.StateDir))
+
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.Equal(t, "name: mine\n", readFile(t, filepath.Join(lib, "actions/a.yaml")))
+	assert.Equal(t, "name: a\n", readFile(t, library.ConflictPath(lib, "actions/a.yaml", library.NewSuffix)))
+	assert.NoFileExists(t, library.ConflictPath(lib, "actions/a.yaml", library.OrigSuffix))
+
+	// Forcing the update settles the conflict
+	require.NoError(t, library.NewUpdater(lib, source, true, false, false).UpdateLibrary())
+	assert.Equal(t, "name: a\n", readFile(t, filepath.Join(lib, "actions/a.yaml")))
+	assert.NoFileExists(t, library.ConflictPath(lib, "actions/a.yaml", library.NewSuffix))
+}
+
+func TestRecordInstalledFiles(t *testing.T) {
+	source := t.TempDir()
+	lib := t.TempDir()
+
+	writeFiles(t, lib, map[string]string{"actions/a.yaml": "name: a\n"})
+	require.NoError(t, library.RecordInstalledFiles(lib))
+
+	writeFiles(t, source, map[string]string{"actions/a.yaml": "name: a\nversion: 2\n"})
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.Equal(t, "name: a\nversion: 2\n", readFile(t, filepath.Join(lib, "actions/a.yaml")))
+}
+
+func TestUpdater_Prune(t *testing.T) {
+	source := t.TempDir()
+	lib := t.TempDir()
+
+	writeFiles(t, source, map[string]string{
+		"actions/keep.yaml":         "name: keep\n",
+		"actions/gone.yaml":         "name: gone\n",
+		"actions/edited.yaml":       "name: edited\n",
+		"templates/old/readme.tmpl": "old\n",
+		"templates/current.tmpl":    "current\n",
+	})
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+
+	writeFiles(t, lib, map[string]string{
+		"actions/edited.yaml": "name: edited\nmine: true\n",
+		"actions/user.yaml":   "name: user\n",
+	})
+	for _, path := range []string{"actions/gone.yaml", "actions/edited.yaml", "templates/old/readme.tmpl"} {
+		require.NoError(t, os.Remove(filepath.Join(source, path)))
+	}
+
+	// Without --prune, files removed upstream stay
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).UpdateLibrary())
+	assert.FileExists(t, filepath.Join(lib, "actions/gone.yaml"))
+
+	// A dry run only previews the removal
+	require.NoError(t, library.NewUpdater(lib, source, false, true, false).WithPrune(true).UpdateLibrary())
+	assert.FileExists(t, filepath.Join(lib, "actions/gone.yaml"))
+
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).WithPrune(true).UpdateLibrary())
+	assert.NoFileExists(t, filepath.Join(lib, "actions/gone.yaml"))
+	assert.NoDirExists(t, filepath.Join(lib, "templates/old"))
+	assert.FileExists(t, filepath.Join(lib, "actions/keep.yaml"))
+	assert.FileExists(t, filepath.Join(lib, "templates/current.tmpl"))
+
+	// Locally changed and never managed files are left alone
+	assert.Equal(t, "name: edited\nmine: true\n", readFile(t, filepath.Join(lib, "actions/edited.yaml")))
+	assert.Equal(t, "name: user\n", readFile(t, filepath.Join(lib, "actions/user.yaml")))
+
+	// The changed file is no longer managed, so a later prune keeps it even when it matches again
+	writeFiles(t, lib, map[string]string{"actions/edited.yaml": "name: edited\n"})
+	require.NoError(t, library.NewUpdater(lib, source, false, false, false).WithPrune(true).UpdateLibrary())
+	assert.FileExists(t, filepath.Join(lib, "actions/edited.yaml"))
+}
@@ -4,24 +4,50 @@
 
 package models
 
+import "io"
+
 // RemediationStep represents a single step in the remediation plan
 type RemediationStep struct {
-	ID         string                 `json:"id"`
-	ActionName string                 `json:"action_name"`
-	Params     map[string]interface{} `json:"params"`
-	Reason     string                 `json:"reason"`
-	DependsOn  []string               `json:"depends_on,omitempty"`
-	Outputs    map[string]interface{} `json:"outputs,omitempty"`     // Capture outputs from this step
-	Status     string                 `json:"status,omitempty"`      // For tracking execution: pending, running, success, failure
-	Error      string                 `json:"error,omitempty"`       // Stores error message if execution fails
-	OutputRefs map[string]string      `json:"output_refs,omitempty"` // References to outputs from other steps
+	ID         string                 `json:"id" yaml:"id"`
+	ActionName string                 `json:"action_name" yaml:"action_name"`
+	Params     map[string]interface{} `json:"params" yaml:"params"`
+	Reason     string                 `json:"reason" yaml:"reason"`
+	DependsOn  []string               `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	Outputs    map[string]interface{} `json:"outputs,omitempty" yaml:"outputs,omitempty"`         // Capture outputs from this step
+	Status     string                 `json:"status,omitempty" yaml:"status,omitempty"`           // For tracking execution: pending, running, success, failure
+	Error      string                 `json:"error,omitempty" yaml:"error,omitempty"`             // Stores error message if execution fails
+	OutputRefs map[string]string      `json:"output_refs,omitempty" yaml:"output_refs,omitempty"` // References to outputs from other steps
 }
 
 // RemediationPlan represents the generated plan
 type RemediationPlan struct {
-	ProjectName string            `json:"project_name"`
-	Repository  string            `json:"repository"`
-	Steps       []RemediationStep `json:"steps"`
+	ProjectName string            `json:"project_name" yaml:"project_name"`
+	Repository  string            `json:"repository" yaml:"repository"`
+	Steps       []RemediationStep `json:"steps" yaml:"steps"`
+	Conflicts   []StepConflict    `json:"conflicts,omitempty" yaml:"conflicts,omitempty"` // Steps dropped by conflict resolution
+	Approvals   []PlanApproval    `json:"approvals,omitempty" yaml:"approvals,omitempty"` // Sign-offs recorded by 'darnit plan approve'
+}
+
+// PlanApproval records an approver's sign-off on a plan's steps. The steps
+// hash ties the approval to the steps as they were when approved, and the
+// signature ties it to the approver's key.
+type PlanApproval struct {
+	Approver   string `json:"approver" yaml:"approver"`
+	ApprovedAt string `json:"approved_at" yaml:"approved_at"` // RFC 3339 timestamp
+	StepsHash  string `json:"steps_hash" yaml:"steps_hash"`   // SHA-256 of the approved steps
+	Comment    string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	KeyID      string `json:"key_id" yaml:"key_id"`       // Key that signed the approval
+	Signature  string `json:"signature" yaml:"signature"` // Base64 ed25519 signature over the fields above
+}
+
+// StepConflict records a step that was left out of a plan because it
+// conflicted with a step from a higher-priority rule
+type StepConflict struct {
+	StepID   string `json:"step_id" yaml:"step_id"`
+	Action   string `json:"action" yaml:"action"`
+	Priority int    `json:"priority" yaml:"priority"`
+	WinnerID string `json:"winner_id" yaml:"winner_id"` // Step that was kept instead
+	Reason   string `json:"reason" yaml:"reason"`
 }
 
 // ExecutionOptions contains options for plan execution
@@ -29,5 +55,27 @@ type ExecutionOptions struct {
 	DryRun          bool
 	VerboseLogging  bool
 	ContinueOnError bool
-	WorkingDir      string
+	WorkingDir      string    // Directory actions run in; relative file targets resolve against it
+	Output          io.Writer // Destination for execution output (nil means os.Stdout)
+
+	// Number of distinct trusted keys whose signed approval must match the
+	// plan's current steps before it runs (0 means approval is not required)
+	RequiredApprovals int
+}
+
+// BatchIndex records the plans produced by a batch generation run
+type BatchIndex struct {
+	GeneratedAt string            `json:"generated_at" yaml:"generated_at"`
+	Entries     []BatchIndexEntry `json:"entries" yaml:"entries"`
+}
+
+// BatchIndexEntry describes the plan generated for a single repository
+type BatchIndexEntry struct {
+	Name       string   `json:"name" yaml:"name"`
+	RepoPath   string   `json:"repo_path" yaml:"repo_path"`
+	ReportFile string   `json:"report_file" yaml:"report_file"`
+	PlanFile   string   `json:"plan_file,omitempty" yaml:"plan_file,omitempty"` // Relative to the index file
+	StepCount  int      `json:"step_count" yaml:"step_count"`
+	Actions    []string `json:"actions,omitempty" yaml:"actions,omitempty"` // Distinct action names used by the plan
+	Error      string   `json:"error,omitempty" yaml:"error,omitempty"`     // Set when plan generation failed
 }
@@ -8,10 +8,15 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/template"
 )
 
 var paramRegex = regexp.MustCompile(`\{\{\.([^}]+)\}\}`)
 
+// fieldPathRegex matches keys that text/template can address as .a.b
+var fieldPathRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
 // ProcessParamsWithSchema processes parameters with schema type awareness
 func ProcessParamsWithSchema(params map[string]interface{}, data map[string]interface{}, schema map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
@@ -29,12 +34,18 @@ func ProcessParamsWithSchema(params map[string]interface{}, data map[string]inte
 
 		switch v := value.(type) {
 		case string:
-			// Process string with template substitution
-			processed, err := substituteParameters(v, data)
+			// Render the value; whole-value references keep their type
+			rendered, err := renderParameter(v, data)
 			if err != nil {
 				return nil, fmt.Errorf("error processing parameter %s: %w", key, err)
 			}
 
+			processed, isString := rendered.(string)
+			if !isString {
+				result[key] = coerceToSchemaType(rendered, propSchema)
+				continue
+			}
+
 			// If we have a schema and it specifies this should be an array, try to convert it
 			if hasSchema && propSchema["type"] == "array" && strings.HasPrefix(processed, "[") && strings.HasSuffix(processed, "]") {
 				// This might be a stringified array - try to convert it
@@ -95,7 +106,7 @@ func processArrayItems(array []interface{}, data map[string]interface{}) ([]inte
 	for i, item := range array {
 		switch v := item.(type) {
 		case string:
-			processed, err := substituteParameters(v, data)
+			processed, err := renderParameter(v, data)
 			if err != nil {
 				return nil, err
 			}
@@ -130,7 +141,7 @@ func processParamMapUntyped(params map[string]interface{}, data map[string]inter
 	for key, value := range params {
 		switch v := value.(type) {
 		case string:
-			processed, err := substituteParameters(v, data)
+			processed, err := renderParameter(v, data)
 			if err != nil {
 				return nil, fmt.Errorf("error processing parameter %s: %w", key, err)
 			}
@@ -158,37 +169,79 @@ func processParamMapUntyped(params map[string]interface{}, data map[string]inter
 	return result, nil
 }
 
-// substituteParameters replaces template parameters in a string with values from data
-func substituteParameters(template string, data map[string]interface{}) (string, error) {
-	result := paramRegex.ReplaceAllStringFunc(template, func(match string) string {
-		// Extract key from {{.key}}
-		key := match[3 : len(match)-2]
+// renderParameter renders a parameter value with the template engine used
+// for action templates (see template.RenderValue). References to keys that
+// are not valid template field names, such as {{.repo-name}}, or flat keys
+// containing dots are rewritten to index lookups so mappings written for the
+// old {{.key}} syntax keep working.
+func renderParameter(value string, data map[string]interface{}) (interface{}, error) {
+	var missing []string
+	value = indexReferences(value, func(key string) bool {
+		if _, found := data[key]; found {
+			// A flat key containing dots takes precedence over a nested path
+			return !fieldPathRegex.MatchString(key) || strings.Contains(key, ".")
+		}
+		if isBareKey(key) && !fieldPathRegex.MatchString(key) {
+			missing = append(missing, key)
+		}
+		return false
+	})
+	if len(missing) > 0 {
+		return nil, &template.MissingValuesError{Keys: missing}
+	}
+
+	return template.RenderValue(value, data)
+}
+
+// ParameterKeys returns the keys a parameter value needs a value for (see
+// template.RequiredKeys), reading references to keys that are not valid
+// template field names, such as {{.repo-name}}, as renderParameter does
+func ParameterKeys(value string) ([]string, error) {
+	return template.RequiredKeys(indexReferences(value, func(key string) bool {
+		return isBareKey(key) && !fieldPathRegex.MatchString(key)
+	}))
+}
+
+// isBareKey reports whether a {{.key}} reference names a key, rather than
+// starting a pipeline such as {{.name | default "x"}}
+func isBareKey(key string) bool {
+	return !strings.ContainsAny(key, " \t|()\"'`$")
+}
 
-		// Look up value
-		value, found := data[key]
-		if !found {
-			return match // Keep original if not found
+// indexReferences rewrites each {{.key}} reference in value to an index
+// lookup when rewrite returns true for its key
+func indexReferences(value string, rewrite func(key string) bool) string {
+	return paramRegex.ReplaceAllStringFunc(value, func(match string) string {
+		key := strings.TrimSpace(match[3 : len(match)-2])
+		if !rewrite(key) {
+			return match
 		}
+		return fmt.Sprintf("{{index . %q}}", key)
+	})
+}
 
-		// Convert value to string, but maintain special representations for complex types
+// coerceToSchemaType converts a rendered non-string value to the type the
+// parameter's schema declares, where that is a lossless change
+func coerceToSchemaType(value interface{}, propSchema map[string]interface{}) interface{} {
+	switch propSchema["type"] {
+	case "number":
 		switch v := value.(type) {
+		case int:
+			return float64(v)
+		case int64:
+			return float64(v)
+		case float32:
+			return float64(v)
+		}
+	case "string":
+		switch value.(type) {
 		case []interface{}, map[string]interface{}:
-			// For complex types, use JSON representation
-			jsonBytes, err := json.Marshal(v)
-			if err != nil {
-				return match // Return original on error
+			if data, err := json.Marshal(value); err == nil {
+				return string(data)
 			}
-			return string(jsonBytes)
 		default:
-			// For simple types, use string representation
 			return fmt.Sprintf("%v", value)
 		}
-	})
-
-	// If any templates remain, there were missing values
-	if paramRegex.MatchString(result) {
-		return result, fmt.Errorf("missing values for some parameters: %s", result)
 	}
-
-	return result, nil
+	return value
 }
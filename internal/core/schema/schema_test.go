@@ -7,6 +7,7 @@ import (
 
 	"github.com/kusari-oss/darn/internal/core/schema"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateParams(t *testing.T) {
@@ -285,6 +286,99 @@ func TestProcessParamsWithSchema(t *testing.T) {
 			},
 			shouldError: false,
 		},
+		{
+			name: "dotted path into nested data",
+			params: map[string]interface{}{
+				"package": "{{.item.name}}@{{.item.version}}",
+				"files":   []interface{}{"{{.item.manifest}}"},
+			},
+			data: map[string]interface{}{
+				"item": map[string]interface{}{
+					"name":     "lodash",
+					"version":  "4.17.21",
+					"manifest": "package.json",
+				},
+			},
+			schema: map[string]interface{}{},
+			expected: map[string]interface{}{
+				"package": "lodash@4.17.21",
+				"files":   []interface{}{"package.json"},
+			},
+			shouldError: false,
+		},
+		{
+			name: "whole-value references keep their type",
+			params: map[string]interface{}{
+				"emails":  []interface{}{"{{.security_email}}"},
+				"owners":  "{{.owners}}",
+				"retries": "{{.retries | default 3}}",
+				"label":   "{{.repo-name}}",
+				"title":   "{{.project | upper}}: {{join \", \" .owners}}",
+			},
+			data: map[string]interface{}{
+				"security_email": "security@example.com",
+				"owners":         []interface{}{"alice", "bob"},
+				"repo-name":      "darn",
+				"project":        "darn",
+			},
+			schema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"retries": map[string]interface{}{"type": "number"},
+				},
+			},
+			expected: map[string]interface{}{
+				"emails":  []interface{}{"security@example.com"},
+				"owners":  []interface{}{"alice", "bob"},
+				"retries": float64(3),
+				"label":   "darn",
+				"title":   "DARN: alice, bob",
+			},
+			shouldError: false,
+		},
+		{
+			name: "lists in text render as JSON",
+			params: map[string]interface{}{
+				"greeting": "hi {{.emails}}",
+			},
+			data: map[string]interface{}{
+				"emails": []interface{}{"a", "b"},
+			},
+			schema: map[string]interface{}{},
+			expected: map[string]interface{}{
+				"greeting": `hi ["a","b"]`,
+			},
+			shouldError: false,
+		},
+		{
+			name: "missing hyphenated key",
+			params: map[string]interface{}{
+				"label": "{{.repo-name}}",
+			},
+			data:        map[string]interface{}{},
+			schema:      map[string]interface{}{},
+			shouldError: true,
+		},
+		{
+			name: "misspelled function",
+			params: map[string]interface{}{
+				"label": `{{.name | defualt "x"}}`,
+			},
+			data:        map[string]interface{}{"name": "darn"},
+			schema:      map[string]interface{}{},
+			shouldError: true,
+		},
+		{
+			name: "missing dotted path",
+			params: map[string]interface{}{
+				"package": "{{.item.missing}}",
+			},
+			data: map[string]interface{}{
+				"item": map[string]interface{}{"name": "lodash"},
+			},
+			schema:      map[string]interface{}{},
+			shouldError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -300,3 +394,29 @@ func TestProcessParamsWithSchema(t *testing.T) {
 		})
 	}
 }
+
+func TestParameterKeys(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"{{.name}}", []string{"name"}},
+		{"{{ .name }} and {{.repo.owner}}", []string{"name", "repo.owner"}},
+		{"{{.repo-name}}", []string{"repo-name"}},
+		{`{{index . "repo-name"}}`, []string{"repo-name"}},
+		{"{{.name | upper}}", []string{"name"}},
+		{`{{.name | default "x"}}`, []string{}},
+		{"{{.name | default .fallback}}", []string{"fallback"}},
+		{"{{if .flag}}{{.name}}{{end}}", []string{"name"}},
+		{"{{range .items}}{{.id}}{{end}}", []string{}},
+		{"${{ secrets.TOKEN }} {{.name}}", []string{"name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			keys, err := schema.ParameterKeys(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, keys)
+		})
+	}
+}
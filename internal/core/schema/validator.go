@@ -47,6 +47,19 @@ func ValidateParams(schema map[string]interface{}, params map[string]interface{}
 	return nil
 }
 
+// CheckSchema checks that schema is a valid JSON schema
+func CheckSchema(schema map[string]interface{}) error {
+	schemaBytes, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to serialize schema: %w", err)
+	}
+
+	if _, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaBytes)); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	return nil
+}
+
 // MergeWithDefaults merges params with default values
 func MergeWithDefaults(params map[string]interface{}, defaults map[string]interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package semver parses semantic versions and version constraints
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version. Build metadata is ignored.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// Parse parses a version such as 1.2.3, v1.2.3 or 1.2.3-rc.1. Missing minor
+// and patch numbers default to zero.
+func Parse(s string) (Version, error) {
+	var v Version
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(text, '+'); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.IndexByte(text, '-'); i >= 0 {
+		v.Prerelease = text[i+1:]
+		text = text[:i]
+		if v.Prerelease == "" {
+			return Version{}, fmt.Errorf("invalid version %q: empty prerelease", s)
+		}
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	numbers := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version %q", s)
+		}
+		*numbers[i] = n
+	}
+	return v, nil
+}
+
+// String returns the version without a leading v
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than o.
+// A prerelease is lower than the release it precedes.
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers;
+// numeric identifiers compare numerically and sort before alphanumeric ones
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a set of version comparisons that must all hold
+type Constraint struct {
+	text        string
+	comparisons []comparison
+}
+
+type comparison struct {
+	op      string
+	version Version
+}
+
+// ParseConstraint parses a constraint made of comma- or space-separated
+// comparisons. Each comparison is one of:
+//
+//	1.2.3, =1.2.3     exactly 1.2.3
+//	>1.2 >=1.2 <2 <=2 !=1.2.3
+//	^1.2.3            compatible: >=1.2.3 <2.0.0 (<0.3.0 for 0.2.x)
+//	~1.2.3            patch updates: >=1.2.3 <1.3.0
+//	1.x, 1.2.*        any version with the given prefix
+//	*                 any version
+//
+// An empty constraint allows any version.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{text: strings.TrimSpace(s)}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	for _, field := range fields {
+		comparisons, err := parseComparison(field)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.comparisons = append(c.comparisons, comparisons...)
+	}
+	return c, nil
+}
+
+// parseComparison expands one comparison into primitive comparisons
+func parseComparison(field string) ([]comparison, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", "!=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(field, prefix) {
+			op = prefix
+			break
+		}
+	}
+	text := strings.TrimPrefix(field, op)
+	if text == "*" || strings.EqualFold(text, "x") {
+		return nil, nil
+	}
+
+	// Wildcards (1.x, 1.2.*) are ranges over the fixed prefix
+	parts := strings.Split(strings.TrimPrefix(text, "v"), ".")
+	fixed := len(parts)
+	for i, part := range parts {
+		if part == "*" || strings.EqualFold(part, "x") {
+			fixed = i
+			break
+		}
+	}
+	if fixed < len(parts) {
+		if op != "" && op != "=" {
+			return nil, fmt.Errorf("wildcard %q cannot be combined with %s", text, op)
+		}
+		if fixed == 0 {
+			return nil, nil
+		}
+		low, err := Parse(strings.Join(parts[:fixed], "."))
+		if err != nil {
+			return nil, err
+		}
+		return []comparison{{">=", low}, {"<", bump(low, fixed)}}, nil
+	}
+
+	v, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "", "=":
+		return []comparison{{"=", v}}, nil
+	case "^":
+		switch {
+		case v.Major > 0 || len(parts) == 1:
+			return []comparison{{">=", v}, {"<", bump(v, 1)}}, nil
+		case v.Minor > 0 || len(parts) == 2:
+			return []comparison{{">=", v}, {"<", bump(v, 2)}}, nil
+		}
+		return []comparison{{">=", v}, {"<", bump(v, 3)}}, nil
+	case "~":
+		if len(parts) == 1 {
+			return []comparison{{">=", v}, {"<", bump(v, 1)}}, nil
+		}
+		return []comparison{{">=", v}, {"<", bump(v, 2)}}, nil
+	}
+	return []comparison{{op, v}}, nil
+}
+
+// bump returns the lowest version above every version sharing v's first n numbers
+func bump(v Version, n int) Version {
+	switch n {
+	case 1:
+		return Version{Major: v.Major + 1}
+	case 2:
+		return Version{Major: v.Major, Minor: v.Minor + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+}
+
+// Check reports whether v satisfies every comparison in the constraint
+func (c Constraint) Check(v Version) bool {
+	for _, cmp := range c.comparisons {
+		r := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint as it was written
+func (c Constraint) String() string {
+	if c.text == "" {
+		return "*"
+	}
+	return c.text
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package semver_test
+
+import (
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	v, err := semver.Parse("v1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	assert.Equal(t, semver.Version{Major: 1, Minor: 2, Patch: 3, Prerelease: "rc.1"}, v)
+	assert.Equal(t, "1.2.3-rc.1", v.String())
+
+	v, err = semver.Parse("2")
+	require.NoError(t, err)
+	assert.Equal(t, "2.0.0", v.String())
+
+	for _, invalid := range []string{"", "1.2.3.4", "one", "1.-2", "1.2.3-"} {
+		_, err := semver.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"0.9.0", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.10.0", "2.0.0"}
+	for i := 1; i < len(ordered); i++ {
+		lower, err := semver.Parse(ordered[i-1])
+		require.NoError(t, err)
+		higher, err := semver.Parse(ordered[i])
+		require.NoError(t, err)
+		assert.Equal(t, -1, lower.Compare(higher), "%s < %s", lower, higher)
+		assert.Equal(t, 1, higher.Compare(lower), "%s > %s", higher, lower)
+		assert.Equal(t, 0, higher.Compare(higher))
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	tests := []struct {
+		constraint string
+		allowed    []string
+		rejected   []string
+	}{
+		{"", []string{"0.0.1", "9.9.9"}, nil},
+		{"*", []string{"1.0.0"}, nil},
+		{"1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"^1.2.3", []string{"1.2.3", "1.9.0"}, []string{"1.2.2", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0"}},
+		{"~1", []string{"1.0.0", "1.9.0"}, []string{"2.0.0"}},
+		{"1.x", []string{"1.0.0", "1.5.2"}, []string{"0.9.0", "2.0.0"}},
+		{"1.2.*", []string{"1.2.0", "1.2.7"}, []string{"1.3.0"}},
+		{">=1.2, <2", []string{"1.2.0", "1.99.0"}, []string{"1.1.9", "2.0.0"}},
+		{">1.0.0 !=1.1.0", []string{"1.0.1", "1.2.0"}, []string{"1.0.0", "1.1.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			c, err := semver.ParseConstraint(tt.constraint)
+			require.NoError(t, err)
+			for _, s := range tt.allowed {
+				v, err := semver.Parse(s)
+				require.NoError(t, err)
+				assert.True(t, c.Check(v), "%s should satisfy %s", s, tt.constraint)
+			}
+			for _, s := range tt.rejected {
+				v, err := semver.Parse(s)
+				require.NoError(t, err)
+				assert.False(t, c.Check(v), "%s should not satisfy %s", s, tt.constraint)
+			}
+		})
+	}
+
+	for _, invalid := range []string{">=one", "^1.x", ">1.2.3.4"} {
+		_, err := semver.ParseConstraint(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
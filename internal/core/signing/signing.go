@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package signing creates and verifies detached ed25519 signatures for plan
+// files, plan approvals and libraries
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SignatureSuffix is appended to a file's path to name its detached signature
+const SignatureSuffix = ".sig"
+
+// LibrarySignatureFile is the name of a library's signature, in the library's root
+const LibrarySignatureFile = "library.sig"
+
+// Signature is a detached signature
+type Signature struct {
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"` // Base64 ed25519 signature
+	// For library signatures, the SHA-256 of every signed file by its
+	// slash-separated path relative to the library root
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// GenerateKey creates an ed25519 key pair and writes the private key to
+// path and the public key to the same path with a .pub extension
+func GenerateKey(path string) (ed25519.PublicKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("error generating key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding public key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("error creating key directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("key %s already exists", path)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return nil, fmt.Errorf("error writing private key: %w", err)
+	}
+	if err := os.WriteFile(PublicKeyPath(path), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return nil, fmt.Errorf("error writing public key: %w", err)
+	}
+
+	return publicKey, nil
+}
+
+// PublicKeyPath returns the path of the public key for a private key file
+func PublicKeyPath(privateKeyPath string) string {
+	return strings.TrimSuffix(privateKeyPath, filepath.Ext(privateKeyPath)) + ".pub"
+}
+
+// LoadPrivateKey reads a PEM-encoded PKCS #8 ed25519 private key
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing private key %s: %w", path, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an ed25519 key", path)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads a PEM-encoded PKIX ed25519 public key
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing public key %s: %w", path, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ed25519 key", path)
+	}
+	return publicKey, nil
+}
+
+// readPEM reads the first PEM block of the given type from path
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("key %s does not contain a PEM %s block", path, blockType)
+	}
+	return block, nil
+}
+
+// KeyID returns a short identifier for a public key: the first 16 hex
+// characters of its SHA-256
+func KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// SignFile writes a detached signature for the file at path to path.sig
+func SignFile(path string, privateKey ed25519.PrivateKey) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	signaturePath := path + SignatureSuffix
+	if err := writeSignature(signaturePath, Sign(data, privateKey)); err != nil {
+		return "", err
+	}
+	return signaturePath, nil
+}
+
+// SignLibrary signs every file in a library and writes the signature to the
+// library's root
+func SignLibrary(dir string, privateKey ed25519.PrivateKey) (string, error) {
+	files, err := libraryFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	signature := Sign(filesDigest(files), privateKey)
+	signature.Files = files
+
+	signaturePath := filepath.Join(dir, LibrarySignatureFile)
+	if err := writeSignature(signaturePath, signature); err != nil {
+		return "", err
+	}
+	return signaturePath, nil
+}
+
+// HashLibrary returns the SHA-256 of a library's content, covering the same
+// files as a library signature, as "sha256:<hex>"
+func HashLibrary(dir string) (string, error) {
+	files, err := libraryFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(filesDigest(files))
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// Sign signs data with privateKey
+func Sign(data []byte, privateKey ed25519.PrivateKey) *Signature {
+	return &Signature{
+		KeyID:     KeyID(privateKey.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)),
+	}
+}
+
+// writeSignature writes a signature as indented JSON
+func writeSignature(path string, signature *Signature) error {
+	data, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding signature: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing signature: %w", err)
+	}
+	return nil
+}
+
+// readSignature reads a signature; a missing signature file is reported as unsigned content
+func readSignature(path, content string) (*Signature, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s is not signed (no signature at %s)", content, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading signature: %w", err)
+	}
+
+	var signature Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return nil, fmt.Errorf("error parsing signature %s: %w", path, err)
+	}
+	return &signature, nil
+}
+
+// libraryFiles returns the SHA-256 of every file in a library, skipping the
+// signature itself and hidden files and directories in the library's root,
+// such as .git. Hidden files below the root, such as actions/.hidden.yaml or
+// templates/.github/, are library content and are included.
+func libraryFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && filepath.Dir(path) == filepath.Clean(dir) && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == LibrarySignatureFile {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		files[relPath] = hex.EncodeToString(sum[:])
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading library %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// filesDigest returns the signed form of a library's file list: one
+// "<sha256>  <path>" line per file, sorted by path
+func filesDigest(files map[string]string) []byte {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", files[path], path)
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package signing_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generateKey creates a key pair in a temporary directory and returns the
+// private key path and a verifier that trusts it
+func generateKey(t *testing.T, name string) (string, *signing.Verifier) {
+	t.Helper()
+	keyPath := filepath.Join(t.TempDir(), name+".key")
+	_, err := signing.GenerateKey(keyPath)
+	require.NoError(t, err)
+
+	verifier, err := signing.NewVerifier([]string{signing.PublicKeyPath(keyPath)})
+	require.NoError(t, err)
+	return keyPath, verifier
+}
+
+func TestSignAndVerifyFile(t *testing.T) {
+	keyPath, verifier := generateKey(t, "release")
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(planFile, []byte(`{"steps":[]}`), 0644))
+
+	assert.ErrorContains(t, verifier.VerifyFile(planFile), "is not signed")
+
+	signaturePath, err := signing.SignFile(planFile, privateKey)
+	require.NoError(t, err)
+	assert.Equal(t, planFile+".sig", signaturePath)
+	assert.NoError(t, verifier.VerifyFile(planFile))
+
+	require.NoError(t, os.WriteFile(planFile, []byte(`{"steps":[{"id":"x"}]}`), 0644))
+	assert.ErrorContains(t, verifier.VerifyFile(planFile), "may have been tampered with")
+}
+
+func TestVerifyRejectsUntrustedKey(t *testing.T) {
+	keyPath, _ := generateKey(t, "other")
+	_, verifier := generateKey(t, "release")
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, os.WriteFile(planFile, []byte(`{}`), 0644))
+	_, err = signing.SignFile(planFile, privateKey)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, verifier.VerifyFile(planFile), "signed with untrusted key")
+}
+
+func TestGenerateKeyRefusesToOverwrite(t *testing.T) {
+	keyPath, _ := generateKey(t, "release")
+	_, err := signing.GenerateKey(keyPath)
+	assert.ErrorContains(t, err, "already exists")
+}
+
+func TestSignAndVerifyLibrary(t *testing.T) {
+	keyPath, verifier := generateKey(t, "release")
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+
+	library := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(library, "actions"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(library, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "actions", "a.yaml"), []byte("name: a\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "actions", "b.yaml"), []byte("name: b\n"), 0644))
+
+	assert.ErrorContains(t, verifier.VerifyLibrary(library), "is not signed")
+
+	_, err = signing.SignLibrary(library, privateKey)
+	require.NoError(t, err)
+	assert.NoError(t, verifier.VerifyLibrary(library))
+
+	// Hidden files in the root are not part of the signature
+	require.NoError(t, os.WriteFile(filepath.Join(library, ".git", "HEAD"), []byte("ref\n"), 0644))
+	assert.NoError(t, verifier.VerifyLibrary(library))
+
+	require.NoError(t, os.WriteFile(filepath.Join(library, "actions", "a.yaml"), []byte("name: evil\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "actions", "c.yaml"), []byte("name: c\n"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(library, "actions", "b.yaml")))
+	require.NoError(t, os.WriteFile(filepath.Join(library, "actions", ".d.yaml"), []byte("name: d\n"), 0644))
+
+	err = verifier.VerifyLibrary(library)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changed after it was signed")
+	assert.Contains(t, err.Error(), "actions/a.yaml (modified)")
+	assert.Contains(t, err.Error(), "actions/b.yaml (removed)")
+	assert.Contains(t, err.Error(), "actions/c.yaml (added)")
+	assert.Contains(t, err.Error(), "actions/.d.yaml (added)")
+}
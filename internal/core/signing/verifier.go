@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package signing
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Verifier checks signatures against a set of trusted public keys
+type Verifier struct {
+	keys map[string]ed25519.PublicKey // By key ID
+}
+
+// NewVerifier loads the trusted public keys at keyPaths
+func NewVerifier(keyPaths []string) (*Verifier, error) {
+	v := &Verifier{keys: make(map[string]ed25519.PublicKey, len(keyPaths))}
+	for _, path := range keyPaths {
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("error loading trusted key: %w", err)
+		}
+		v.keys[KeyID(key)] = key
+	}
+	return v, nil
+}
+
+// VerifyFile checks the detached signature at path.sig against the file's content
+func (v *Verifier) VerifyFile(path string) error {
+	signature, err := readSignature(path+SignatureSuffix, path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	if err := v.Verify(data, signature); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// VerifyLibrary checks a library's signature and that none of its files were
+// added, changed or removed since it was signed
+func (v *Verifier) VerifyLibrary(dir string) error {
+	signature, err := readSignature(filepath.Join(dir, LibrarySignatureFile), "library "+dir)
+	if err != nil {
+		return err
+	}
+
+	// Check the signature over the recorded file list first, so a forged list is reported as such
+	if err := v.Verify(filesDigest(signature.Files), signature); err != nil {
+		return fmt.Errorf("library %s: %w", dir, err)
+	}
+
+	files, err := libraryFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	var changes []string
+	for path, hash := range files {
+		signedHash, ok := signature.Files[path]
+		switch {
+		case !ok:
+			changes = append(changes, path+" (added)")
+		case signedHash != hash:
+			changes = append(changes, path+" (modified)")
+		}
+	}
+	for path := range signature.Files {
+		if _, ok := files[path]; !ok {
+			changes = append(changes, path+" (removed)")
+		}
+	}
+	if len(changes) > 0 {
+		sort.Strings(changes)
+		return fmt.Errorf("library %s changed after it was signed: %s", dir, strings.Join(changes, ", "))
+	}
+
+	return nil
+}
+
+// Verify checks a signature over data
+func (v *Verifier) Verify(data []byte, signature *Signature) error {
+	key, ok := v.keys[signature.KeyID]
+	if !ok {
+		return fmt.Errorf("signed with untrusted key %s", signature.KeyID)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return fmt.Errorf("signature does not match the content; it may have been tampered with")
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// FuncMap returns the functions available to file templates, action
+// arguments and mapping parameters. Argument order follows the pipeline
+// convention, so {{.name | default "unknown" | lower}} works as expected.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Defaults and missing values
+		"default":  defaultValue,
+		"required": required,
+		"empty":    isEmpty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+
+		// Strings
+		"lower":      func(v interface{}) string { return strings.ToLower(toString(v)) },
+		"upper":      func(v interface{}) string { return strings.ToUpper(toString(v)) },
+		"title":      title,
+		"trim":       func(v interface{}) string { return strings.TrimSpace(toString(v)) },
+		"trimPrefix": func(prefix string, v interface{}) string { return strings.TrimPrefix(toString(v), prefix) },
+		"trimSuffix": func(suffix string, v interface{}) string { return strings.TrimSuffix(toString(v), suffix) },
+		"replace":    func(old, new string, v interface{}) string { return strings.ReplaceAll(toString(v), old, new) },
+		"repeat":     func(count int, v interface{}) string { return strings.Repeat(toString(v), count) },
+		"contains":   func(substr string, v interface{}) bool { return strings.Contains(toString(v), substr) },
+		"hasPrefix":  func(prefix string, v interface{}) bool { return strings.HasPrefix(toString(v), prefix) },
+		"hasSuffix":  func(suffix string, v interface{}) bool { return strings.HasSuffix(toString(v), suffix) },
+		"split":      func(sep string, v interface{}) []string { return strings.Split(toString(v), sep) },
+		"quote":      func(v interface{}) string { return strconv.Quote(toString(v)) },
+		"squote":     func(v interface{}) string { return "'" + toString(v) + "'" },
+		"indent":     indent,
+		"nindent":    func(spaces int, v interface{}) string { return "\n" + indent(spaces, v) },
+
+		// Lists
+		"list":      func(items ...interface{}) []interface{} { return items },
+		"join":      join,
+		"has":       has,
+		"first":     first,
+		"last":      last,
+		"uniq":      uniq,
+		"sortAlpha": sortAlpha,
+
+		// Dates
+		"now":  time.Now,
+		"date": formatDate,
+
+		// Encoding
+		"toJSON": toJSON,
+	}
+}
+
+// defaultValue returns value, or def when value is missing or empty
+func defaultValue(def, value interface{}) interface{} {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// required returns value, or an error with message when value is missing or empty
+func required(message string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, fmt.Errorf("%s", message)
+	}
+	return value, nil
+}
+
+// isEmpty reports whether value is nil or the zero value of its type. Empty
+// strings, slices and maps are empty.
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// Truthy reports whether a rendered value counts as true: false, zero, empty
+// values and the strings "false", "no" and "0" do not
+func Truthy(value interface{}) bool {
+	if s, ok := value.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "", "false", "no", "0":
+			return false
+		}
+		return true
+	}
+	return !isEmpty(value)
+}
+
+// join joins the elements of a list with sep
+func join(sep string, list interface{}) (string, error) {
+	items, err := toList(list)
+	if err != nil {
+		return "", fmt.Errorf("join: %w", err)
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// has reports whether list contains needle
+func has(needle, list interface{}) (bool, error) {
+	items, err := toList(list)
+	if err != nil {
+		return false, fmt.Errorf("has: %w", err)
+	}
+
+	for _, item := range items {
+		if reflect.DeepEqual(item, needle) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// toJSON encodes a value as JSON
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toJSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// coalesce returns the first value that is not empty
+func coalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !isEmpty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// ternary returns ifTrue when condition is true and ifFalse otherwise
+func ternary(ifTrue, ifFalse interface{}, condition bool) interface{} {
+	if condition {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// title upper-cases the first letter of each space-separated word
+func title(value interface{}) string {
+	words := strings.Split(toString(value), " ")
+	for i, word := range words {
+		if word != "" {
+			runes := []rune(word)
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// indent prefixes every line of value with the given number of spaces
+func indent(spaces int, value interface{}) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(toString(value), "\n", "\n"+pad)
+}
+
+// first returns the first element of a list, or nil if it is empty
+func first(list interface{}) (interface{}, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, fmt.Errorf("first: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0], nil
+}
+
+// last returns the last element of a list, or nil if it is empty
+func last(list interface{}) (interface{}, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, fmt.Errorf("last: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[len(items)-1], nil
+}
+
+// uniq returns the elements of a list with duplicates removed, keeping the first occurrence
+func uniq(list interface{}) ([]interface{}, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, fmt.Errorf("uniq: %w", err)
+	}
+
+	result := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		duplicate := false
+		for _, seen := range result {
+			if reflect.DeepEqual(item, seen) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// sortAlpha returns the elements of a list as strings in alphabetical order
+func sortAlpha(list interface{}) ([]string, error) {
+	items, err := toList(list)
+	if err != nil {
+		return nil, fmt.Errorf("sortAlpha: %w", err)
+	}
+
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = toString(item)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// formatDate formats a date using a Go reference layout such as "2006-01-02".
+// The date may be a time.Time, an RFC 3339 string or a Unix timestamp.
+func formatDate(layout string, value interface{}) (string, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		t = parsed
+	case int:
+		t = time.Unix(int64(v), 0).UTC()
+	case int64:
+		t = time.Unix(v, 0).UTC()
+	case float64:
+		t = time.Unix(int64(v), 0).UTC()
+	default:
+		return "", fmt.Errorf("date: unsupported value %T", value)
+	}
+	return t.Format(layout), nil
+}
+
+// toList converts a slice or array of any element type to []interface{}; nil is an empty list
+func toList(list interface{}) ([]interface{}, error) {
+	if list == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("expected a list, got %T", list)
+	}
+
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// toString formats a value as text; missing values become the empty string
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
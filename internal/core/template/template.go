@@ -4,40 +4,622 @@ package template
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"text/template"
+	"text/template/parse"
+
+	"github.com/kusari-oss/darn/internal/core/vfs"
 )
 
-// ProcessFile processes a template file with the given parameters
+// missingKeyRegex matches the error text/template reports for a missing map
+// key under missingkey=error
+var missingKeyRegex = regexp.MustCompile(`map has no entry for key "([^"]*)"`)
+
+// maxIncludeDepth limits nested includes so a partial cannot include itself forever
+const maxIncludeDepth = 10
+
+// MissingValuesError reports template fields that had no value
+type MissingValuesError struct {
+	Keys []string
+}
+
+func (e *MissingValuesError) Error() string {
+	return fmt.Sprintf("missing values for some parameters: %s", strings.Join(e.Keys, ", "))
+}
+
+// ProcessFile processes a template file with the given parameters. Partials
+// are included from the template's own directory.
 func ProcessFile(filePath string, params map[string]interface{}) ([]byte, error) {
+	return ProcessFileWithIncludes(filePath, params, []string{filepath.Dir(filePath)})
+}
+
+// ProcessFileWithIncludes processes a template file with the given
+// parameters. {{include "name" .}} renders the partial at name, relative to
+// the first of includeDirs that contains it.
+func ProcessFileWithIncludes(filePath string, params map[string]interface{}, includeDirs []string) ([]byte, error) {
 	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+	if _, err := vfs.Stat(filePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("template file does not exist: %s", filePath)
 	}
 
 	// Read template file
-	content, err := os.ReadFile(filePath)
+	content, err := vfs.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading template file: %w", err)
 	}
 
-	return ProcessString(string(content), params)
+	return processString(string(content), params, includeDirs)
 }
 
-// ProcessString processes a template string with the given parameters
+// Check parses text without rendering it, resolving a layout it extends
+// from includeDirs. Partials it includes are only found when rendering.
+func Check(text string, includeDirs []string) error {
+	_, err := parseWithLayout(text, includeDirs, 0)
+	return err
+}
+
+// extendsRegex matches the {{/* extends "layout" */}} comment that starts a child template
+var extendsRegex = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?\}\}`)
+
+// githubExpressionRegex matches a GitHub Actions expression such as ${{ secrets.TOKEN }}
+var githubExpressionRegex = regexp.MustCompile(`\$\{\{.*?\}\}`)
+
+// ProcessString processes a template string with the given parameters. A
+// missing key may be tested with if, or given a value with default, but
+// printing it is an error. GitHub Actions expressions such as
+// ${{ secrets.TOKEN }} that do not parse as templates are kept as written.
 func ProcessString(text string, params map[string]interface{}) ([]byte, error) {
-	// Create a new template
-	tmpl, err := template.New("template").Option("missingkey=error").Parse(text)
+	return processString(literalExpressions(text), params, nil)
+}
+
+// literalExpressions returns text with its GitHub Actions expressions quoted
+// as template strings, so they are printed as written, if text does not parse
+// as a template as it is and does with them quoted. Otherwise it returns text.
+func literalExpressions(text string) string {
+	if !strings.Contains(text, "${{") {
+		return text
+	}
+	if _, err := parseTemplate(text, includeFuncs(nil, 0)); err == nil {
+		return text
+	}
+
+	quoted := githubExpressionRegex.ReplaceAllStringFunc(text, func(expression string) string {
+		return fmt.Sprintf("{{%q}}", expression)
+	})
+	if _, err := parseTemplate(quoted, includeFuncs(nil, 0)); err != nil {
+		return text
+	}
+	return quoted
+}
+
+// processString implements ProcessString with partials from includeDirs
+func processString(text string, params map[string]interface{}, includeDirs []string) ([]byte, error) {
+	tmpl, err := parseWithLayout(text, includeDirs, 0)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing template: %w", err)
+		return nil, err
 	}
 
-	// Execute the template with the parameters
+	return execute(tmpl, params)
+}
+
+// execute runs tmpl with data
+func execute(tmpl *template.Template, data interface{}) ([]byte, error) {
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, params); err != nil {
-		return nil, fmt.Errorf("error executing template: %w", err)
+	if err := executeAs(tmpl, tmpl, data, &buf); err != nil {
+		return nil, err
 	}
-
 	return buf.Bytes(), nil
 }
+
+// executeAs runs tmpl with data, as a rendering of source. Missing keys that
+// source only tests or passes on, such as .name in {{if .name}} or
+// {{.name | default "x"}}, are given a nil value first, so that
+// missingkey=error only stops source from printing a missing key.
+func executeAs(source, tmpl *template.Template, data interface{}, w io.Writer) error {
+	params, isMap := data.(map[string]interface{})
+	if isMap {
+		params = withGuardedKeys(source, params)
+		data = params
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		if match := missingKeyRegex.FindStringSubmatch(err.Error()); match != nil {
+			return missingValues(source, params, match[1])
+		}
+		return fmt.Errorf("error executing template: %w", err)
+	}
+	return nil
+}
+
+// RenderValue renders a parameter value. A value that is a single action,
+// such as "{{.emails}}" or "{{.count | default 1}}", keeps the type of its
+// result instead of being formatted as text. Anything else renders to a
+// string, with GitHub Actions expressions kept as in ProcessString.
+func RenderValue(text string, params map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	text = literalExpressions(text)
+	tmpl, err := parseTemplate(text, includeFuncs(nil, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	action, ok := singleAction(tmpl)
+	if !ok {
+		return renderText(tmpl, text, params)
+	}
+
+	// Re-run the action's pipeline through a function that captures its result
+	var value interface{}
+	capture := includeFuncs(nil, 0)
+	capture["__value"] = func(v interface{}) string {
+		value = v
+		return ""
+	}
+	valueTmpl, err := parseTemplate(fmt.Sprintf("{{__value (%s)}}", action.Pipe.String()), capture)
+	if err != nil {
+		return nil, err
+	}
+	if err := executeAs(tmpl, valueTmpl, params, io.Discard); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// renderText renders a parameter value that mixes text and actions. Lists
+// and maps it prints are formatted as JSON, as in "hi {{.emails}}".
+func renderText(source *template.Template, text string, params map[string]interface{}) (string, error) {
+	tmpl, err := parseTemplate(text, template.FuncMap{"__text": textValue})
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			printAsText(t.Tree, t.Tree.Root)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := executeAs(source, tmpl, params, &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// printAsText pipes the value of every action under node that prints one
+// through __text
+func printAsText(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			printAsText(tree, child)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      n.Pos,
+				Args:     []parse.Node{parse.NewIdentifier("__text").SetTree(tree).SetPos(n.Pos)},
+			})
+		}
+	case *parse.IfNode:
+		printAsText(tree, n.List)
+		printAsText(tree, n.ElseList)
+	case *parse.RangeNode:
+		printAsText(tree, n.List)
+		printAsText(tree, n.ElseList)
+	case *parse.WithNode:
+		printAsText(tree, n.List)
+		printAsText(tree, n.ElseList)
+	}
+}
+
+// textValue returns lists and maps as JSON, and other values as they are
+func textValue(value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// parseTemplate parses text with the shared functions and the given extra functions
+func parseTemplate(text string, extra template.FuncMap) (*template.Template, error) {
+	tmpl := template.New("template").Funcs(FuncMap()).Funcs(extra).Option("missingkey=error")
+
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// parseWithLayout parses text. A template that starts with
+// {{/* extends "layout" */}} renders the layout (found in includeDirs) with
+// the child's {{define}} blocks replacing the layout's {{block}}s; anything
+// outside those blocks in the child is ignored.
+func parseWithLayout(text string, includeDirs []string, depth int) (*template.Template, error) {
+	match := extendsRegex.FindStringSubmatch(text)
+	if match == nil {
+		return parseTemplate(text, includeFuncs(includeDirs, 0))
+	}
+
+	layout := match[1]
+	if depth >= maxIncludeDepth {
+		return nil, fmt.Errorf("extends %s: layouts nested more than %d deep", layout, maxIncludeDepth)
+	}
+
+	layoutPath, err := findPartial(layout, includeDirs)
+	if err != nil {
+		return nil, fmt.Errorf("extends %s: %w", layout, err)
+	}
+	content, err := vfs.ReadFile(layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading layout %s: %w", layout, err)
+	}
+
+	tmpl, err := parseWithLayout(string(content), includeDirs, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.New("extends").Parse(text[len(match[0]):]); err != nil {
+		return nil, fmt.Errorf("error parsing template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// includeFuncs returns the include function, which renders partials found in
+// includeDirs. depth counts the includes already being rendered.
+func includeFuncs(includeDirs []string, depth int) template.FuncMap {
+	return template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			if depth >= maxIncludeDepth {
+				return "", fmt.Errorf("include %s: includes nested more than %d deep", name, maxIncludeDepth)
+			}
+
+			partialPath, err := findPartial(name, includeDirs)
+			if err != nil {
+				return "", fmt.Errorf("include %s: %w", name, err)
+			}
+			content, err := vfs.ReadFile(partialPath)
+			if err != nil {
+				return "", fmt.Errorf("error reading partial %s: %w", name, err)
+			}
+
+			tmpl, err := parseTemplate(string(content), includeFuncs(includeDirs, depth+1))
+			if err != nil {
+				return "", fmt.Errorf("include %s: %w", name, err)
+			}
+
+			rendered, err := execute(tmpl, data)
+			if err != nil {
+				return "", fmt.Errorf("include %s: %w", name, err)
+			}
+			return string(rendered), nil
+		},
+	}
+}
+
+// findPartial locates a partial or layout in the first include directory containing it
+func findPartial(name string, includeDirs []string) (string, error) {
+	cleaned := filepath.Clean(name)
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("names must be relative to the templates directory")
+	}
+	if len(includeDirs) == 0 {
+		return "", fmt.Errorf("no template directories to look in")
+	}
+
+	for _, dir := range includeDirs {
+		candidate := filepath.Join(dir, cleaned)
+		if info, err := vfs.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("not found in %s", strings.Join(includeDirs, ", "))
+}
+
+// singleAction returns the template's only node if it is an action that
+// prints a value (surrounding whitespace aside)
+func singleAction(tmpl *template.Template) (*parse.ActionNode, bool) {
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return nil, false
+	}
+
+	var action *parse.ActionNode
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			if len(bytes.TrimSpace(n.Text)) > 0 {
+				return nil, false
+			}
+		case *parse.ActionNode:
+			if action != nil || len(n.Pipe.Decl) > 0 {
+				return nil, false
+			}
+			action = n
+		default:
+			return nil, false
+		}
+	}
+
+	return action, action != nil
+}
+
+// fieldPaths calls fn with the path of every top-level field the template
+// refers to, and whether the template only tests or passes on its value
+// rather than printing it. Fields inside range and with blocks are relative
+// to the block's value, so they are skipped.
+func fieldPaths(tmpl *template.Template, fn func(path []string, guarded bool)) {
+	var walk func(node parse.Node, guarded bool)
+	walk = func(node parse.Node, guarded bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, guarded)
+			}
+		case *parse.ActionNode:
+			// Only a lone field, as in {{.name}}, is printed as it is
+			walk(n.Pipe, len(n.Pipe.Decl) > 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1)
+		case *parse.IfNode:
+			walk(n.Pipe, true)
+			walk(n.List, false)
+			walk(n.ElseList, false)
+		case *parse.RangeNode:
+			walk(n.Pipe, true)
+			walk(n.ElseList, false)
+		case *parse.WithNode:
+			walk(n.Pipe, true)
+			walk(n.ElseList, false)
+		case *parse.TemplateNode:
+			walk(n.Pipe, true)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for i, cmd := range n.Cmds {
+				// A function's arguments and a value piped on are passed on
+				passed := guarded || i < len(n.Cmds)-1 || len(cmd.Args) > 1
+				for _, arg := range cmd.Args {
+					walk(arg, passed)
+				}
+			}
+		case *parse.FieldNode:
+			fn(n.Ident, guarded)
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				fn(n.Ident[1:], guarded)
+			}
+		}
+	}
+
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root, false)
+		}
+	}
+}
+
+// RequiredKeys returns the dotted path of every top-level key text needs a
+// value for: keys it refers to, as .name, $.name or index . "name", other
+// than those it only tests with if, with or range or gives a fallback with
+// default. Keys inside range and with blocks are relative to the block's
+// value, so they are skipped.
+func RequiredKeys(text string) ([]string, error) {
+	tmpl, err := parseTemplate(literalExpressions(text), includeFuncs(nil, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	var walk func(node parse.Node, optional bool)
+	walk = func(node parse.Node, optional bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, optional)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, optional)
+		case *parse.IfNode:
+			walk(n.Pipe, true)
+			walk(n.List, optional)
+			walk(n.ElseList, optional)
+		case *parse.RangeNode:
+			walk(n.Pipe, true)
+			walk(n.ElseList, optional)
+		case *parse.WithNode:
+			walk(n.Pipe, true)
+			walk(n.ElseList, optional)
+		case *parse.TemplateNode:
+			walk(n.Pipe, optional)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for i, cmd := range n.Cmds {
+				// A value piped into default has a fallback
+				defaulted := i+1 < len(n.Cmds) && isCall(n.Cmds[i+1], "default")
+				walk(cmd, optional || defaulted)
+			}
+		case *parse.CommandNode:
+			if path, ok := indexPath(n); ok {
+				if !optional {
+					keys[strings.Join(path, ".")] = true
+				}
+				return
+			}
+			for i, arg := range n.Args {
+				// In default "x" .name, the last argument has a fallback
+				defaulted := isCall(n, "default") && i == len(n.Args)-1 && len(n.Args) > 2
+				walk(arg, optional || defaulted)
+			}
+		case *parse.FieldNode:
+			if !optional {
+				keys[strings.Join(n.Ident, ".")] = true
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" && !optional {
+				keys[strings.Join(n.Ident[1:], ".")] = true
+			}
+		}
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			walk(t.Tree.Root, false)
+		}
+	}
+
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// isCall reports whether cmd calls the function name
+func isCall(cmd *parse.CommandNode, name string) bool {
+	if len(cmd.Args) == 0 {
+		return false
+	}
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == name
+}
+
+// indexPath returns the keys of a command such as index . "repo-name" "owner"
+func indexPath(cmd *parse.CommandNode) ([]string, bool) {
+	if !isCall(cmd, "index") || len(cmd.Args) < 3 {
+		return nil, false
+	}
+	if _, ok := cmd.Args[1].(*parse.DotNode); !ok {
+		return nil, false
+	}
+
+	var path []string
+	for _, arg := range cmd.Args[2:] {
+		key, ok := arg.(*parse.StringNode)
+		if !ok {
+			return nil, false
+		}
+		path = append(path, key.Text)
+	}
+	return path, true
+}
+
+// withGuardedKeys returns params with a nil value for every missing key the
+// template only tests or passes on. Maps along the way are copied, so params
+// is not changed.
+func withGuardedKeys(tmpl *template.Template, params map[string]interface{}) map[string]interface{} {
+	result := params
+	copied := false
+	fieldPaths(tmpl, func(path []string, guarded bool) {
+		if !guarded {
+			return
+		}
+		if _, ok := lookupPath(result, path); ok {
+			return
+		}
+		if !copied {
+			result = copyMap(params)
+			copied = true
+		}
+		setMissing(result, path)
+	})
+	return result
+}
+
+// setMissing sets the first missing key along path to nil, copying the maps
+// it passes through
+func setMissing(data map[string]interface{}, path []string) {
+	current := data
+	for _, part := range path {
+		value, ok := current[part]
+		if !ok {
+			current[part] = nil
+			return
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = copyMap(m)
+		current[part] = m
+		current = m
+	}
+}
+
+// copyMap returns a shallow copy of m
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m)+1)
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
+
+// missingValues builds the error for a template that printed a missing key.
+// It lists every top-level field the template prints without a value, or
+// key, the one execution stopped at, when those are all inside blocks.
+func missingValues(tmpl *template.Template, params map[string]interface{}, key string) error {
+	missing := make(map[string]bool)
+	fieldPaths(tmpl, func(path []string, guarded bool) {
+		if _, ok := lookupPath(params, path); !ok && !guarded {
+			missing[strings.Join(path, ".")] = true
+		}
+	})
+
+	keys := make([]string, 0, len(missing))
+	for key := range missing {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		keys = []string{key}
+	}
+
+	return &MissingValuesError{Keys: keys}
+}
+
+// lookupPath walks nested maps along path
+func lookupPath(data map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = data
+	for _, part := range path {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -95,3 +95,248 @@ func TestProcessFile(t *testing.T) {
 	_, err = template.ProcessFile(filepath.Join(tempDir, "nonexistent.tmpl"), params)
 	assert.Error(t, err)
 }
+
+func TestProcessStringFunctions(t *testing.T) {
+	params := map[string]interface{}{
+		"name":     "Darn",
+		"emails":   []interface{}{"a@example.com", "b@example.com"},
+		"controls": []interface{}{"OSPS-VM-04.01"},
+		"repo":     map[string]interface{}{"owner": "kusari-oss", "name": "darn"},
+		"empty":    "",
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "default for missing key", template: `{{.missing | default "none"}}`, expected: "none"},
+		{name: "default for empty value", template: `{{.empty | default "none"}}`, expected: "none"},
+		{name: "default keeps value", template: `{{.name | default "none"}}`, expected: "Darn"},
+		{name: "lower and upper", template: `{{lower .name}}-{{upper .name}}`, expected: "darn-DARN"},
+		{name: "join", template: `{{join ", " .emails}}`, expected: "a@example.com, b@example.com"},
+		{name: "nested keys", template: `{{.repo.owner}}/{{.repo.name}}`, expected: "kusari-oss/darn"},
+		{name: "toJSON", template: `{{toJSON .emails}}`, expected: `["a@example.com","b@example.com"]`},
+		{name: "has", template: `{{if has "OSPS-VM-04.01" .controls}}yes{{else}}no{{end}}`, expected: "yes"},
+		{name: "missing key in condition", template: `{{if .missing}}yes{{else}}no{{end}}`, expected: "no"},
+		{name: "replace", template: `{{.name | replace "D" "B"}}`, expected: "Barn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := template.ProcessString(tt.template, params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	result, err := template.ProcessString(`{{now.Year}}`, params)
+	require.NoError(t, err)
+	assert.Len(t, string(result), 4)
+}
+
+func TestProcessStringMissingValues(t *testing.T) {
+	_, err := template.ProcessString("{{.name}} {{.repo.owner}} {{.missing}}", map[string]interface{}{
+		"name": "Darn",
+		"repo": map[string]interface{}{},
+	})
+	require.Error(t, err)
+
+	var missing *template.MissingValuesError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, []string{"missing", "repo.owner"}, missing.Keys)
+	assert.EqualError(t, err, "missing values for some parameters: missing, repo.owner")
+
+	_, err = template.ProcessString(`{{required "name is required" .name}}`, map[string]interface{}{})
+	assert.ErrorContains(t, err, "name is required")
+
+	// Values that print as <no value> are not missing
+	result, err := template.ProcessString("{{.text}} {{.nothing}}", map[string]interface{}{"text": "<no value>", "nothing": nil})
+	require.NoError(t, err)
+	assert.Equal(t, "<no value> <no value>", string(result))
+
+	// Keys missing inside blocks are reported too
+	_, err = template.ProcessString("{{range .items}}{{.name}}{{end}}", map[string]interface{}{
+		"items": []interface{}{map[string]interface{}{}},
+	})
+	assert.EqualError(t, err, "missing values for some parameters: name")
+
+	// GitHub Actions expressions are kept, alongside darn's own templates
+	result, err = template.ProcessString("run: echo ${{ secrets.TOKEN }} {{.name}}", map[string]interface{}{"name": "Darn"})
+	require.NoError(t, err)
+	assert.Equal(t, "run: echo ${{ secrets.TOKEN }} Darn", string(result))
+
+	// Other text that does not parse is an error, not text
+	for _, text := range []string{`{{.name | defualt "x"}}`, "{{.repo-name}}", "{{.name", "${{ x }} {{.name"} {
+		_, err = template.ProcessString(text, map[string]interface{}{"name": "Darn"})
+		assert.ErrorContains(t, err, "error parsing template", text)
+	}
+}
+
+func TestRenderValue(t *testing.T) {
+	params := map[string]interface{}{
+		"emails": []interface{}{"a@example.com"},
+		"count":  3,
+		"flag":   false,
+		"name":   "Darn",
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected interface{}
+	}{
+		{name: "plain text", value: "no templates", expected: "no templates"},
+		{name: "whole list reference", value: "{{.emails}}", expected: []interface{}{"a@example.com"}},
+		{name: "whole number reference", value: " {{ .count }} ", expected: 3},
+		{name: "false is a value", value: "{{.flag}}", expected: false},
+		{name: "pipeline keeps type", value: "{{.missing | default 5}}", expected: 5},
+		{name: "mixed text renders a string", value: "count={{.count}}", expected: "count=3"},
+		{name: "lists in text render as JSON", value: "to {{.emails}}", expected: `to ["a@example.com"]`},
+		{name: "GitHub expression", value: "${{ secrets.TOKEN }}", expected: "${{ secrets.TOKEN }}"},
+		{name: "GitHub expression in text", value: "${{ github.actor }} {{.name}}", expected: "${{ github.actor }} Darn"},
+		{name: "conditional renders a string", value: "{{if .flag}}on{{else}}off{{end}}", expected: "off"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := template.RenderValue(tt.value, params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+
+	_, err := template.RenderValue("{{.missing}}", params)
+	assert.EqualError(t, err, "missing values for some parameters: missing")
+
+	// Text meant as a template that does not parse is an error
+	for _, text := range []string{"{{.name", `{{.name | defualt "x"}}`, "hi {{.repo-name}}"} {
+		_, err := template.RenderValue(text, params)
+		assert.ErrorContains(t, err, "error parsing template", text)
+	}
+
+	// An explicit nil is a value, not a missing key
+	value, err := template.RenderValue("{{.nothing}}", map[string]interface{}{"nothing": nil})
+	require.NoError(t, err)
+	assert.Nil(t, value)
+}
+
+func TestTemplateFunctions(t *testing.T) {
+	params := map[string]interface{}{
+		"name":     "darn project",
+		"tags":     []interface{}{"go", "cli", "go"},
+		"body":     "line one\nline two",
+		"released": "2024-03-05T10:00:00Z",
+		"stamp":    1700000000,
+		"public":   true,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "title", template: `{{title .name}}`, expected: "Darn Project"},
+		{name: "trimPrefix and trimSuffix", template: `{{.name | trimPrefix "darn " | trimSuffix "ect"}}`, expected: "proj"},
+		{name: "repeat", template: `{{repeat 3 "="}}`, expected: "==="},
+		{name: "quote and squote", template: `{{quote .name}} {{squote .name}}`, expected: `"darn project" 'darn project'`},
+		{name: "indent", template: `{{indent 2 .body}}`, expected: "  line one\n  line two"},
+		{name: "nindent", template: `key:{{nindent 2 .body}}`, expected: "key:\n  line one\n  line two"},
+		{name: "list and join", template: `{{join "," (list "a" "b")}}`, expected: "a,b"},
+		{name: "first and last", template: `{{first .tags}} {{last .tags}}`, expected: "go go"},
+		{name: "uniq", template: `{{join "," (uniq .tags)}}`, expected: "go,cli"},
+		{name: "sortAlpha", template: `{{join "," (sortAlpha .tags)}}`, expected: "cli,go,go"},
+		{name: "date from string", template: `{{date "2006-01-02" .released}}`, expected: "2024-03-05"},
+		{name: "date from timestamp", template: `{{date "2006" .stamp}}`, expected: "2023"},
+		{name: "coalesce", template: `{{coalesce .missing "" "fallback"}}`, expected: "fallback"},
+		{name: "ternary", template: `{{ternary "public" "private" .public}}`, expected: "public"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := template.ProcessString(tt.template, params)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+
+	_, err := template.ProcessString(`{{date "2006" "yesterday"}}`, params)
+	assert.Error(t, err)
+}
+
+func TestProcessFileWithIncludes(t *testing.T) {
+	localDir := t.TempDir()
+	sharedDir := t.TempDir()
+
+	templatePath := filepath.Join(localDir, "security.md.tmpl")
+	require.NoError(t, os.WriteFile(templatePath, []byte("# {{.name}}\n{{include \"partials/contact.md.tmpl\" .}}"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(sharedDir, "partials"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sharedDir, "partials", "contact.md.tmpl"),
+		[]byte("Contact: {{join \", \" .emails}}{{include \"partials/footer.md.tmpl\" .}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sharedDir, "partials", "footer.md.tmpl"),
+		[]byte("\n-- {{.name | upper}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(sharedDir, "loop.tmpl"), []byte(`{{include "loop.tmpl" .}}`), 0644))
+
+	params := map[string]interface{}{
+		"name":   "Darn",
+		"emails": []interface{}{"a@example.com", "b@example.com"},
+	}
+
+	result, err := template.ProcessFileWithIncludes(templatePath, params, []string{localDir, sharedDir})
+	require.NoError(t, err)
+	assert.Equal(t, "# Darn\nContact: a@example.com, b@example.com\n-- DARN", string(result))
+
+	// Without the shared directory the partial cannot be found
+	_, err = template.ProcessFile(templatePath, params)
+	assert.ErrorContains(t, err, "include partials/contact.md.tmpl: not found in")
+
+	_, err = template.ProcessString(`{{include "loop.tmpl" .}}`, params)
+	assert.ErrorContains(t, err, "no template directories to look in")
+
+	loopPath := filepath.Join(sharedDir, "loop.tmpl")
+	_, err = template.ProcessFileWithIncludes(loopPath, params, []string{sharedDir})
+	assert.ErrorContains(t, err, "nested more than")
+
+	// Missing values inside a partial are reported with the partial's name
+	require.NoError(t, os.WriteFile(filepath.Join(sharedDir, "owner.tmpl"), []byte("Owner: {{.owner}}"), 0644))
+	ownerPath := filepath.Join(localDir, "owner.tmpl")
+	require.NoError(t, os.WriteFile(ownerPath, []byte(`{{include "owner.tmpl" .}}`), 0644))
+	_, err = template.ProcessFileWithIncludes(ownerPath, params, []string{sharedDir})
+	assert.ErrorContains(t, err, "include owner.tmpl: missing values for some parameters: owner")
+
+	escapePath := filepath.Join(localDir, "escape.tmpl")
+	require.NoError(t, os.WriteFile(escapePath, []byte(`{{include "../secret" .}}`), 0644))
+	_, err = template.ProcessFile(escapePath, params)
+	assert.ErrorContains(t, err, "must be relative")
+}
+
+func TestProcessFileExtendsLayout(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "layouts"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "layouts", "base.md.tmpl"),
+		[]byte("# {{block \"title\" .}}Untitled{{end}}\n{{block \"body\" .}}No content{{end}}\n-- {{.name}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "layouts", "policy.md.tmpl"),
+		[]byte("{{/* extends \"layouts/base.md.tmpl\" */}}{{define \"title\"}}Policy for {{.name}}{{end}}"), 0644))
+
+	params := map[string]interface{}{"name": "Darn"}
+
+	childPath := filepath.Join(dir, "security.md.tmpl")
+	require.NoError(t, os.WriteFile(childPath, []byte(`{{/* extends "layouts/base.md.tmpl" */}}
+{{define "title"}}Security{{end}}
+{{define "body"}}Report issues privately.{{end}}`), 0644))
+	result, err := template.ProcessFile(childPath, params)
+	require.NoError(t, err)
+	assert.Equal(t, "# Security\nReport issues privately.\n-- Darn", string(result))
+
+	// Layouts can extend other layouts; blocks the child does not define keep their defaults
+	nestedPath := filepath.Join(dir, "nested.md.tmpl")
+	require.NoError(t, os.WriteFile(nestedPath, []byte(`{{/* extends "layouts/policy.md.tmpl" */}}`), 0644))
+	result, err = template.ProcessFile(nestedPath, params)
+	require.NoError(t, err)
+	assert.Equal(t, "# Policy for Darn\nNo content\n-- Darn", string(result))
+
+	missingPath := filepath.Join(dir, "missing.md.tmpl")
+	require.NoError(t, os.WriteFile(missingPath, []byte(`{{/* extends "layouts/none.tmpl" */}}`), 0644))
+	_, err = template.ProcessFile(missingPath, params)
+	assert.ErrorContains(t, err, "extends layouts/none.tmpl: not found in")
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package vfs reads library files from disk or from an fs.FS mounted under
+// a scheme. A path that starts with a mounted scheme and a colon, such as
+// "embedded:/actions/add-security-md.yaml", is read from the scheme's fs.FS;
+// every other path is read from disk. Paths under a scheme can be built with
+// filepath.Join like any other path, and a disk path that starts with the
+// same name can still be read by writing it as "./embedded:".
+package vfs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// schemeRegex matches mount schemes. Single letters are left to Windows
+// drive letters.
+var schemeRegex = regexp.MustCompile(`^[a-z][a-z0-9+.-]+$`)
+
+var (
+	mu     sync.RWMutex
+	mounts = make(map[string]fs.FS)
+)
+
+// Mount serves fsys under scheme and returns the root path of the mount,
+// "<scheme>:". It panics if the scheme is not valid.
+func Mount(scheme string, fsys fs.FS) string {
+	if !schemeRegex.MatchString(scheme) {
+		panic(fmt.Sprintf("vfs: invalid scheme %q", scheme))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	mounts[scheme] = fsys
+	return scheme + ":"
+}
+
+// lookup returns the file system mounted under path's scheme and path's
+// name in it
+func lookup(p string) (fs.FS, string, bool) {
+	scheme, rest, ok := strings.Cut(p, ":")
+	if !ok {
+		return nil, "", false
+	}
+
+	mu.RLock()
+	fsys, ok := mounts[scheme]
+	mu.RUnlock()
+	if !ok {
+		return nil, "", false
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(rest)), "/")
+	if name == "" {
+		name = "."
+	}
+	return fsys, name, true
+}
+
+// ReadFile reads the file at path, like os.ReadFile
+func ReadFile(path string) ([]byte, error) {
+	if fsys, name, ok := lookup(path); ok {
+		return fs.ReadFile(fsys, name)
+	}
+	return os.ReadFile(path)
+}
+
+// Stat describes the file at path, like os.Stat
+func Stat(path string) (fs.FileInfo, error) {
+	if fsys, name, ok := lookup(path); ok {
+		return fs.Stat(fsys, name)
+	}
+	return os.Stat(path)
+}
+
+// ReadDir reads the directory at path, sorted by file name, like os.ReadDir
+func ReadDir(path string) ([]fs.DirEntry, error) {
+	if fsys, name, ok := lookup(path); ok {
+		return fs.ReadDir(fsys, name)
+	}
+	return os.ReadDir(path)
+}
+
+// WalkDir walks the tree at root, like filepath.WalkDir. Paths passed to fn
+// start with root, whether the tree is mounted or on disk.
+func WalkDir(root string, fn fs.WalkDirFunc) error {
+	fsys, name, ok := lookup(root)
+	if !ok {
+		return filepath.WalkDir(root, fn)
+	}
+	return fs.WalkDir(fsys, name, func(path string, entry fs.DirEntry, err error) error {
+		// Walked paths start with name; "." joins to root
+		rel := path
+		if name != "." {
+			rel = strings.TrimPrefix(strings.TrimPrefix(path, name), "/")
+		}
+		return fn(filepath.Join(root, filepath.FromSlash(rel)), entry, err)
+	})
+}
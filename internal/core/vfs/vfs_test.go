@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package vfs_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kusari-oss/darn/internal/core/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMountedFS(t *testing.T) {
+	root := vfs.Mount("vfs-test", fstest.MapFS{
+		"templates/readme.tmpl":      {Data: []byte("# {{.name}}\n")},
+		"templates/docs/guide.md":    {Data: []byte("guide\n")},
+		"templates/docs/_partial.md": {Data: []byte("partial\n")},
+	})
+	assert.Equal(t, "vfs-test:", root)
+
+	content, err := vfs.ReadFile(filepath.Join(root, "templates", "readme.tmpl"))
+	require.NoError(t, err)
+	assert.Equal(t, "# {{.name}}\n", string(content))
+
+	info, err := vfs.Stat(filepath.Join(root, "templates", "docs"))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	_, err = vfs.Stat(filepath.Join(root, "templates", "missing.tmpl"))
+	assert.True(t, os.IsNotExist(err))
+
+	entries, err := vfs.ReadDir(filepath.Join(root, "templates"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "docs", entries[0].Name())
+
+	docsDir := filepath.Join(root, "templates", "docs")
+	var walked []string
+	require.NoError(t, vfs.WalkDir(docsDir, func(path string, entry fs.DirEntry, err error) error {
+		walked = append(walked, path)
+		return err
+	}))
+	assert.Equal(t, []string{docsDir, filepath.Join(docsDir, "_partial.md"), filepath.Join(docsDir, "guide.md")}, walked)
+}
+
+func TestUnmountedPaths(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("on disk"), 0644))
+
+	content, err := vfs.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "on disk", string(content))
+
+	// A directory named like a mount is on disk when the path does not start with the scheme
+	vfs.Mount("vfs-shadow", fstest.MapFS{})
+	shadowed := filepath.Join(t.TempDir(), "vfs-shadow:", "file.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(shadowed), 0755))
+	require.NoError(t, os.WriteFile(shadowed, []byte("also on disk"), 0644))
+	content, err = vfs.ReadFile(shadowed)
+	require.NoError(t, err)
+	assert.Equal(t, "also on disk", string(content))
+	_, err = vfs.Stat(filepath.Join("vfs-shadow:", "file.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Panics(t, func() { vfs.Mount("c", fstest.MapFS{}) })
+
+	var walked []string
+	require.NoError(t, vfs.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		walked = append(walked, path)
+		return err
+	}))
+	assert.Equal(t, []string{dir, path}, walked)
+}
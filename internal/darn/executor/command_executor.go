@@ -8,6 +8,8 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/template"
@@ -21,6 +23,7 @@ type CommandExecutor struct {
 	workingDir  string
 	environment []string
 	verbose     bool
+	output      io.Writer // Destination for progress and verbose output (defaults to os.Stdout)
 }
 
 // CommandResult holds the result of command execution
@@ -56,6 +59,12 @@ func (e *CommandExecutor) WithVerbose(verbose bool) *CommandExecutor {
 	return e
 }
 
+// WithOutput sets where progress and verbose command output is written
+func (e *CommandExecutor) WithOutput(output io.Writer) *CommandExecutor {
+	e.output = output
+	return e
+}
+
 // ProcessParameters processes command and arguments with template parameters
 func (e *CommandExecutor) ProcessParameters(params map[string]interface{}) error {
 	// Process command with templating
@@ -76,8 +85,12 @@ func (e *CommandExecutor) ProcessParameters(params map[string]interface{}) error
 	}
 	e.args = processedArgs
 
-	// Set working directory if specified in params
+	// Set working directory if specified in params. A relative directory is
+	// resolved against the executor's working directory when one is set.
 	if workingDir, ok := params["working_dir"].(string); ok && workingDir != "" {
+		if !filepath.IsAbs(workingDir) && e.workingDir != "" {
+			workingDir = filepath.Join(e.workingDir, workingDir)
+		}
 		e.workingDir = workingDir
 	}
 
@@ -99,6 +112,24 @@ func (e *CommandExecutor) ProcessParameters(params map[string]interface{}) error
 	return nil
 }
 
+// CommandLine returns the command and its arguments as a shell-style line.
+// Arguments that are empty or contain spaces or quotes are quoted.
+func (e *CommandExecutor) CommandLine() string {
+	parts := make([]string, 0, len(e.args)+1)
+	for _, part := range append([]string{e.command}, e.args...) {
+		if part == "" || strings.ContainsAny(part, " \t\n'\"") {
+			part = strconv.Quote(part)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// WorkingDir returns the directory the command runs in; "" means the process working directory
+func (e *CommandExecutor) WorkingDir() string {
+	return e.workingDir
+}
+
 // Execute runs the command and returns its output
 func (e *CommandExecutor) Execute() (*CommandResult, error) {
 	// Create and configure the command
@@ -106,10 +137,19 @@ func (e *CommandExecutor) Execute() (*CommandResult, error) {
 
 	var stdout, stderr bytes.Buffer
 
+	output := e.output
+	if output == nil {
+		output = os.Stdout
+	}
+
 	// Configure stdout/stderr based on verbosity
 	if e.verbose {
-		cmd.Stdout = io.MultiWriter(&stdout, os.Stdout)
-		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+		errOutput := output
+		if e.output == nil {
+			errOutput = os.Stderr
+		}
+		cmd.Stdout = io.MultiWriter(&stdout, output)
+		cmd.Stderr = io.MultiWriter(&stderr, errOutput)
 	} else {
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
@@ -126,7 +166,7 @@ func (e *CommandExecutor) Execute() (*CommandResult, error) {
 	}
 
 	// Print the command being executed
-	fmt.Printf("Executing: %s %s\n", e.command, strings.Join(e.args, " "))
+	fmt.Fprintf(output, "Executing: %s %s\n", e.command, strings.Join(e.args, " "))
 
 	// Run the command
 	err := cmd.Run()
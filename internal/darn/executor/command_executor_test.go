@@ -3,9 +3,11 @@
 package executor_test
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/kusari-oss/darn/internal/darn/executor"
@@ -187,3 +189,45 @@ func TestCommandExecutorWithMultipleArguments(t *testing.T) {
 	assert.NoError(t, err, "Failed to execute command")
 	assert.Contains(t, string(result.Output), "one two three")
 }
+
+func TestCommandExecutorWithOutput(t *testing.T) {
+	// Skip tests if running on Windows
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows")
+	}
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workingDir, "sub"), 0755))
+
+	var output bytes.Buffer
+	cmdExecutor := executor.NewCommandExecutor("pwd", nil).
+		WithWorkingDir(workingDir).
+		WithOutput(&output).
+		WithVerbose(true)
+
+	// A relative working_dir parameter is resolved against the executor's working directory
+	err := cmdExecutor.ProcessParameters(map[string]interface{}{
+		"working_dir": "sub",
+	})
+	require.NoError(t, err, "Failed to process parameters")
+
+	result, err := cmdExecutor.Execute()
+	require.NoError(t, err, "Failed to execute command")
+
+	resolvedDir, err := filepath.EvalSymlinks(filepath.Join(workingDir, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, resolvedDir, strings.TrimSpace(string(result.Output)))
+
+	// Progress and verbose command output go to the configured writer
+	assert.Contains(t, output.String(), "Executing: pwd")
+	assert.Contains(t, output.String(), resolvedDir)
+}
+
+func TestCommandExecutorCommandLine(t *testing.T) {
+	cmdExecutor := executor.NewCommandExecutor("gh", []string{"issue", "create", "--title", "{{.title}}", "--body", ""}).
+		WithWorkingDir("/tmp/repo")
+
+	require.NoError(t, cmdExecutor.ProcessParameters(map[string]interface{}{"title": "Add \"SECURITY.md\""}))
+	assert.Equal(t, `gh issue create --title "Add \"SECURITY.md\"" --body ""`, cmdExecutor.CommandLine())
+	assert.Equal(t, "/tmp/repo", cmdExecutor.WorkingDir())
+}
@@ -0,0 +1,387 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package librarytest runs the golden tests a library keeps under tests/.
+// Each test case generates a plan from a mapping and a fixture report, or
+// runs a single action, renders the plan's file actions into a temporary
+// directory, and compares the plan and the files with the expected ones.
+package librarytest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/kusari-oss/darn/internal/core/diff"
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"gopkg.in/yaml.v3"
+)
+
+// TestsDir is the library directory that holds test cases, one per directory
+const TestsDir = "tests"
+
+// CaseFile describes a test case in its directory
+const CaseFile = "test.yaml"
+
+// Case is a golden test case. Paths are relative to the case's directory,
+// except Mapping, which is looked up in the library's mappings directory.
+type Case struct {
+	Name string `yaml:"-"` // Directory name
+	Dir  string `yaml:"-"`
+
+	Description string `yaml:"description,omitempty"`
+
+	// What to run: a mapping with a fixture report, or a single action
+	Mapping string `yaml:"mapping,omitempty"`
+	Report  string `yaml:"report,omitempty"`
+	Action  string `yaml:"action,omitempty"`
+
+	// Parameters from a JSON or YAML file, overridden by inline parameters
+	Params     string                 `yaml:"params,omitempty"`
+	Parameters map[string]interface{} `yaml:"parameters,omitempty"`
+
+	// Goldens: the plan (YAML, or JSON by extension) and a directory holding
+	// every file the plan's file actions write
+	ExpectedPlan  string `yaml:"expected_plan,omitempty"`
+	ExpectedFiles string `yaml:"expected_files,omitempty"`
+}
+
+// Status is the outcome of a test case
+type Status string
+
+const (
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"  // The results differ from the goldens
+	StatusUpdated Status = "updated" // The goldens were rewritten with --update
+	StatusError   Status = "error"   // The case could not run
+)
+
+// Result is the outcome of running a test case
+type Result struct {
+	Case     *Case
+	Status   Status
+	Failures []string // Differences from the goldens, as unified diffs
+	Skipped  []string // Steps that were not run because they are not file actions
+	Err      error
+}
+
+// Discover loads the test cases in a library's tests directory, sorted by
+// name. Cases whose names do not match filter are left out.
+func Discover(libraryPath string, filter *regexp.Regexp) ([]*Case, error) {
+	testsDir := filepath.Join(libraryPath, TestsDir)
+	entries, err := os.ReadDir(testsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading tests directory: %w", err)
+	}
+
+	var cases []*Case
+	for _, entry := range entries {
+		if !entry.IsDir() || (filter != nil && !filter.MatchString(entry.Name())) {
+			continue
+		}
+		dir := filepath.Join(testsDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, CaseFile))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading test case %s: %w", entry.Name(), err)
+		}
+
+		testCase := &Case{}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(testCase); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error parsing test case %s: %w", entry.Name(), err)
+		}
+		testCase.Name = entry.Name()
+		testCase.Dir = dir
+		if err := testCase.validate(); err != nil {
+			return nil, fmt.Errorf("invalid test case %s: %w", entry.Name(), err)
+		}
+		cases = append(cases, testCase)
+	}
+
+	sort.Slice(cases, func(i, j int) bool { return cases[i].Name < cases[j].Name })
+	return cases, nil
+}
+
+// validate checks that the case names one thing to run and a golden
+func (c *Case) validate() error {
+	switch {
+	case c.Mapping != "" && c.Action != "":
+		return fmt.Errorf("set either mapping or action, not both")
+	case c.Mapping != "" && c.Report == "":
+		return fmt.Errorf("mapping tests need a report")
+	case c.Mapping == "" && c.Action == "":
+		return fmt.Errorf("set a mapping and report, or an action")
+	case c.Action != "" && c.ExpectedPlan != "":
+		return fmt.Errorf("action tests have no plan; use expected_files")
+	case c.ExpectedPlan == "" && c.ExpectedFiles == "":
+		return fmt.Errorf("set expected_plan, expected_files or both")
+	}
+
+	// --update writes the goldens and replaces the expected_files
+	// directory, so both must be inside the case directory
+	goldens := []struct{ field, path string }{
+		{"expected_plan", c.ExpectedPlan},
+		{"expected_files", c.ExpectedFiles},
+	}
+	for _, golden := range goldens {
+		if golden.path != "" && (!filepath.IsLocal(golden.path) || filepath.Clean(golden.path) == ".") {
+			return fmt.Errorf("%s %q must be a path inside the test case directory", golden.field, golden.path)
+		}
+	}
+	return nil
+}
+
+// Run runs a test case against the library. With update, the goldens are
+// rewritten from the results instead of compared with them.
+func Run(libraryPath string, c *Case, update bool) *Result {
+	result := &Result{Case: c}
+	if err := run(libraryPath, c, update, result); err != nil {
+		result.Status = StatusError
+		result.Err = err
+		return result
+	}
+
+	switch {
+	case len(result.Failures) > 0:
+		result.Status = StatusFailed
+	case update:
+		result.Status = StatusUpdated
+	default:
+		result.Status = StatusPassed
+	}
+	return result
+}
+
+func run(libraryPath string, c *Case, update bool, result *Result) error {
+	// Cases built by hand have not been through Discover
+	if err := c.validate(); err != nil {
+		return fmt.Errorf("invalid test case: %w", err)
+	}
+
+	absLibraryPath, err := filepath.Abs(libraryPath)
+	if err != nil {
+		return fmt.Errorf("error resolving library path: %w", err)
+	}
+
+	params := make(map[string]interface{})
+	if c.Params != "" {
+		if err := format.ParseFile(filepath.Join(c.Dir, c.Params), &params); err != nil {
+			return fmt.Errorf("error loading params: %w", err)
+		}
+	}
+	for key, value := range c.Parameters {
+		params[key] = value
+	}
+
+	// Files are rendered into an empty directory, so every file there is
+	// one the plan wrote
+	workDir, err := os.MkdirTemp("", "darn-library-test-")
+	if err != nil {
+		return fmt.Errorf("error creating work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	var remediationPlan *models.RemediationPlan
+	if c.Mapping != "" {
+		report, err := darnit.ParseReportFile(filepath.Join(c.Dir, c.Report))
+		if err != nil {
+			return err
+		}
+		mappingsDir := filepath.Join(absLibraryPath, "mappings")
+		remediationPlan, err = plan.GenerateRemediationPlan(report, filepath.Join(mappingsDir, c.Mapping), darnit.GenerateOptions{
+			RepoPath:          workDir,
+			MappingsDir:       mappingsDir,
+			ExtraParams:       params,
+			SkipDefaults:      true,
+			SkipRepoInference: true,
+			NonInteractive:    true,
+			LibraryPath:       absLibraryPath,
+		})
+		if err != nil {
+			return fmt.Errorf("error generating plan: %w", err)
+		}
+	} else {
+		remediationPlan = &models.RemediationPlan{
+			Steps: []models.RemediationStep{{ID: c.Action, ActionName: c.Action, Params: params}},
+		}
+	}
+
+	if c.ExpectedPlan != "" {
+		if err := checkPlan(c, remediationPlan, update, result); err != nil {
+			return err
+		}
+	}
+	if c.ExpectedFiles != "" {
+		if err := renderFiles(absLibraryPath, workDir, remediationPlan, result); err != nil {
+			return err
+		}
+		if err := checkFiles(c, workDir, update, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPlan compares the plan with the expected plan. Both are formatted
+// the same way first, so the golden's formatting does not matter.
+func checkPlan(c *Case, remediationPlan *models.RemediationPlan, update bool, result *Result) error {
+	path := filepath.Join(c.Dir, c.ExpectedPlan)
+	useYAML := !format.IsJSONFile(path)
+	actual, err := format.FormatData(remediationPlan, useYAML)
+	if err != nil {
+		return err
+	}
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("error creating golden directory: %w", err)
+		}
+		return os.WriteFile(path, []byte(actual), 0644)
+	}
+
+	expected := ""
+	if _, err := os.Stat(path); err == nil {
+		expectedPlan, err := darnit.LoadPlanFile(path)
+		if err != nil {
+			return fmt.Errorf("error loading expected plan: %w", err)
+		}
+		if expected, err = format.FormatData(expectedPlan, useYAML); err != nil {
+			return err
+		}
+	}
+	if d := diff.Unified(c.ExpectedPlan, "plan", []byte(expected), []byte(actual)); d != "" {
+		result.Failures = append(result.Failures, d)
+	}
+	return nil
+}
+
+// renderFiles runs the plan's file actions in workDir. Other actions, such
+// as commands, are not run and are reported as skipped.
+func renderFiles(libraryPath, workDir string, remediationPlan *models.RemediationPlan, result *Result) error {
+	_, resolver, err := darnit.CreateLibraryActionResolver(workDir, libraryPath, io.Discard)
+	if err != nil {
+		return fmt.Errorf("error creating action resolver: %w", err)
+	}
+
+	for _, step := range remediationPlan.Steps {
+		actionConfig, err := resolver.GetActionConfig(step.ActionName)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+		if actionConfig.Type != "file" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s (%s action %s)", step.ID, actionConfig.Type, step.ActionName))
+			continue
+		}
+
+		act, err := resolver.ResolveAction(step.ActionName)
+		if err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+		if err := act.Execute(step.Params); err != nil {
+			return fmt.Errorf("step %s: %w", step.ID, err)
+		}
+	}
+	return nil
+}
+
+// checkFiles compares the files rendered into workDir with the expected
+// files directory, or replaces the directory with them on update
+func checkFiles(c *Case, workDir string, update bool, result *Result) error {
+	expectedDir := filepath.Join(c.Dir, c.ExpectedFiles)
+	actual, err := readTree(workDir)
+	if err != nil {
+		return err
+	}
+
+	if update {
+		if err := os.RemoveAll(expectedDir); err != nil {
+			return fmt.Errorf("error removing expected files: %w", err)
+		}
+		if err := os.MkdirAll(expectedDir, 0755); err != nil {
+			return fmt.Errorf("error creating expected files directory: %w", err)
+		}
+		for path, content := range actual {
+			fullPath := filepath.Join(expectedDir, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				return fmt.Errorf("error creating expected files directory: %w", err)
+			}
+			if err := os.WriteFile(fullPath, content, 0644); err != nil {
+				return fmt.Errorf("error writing expected file: %w", err)
+			}
+		}
+		return nil
+	}
+
+	expected := make(map[string][]byte)
+	if _, err := os.Stat(expectedDir); err == nil {
+		if expected, err = readTree(expectedDir); err != nil {
+			return err
+		}
+	}
+
+	paths := make([]string, 0, len(actual)+len(expected))
+	for path := range actual {
+		paths = append(paths, path)
+	}
+	for path := range expected {
+		if _, ok := actual[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		expectedContent, inExpected := expected[path]
+		actualContent, inActual := actual[path]
+		switch {
+		case !inExpected:
+			result.Failures = append(result.Failures, fmt.Sprintf("unexpected file %s", path))
+		case !inActual:
+			result.Failures = append(result.Failures, fmt.Sprintf("missing file %s", path))
+		default:
+			golden := filepath.ToSlash(filepath.Join(c.ExpectedFiles, path))
+			if d := diff.Unified(golden, path, expectedContent, actualContent); d != "" {
+				result.Failures = append(result.Failures, d)
+			}
+		}
+	}
+	return nil
+}
+
+// readTree reads every file under root, by slash-separated relative path
+func readTree(root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relPath)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package librarytest_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/darn/librarytest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		fullPath := filepath.Join(root, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+}
+
+// setupLibrary writes a library with a file action, a cli action, a mapping
+// that uses both and two test cases
+func setupLibrary(t *testing.T) string {
+	t.Helper()
+	t.Setenv("DARN_HOME", t.TempDir())
+	lib := t.TempDir()
+	writeFiles(t, lib, map[string]string{
+		"templates/security.md.tmpl": "# Security policy for {{.name}}\n",
+		"actions/add-security-md.yaml": `apiVersion: darn/v1
+kind: Action
+name: add-security-md
+type: file
+template_path: security.md.tmpl
+target_path: "{{.dir}}/SECURITY.md"
+create_dirs: true
+schema:
+  type: object
+  required: [name, dir]
+`,
+		"actions/enable-mfa.yaml": `apiVersion: darn/v1
+kind: Action
+name: enable-mfa
+type: cli
+command: "false"
+`,
+		"mappings/security.yaml": `mappings:
+  - id: security-md
+    condition: "security_policy == 'missing'"
+    action: add-security-md
+    reason: Add a security policy
+    parameters:
+      name: "{{.project_name}}"
+      dir: docs
+  - id: mfa
+    condition: "mfa_status == 'disabled'"
+    action: enable-mfa
+    reason: Enable MFA
+`,
+		"tests/missing-policy/test.yaml": `description: A missing policy adds SECURITY.md
+mapping: security.yaml
+report: report.json
+parameters:
+  project_name: demo
+expected_plan: plan.golden.yaml
+expected_files: files
+`,
+		"tests/missing-policy/report.json": `{"security_policy": "missing", "mfa_status": "disabled"}`,
+		"tests/action-only/test.yaml": `action: add-security-md
+params: params.yaml
+expected_files: files
+`,
+		"tests/action-only/params.yaml": "name: solo\ndir: .\n",
+	})
+	return lib
+}
+
+func runAll(t *testing.T, lib string, update bool) map[string]*librarytest.Result {
+	t.Helper()
+	cases, err := librarytest.Discover(lib, nil)
+	require.NoError(t, err)
+	results := make(map[string]*librarytest.Result)
+	for _, c := range cases {
+		results[c.Name] = librarytest.Run(lib, c, update)
+	}
+	return results
+}
+
+func TestRun_UpdateThenPass(t *testing.T) {
+	lib := setupLibrary(t)
+
+	results := runAll(t, lib, true)
+	require.Len(t, results, 2)
+	for name, result := range results {
+		require.NoError(t, result.Err, name)
+		assert.Equal(t, librarytest.StatusUpdated, result.Status, name)
+	}
+	assert.Equal(t, []string{"mfa (cli action enable-mfa)"}, results["missing-policy"].Skipped)
+
+	golden, err := os.ReadFile(filepath.Join(lib, "tests/missing-policy/files/docs/SECURITY.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Security policy for demo\n", string(golden))
+	plan, err := os.ReadFile(filepath.Join(lib, "tests/missing-policy/plan.golden.yaml"))
+	require.NoError(t, err)
+	assert.Contains(t, string(plan), "action_name: enable-mfa")
+	assert.FileExists(t, filepath.Join(lib, "tests/action-only/files/SECURITY.md"))
+
+	results = runAll(t, lib, false)
+	for name, result := range results {
+		assert.Equal(t, librarytest.StatusPassed, result.Status, name)
+		assert.Empty(t, result.Failures, name)
+	}
+}
+
+func TestRun_Differences(t *testing.T) {
+	lib := setupLibrary(t)
+	runAll(t, lib, true)
+
+	// Change the template and the report, and add a golden file nothing writes
+	writeFiles(t, lib, map[string]string{
+		"templates/security.md.tmpl":               "# Security for {{.name}}\n",
+		"tests/missing-policy/files/docs/EXTRA.md": "stale\n",
+		"tests/missing-policy/report.json":         `{"security_policy": "missing", "mfa_status": "enabled"}`,
+	})
+
+	results := runAll(t, lib, false)
+	result := results["missing-policy"]
+	require.NoError(t, result.Err)
+	assert.Equal(t, librarytest.StatusFailed, result.Status)
+	require.Len(t, result.Failures, 3)
+	assert.Contains(t, result.Failures[0], "-    - id: mfa")
+	assert.Equal(t, "missing file docs/EXTRA.md", result.Failures[1])
+	assert.Contains(t, result.Failures[2], "+# Security for demo")
+
+	assert.Equal(t, librarytest.StatusFailed, results["action-only"].Status)
+}
+
+func TestRun_Error(t *testing.T) {
+	lib := setupLibrary(t)
+	writeFiles(t, lib, map[string]string{
+		"tests/bad-params/test.yaml": "action: add-security-md\nparameters:\n  name: x\nexpected_files: files\n",
+	})
+
+	cases, err := librarytest.Discover(lib, regexp.MustCompile("^bad"))
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+
+	result := librarytest.Run(lib, cases[0], false)
+	assert.Equal(t, librarytest.StatusError, result.Status)
+	assert.Contains(t, result.Err.Error(), "step add-security-md")
+}
+
+func TestDiscover_Invalid(t *testing.T) {
+	lib := t.TempDir()
+
+	cases, err := librarytest.Discover(lib, nil)
+	require.NoError(t, err)
+	assert.Empty(t, cases)
+
+	writeFiles(t, lib, map[string]string{"tests/broken/test.yaml": "mapping: m.yaml\nexpected_plan: plan.yaml\n"})
+	_, err = librarytest.Discover(lib, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mapping tests need a report")
+
+	writeFiles(t, lib, map[string]string{"tests/broken/test.yaml": "action: a\nexpectd_files: files\n"})
+	_, err = librarytest.Discover(lib, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expectd_files")
+
+	// Goldens outside the case directory would be overwritten by --update
+	for _, golden := range []string{"expected_files: ../../actions", "expected_files: .", "expected_plan: /tmp/plan.yaml", "expected_plan: files/../../plan.yaml"} {
+		writeFiles(t, lib, map[string]string{"tests/broken/test.yaml": "mapping: m.yaml\nreport: r.json\n" + golden + "\n"})
+		_, err = librarytest.Discover(lib, nil)
+		require.Error(t, err, golden)
+		assert.Contains(t, err.Error(), "must be a path inside the test case directory", golden)
+	}
+}
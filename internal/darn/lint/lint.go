@@ -0,0 +1,712 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lint checks a library's actions, templates and mappings for
+// mistakes that darn would otherwise only report, or silently ignore, when
+// an action runs.
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/schema"
+	"github.com/kusari-oss/darn/internal/core/template"
+	"github.com/kusari-oss/darn/internal/core/vfs"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/kusari-oss/darn/internal/defaults"
+	"github.com/kusari-oss/darn/internal/version"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity is how serious a finding is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // darn fails or ignores part of the library
+	SeverityWarning Severity = "warning" // Probably a mistake
+)
+
+// Rule identifies a check
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// Rules are the checks lint runs
+var (
+	RuleYAML             = Rule{"yaml-parse", "File is not valid YAML"}
+	RuleUnknownKey       = Rule{"unknown-key", "Key is not read by darn"}
+	RuleManifest         = Rule{"library-manifest", "Library manifest or its dependencies cannot be resolved"}
+	RuleActionVersion    = Rule{"action-version", "Action has no apiVersion and kind, or unsupported ones"}
+	RuleActionName       = Rule{"action-name", "Action name is missing, duplicated or does not match its file name"}
+	RuleActionFields     = Rule{"action-fields", "Action is missing fields its type requires or sets fields its type ignores"}
+	RuleTemplateMissing  = Rule{"template-missing", "Referenced template does not exist"}
+	RuleTemplateParse    = Rule{"template-parse", "Template does not parse"}
+	RuleSchema           = Rule{"schema-invalid", "Parameter schema is not a valid JSON schema"}
+	RuleOutputParser     = Rule{"output-parser", "Output parser is invalid or never used"}
+	RuleMappingReference = Rule{"mapping-reference", "Mapping refers to an action or mapping file that does not exist"}
+)
+
+// AllRules lists every rule, in the order reports describe them
+var AllRules = []Rule{
+	RuleYAML, RuleUnknownKey, RuleManifest, RuleActionVersion, RuleActionName, RuleActionFields,
+	RuleTemplateMissing, RuleTemplateParse, RuleSchema, RuleOutputParser, RuleMappingReference,
+}
+
+// Finding is a problem found in a library file
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"` // Slash-separated path relative to the library
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Report holds the findings for a library
+type Report struct {
+	Library  string    `json:"library"`
+	Findings []Finding `json:"findings"`
+}
+
+// Count returns the number of findings with the given severity
+func (r *Report) Count(severity Severity) int {
+	count := 0
+	for _, finding := range r.Findings {
+		if finding.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// HasErrors reports whether any finding is an error
+func (r *Report) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// actionKeys are the keys darn reads from action files
+var actionKeys = yamlKeys(reflect.TypeOf(action.Config{}))
+
+// outputKeys are the keys of a cli action's output parser
+var outputKeys = map[string]bool{"format": true, "path": true, "pattern": true}
+
+// fileOnlyKeys are the action keys only file actions read
+var fileOnlyKeys = []string{"template_path", "target_path", "create_dirs", "on_exists", "template_dir", "target_dir", "file_conditions"}
+
+// cliOnlyKeys are the action keys only cli actions read
+var cliOnlyKeys = []string{"command", "args", "outputs"}
+
+// yamlLineRegex extracts the line from a yaml.v3 decoding error
+var yamlLineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// LintLibrary checks the library at libraryPath
+func LintLibrary(libraryPath string) (*Report, error) {
+	absPath, err := filepath.Abs(libraryPath)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving library path: %w", err)
+	}
+	if info, err := os.Stat(absPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("library directory not found: %s", absPath)
+	}
+
+	l := &linter{
+		root:         absPath,
+		report:       &Report{Library: absPath, Findings: []Finding{}},
+		templateDirs: []string{filepath.Join(absPath, "templates")},
+		actions:      make(map[string]string),
+		dependencies: make(map[string]bool),
+		builtin:      make(map[string]bool),
+	}
+	l.resolveDependencies()
+	l.addEmbeddedDefaults()
+
+	actionFiles, err := yamlFiles(filepath.Join(absPath, "actions"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range actionFiles {
+		l.lintAction(path)
+	}
+
+	if err := l.lintTemplates(); err != nil {
+		return nil, err
+	}
+
+	mappingFiles, err := yamlFiles(filepath.Join(absPath, "mappings"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range mappingFiles {
+		l.lintMapping(path)
+	}
+
+	sort.SliceStable(l.report.Findings, func(i, j int) bool {
+		a, b := l.report.Findings[i], l.report.Findings[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return l.report, nil
+}
+
+// linter holds the state of one LintLibrary run
+type linter struct {
+	root         string
+	report       *Report
+	templateDirs []string          // The library's templates, its dependencies' and the built-in ones
+	actions      map[string]string // Action name -> file declaring it
+	dependencies map[string]bool   // Actions provided by dependencies
+	builtin      map[string]bool   // Actions built into darn
+	parsed       map[string]bool   // Templates already parsed
+}
+
+// add records a finding in the file at path
+func (l *linter) add(rule Rule, severity Severity, path string, line int, format string, args ...interface{}) {
+	rel, err := filepath.Rel(l.root, path)
+	if err != nil {
+		rel = path
+	}
+	l.report.Findings = append(l.report.Findings, Finding{
+		Rule:     rule.ID,
+		Severity: severity,
+		File:     filepath.ToSlash(rel),
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// resolveDependencies adds the templates and actions of the libraries the
+// library's manifest depends on
+func (l *linter) resolveDependencies() {
+	manifest, err := library.LoadManifest(l.root)
+	if err != nil {
+		l.add(RuleManifest, SeverityError, filepath.Join(l.root, library.ManifestFile), 0, "%v", err)
+		return
+	}
+	if manifest == nil {
+		return
+	}
+
+	libraries, err := library.ResolveLibraries(l.root, version.Version, config.ExpandPathWithTilde(config.DefaultCacheDir))
+	if err != nil {
+		l.add(RuleManifest, SeverityError, filepath.Join(l.root, library.ManifestFile), 0, "%v", err)
+		return
+	}
+	for _, dep := range libraries[1:] {
+		l.templateDirs = append(l.templateDirs, filepath.Join(dep.Path, "templates"))
+		files, _ := yamlFiles(filepath.Join(dep.Path, "actions"))
+		for _, file := range files {
+			l.dependencies[strings.TrimSuffix(filepath.Base(file), ".yaml")] = true
+		}
+	}
+}
+
+// addEmbeddedDefaults adds the templates and actions built into darn. An
+// installed library only falls back to them with use_builtin, so using them
+// is a warning.
+func (l *linter) addEmbeddedDefaults() {
+	l.templateDirs = append(l.templateDirs, filepath.Join(defaults.EmbeddedLibraryPath, "templates"))
+	files, _ := yamlFiles(filepath.Join(defaults.EmbeddedLibraryPath, "actions"))
+	for _, file := range files {
+		l.builtin[strings.TrimSuffix(filepath.Base(file), ".yaml")] = true
+	}
+}
+
+// isBuiltin reports whether a template path is one built into darn
+func isBuiltin(path string) bool {
+	return strings.HasPrefix(path, defaults.EmbeddedLibraryPath)
+}
+
+// lintAction checks an action file
+func (l *linter) lintAction(path string) {
+	root, ok := l.parseYAML(path)
+	if !ok {
+		return
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if !actionKeys[key.Value] {
+			l.add(RuleUnknownKey, SeverityError, path, key.Line, "unknown key '%s' is ignored", key.Value)
+		}
+	}
+
+	// Unknown keys are reported above, with their lines
+	var config action.Config
+	if err := root.Decode(&config); err != nil {
+		l.add(RuleYAML, SeverityError, path, root.Line, "%v", err)
+		return
+	}
+
+	switch {
+	case config.APIVersion == "" && config.Kind == "":
+		l.add(RuleActionVersion, SeverityWarning, path, 1, "action has no apiVersion; run 'darn action migrate' to convert it to %s", action.APIVersion)
+	case config.APIVersion != action.APIVersion:
+		l.add(RuleActionVersion, SeverityError, path, keyLine(root, "apiVersion"), "unsupported apiVersion '%s' (expected %s)", config.APIVersion, action.APIVersion)
+	case config.Kind != action.KindAction:
+		l.add(RuleActionVersion, SeverityError, path, keyLine(root, "kind"), "unsupported kind '%s' (expected %s)", config.Kind, action.KindAction)
+	}
+
+	// darn finds actions by file name
+	fileName := strings.TrimSuffix(filepath.Base(path), ".yaml")
+	switch {
+	case config.Name == "":
+		l.add(RuleActionName, SeverityError, path, 1, "action has no name")
+	case config.Name != fileName:
+		l.add(RuleActionName, SeverityError, path, keyLine(root, "name"), "action '%s' must be in a file named %s.yaml to be found", config.Name, config.Name)
+	}
+	if config.Name != "" {
+		if other, ok := l.actions[config.Name]; ok {
+			l.add(RuleActionName, SeverityError, path, keyLine(root, "name"), "action '%s' is also declared in %s", config.Name, other)
+		} else {
+			l.actions[config.Name] = filepath.Base(path)
+		}
+	}
+
+	// Creating the action checks the fields its type requires
+	factory := action.NewFactory(action.ActionContext{})
+	factory.RegisterDefaultTypes()
+	if config.Type == "" {
+		l.add(RuleActionFields, SeverityError, path, 1, "action has no type (file or cli)")
+	} else if _, err := factory.Create(config); err != nil {
+		l.add(RuleActionFields, SeverityError, path, keyLine(root, "type"), "%v", err)
+	}
+
+	switch config.Type {
+	case "file":
+		l.checkIgnoredKeys(root, path, config.Type, cliOnlyKeys)
+		l.lintFileAction(root, path, config)
+	case "cli":
+		l.checkIgnoredKeys(root, path, config.Type, fileOnlyKeys)
+		l.lintCLIAction(root, path, config)
+	}
+
+	if config.Schema != nil {
+		l.lintSchema(root, path, config)
+	}
+}
+
+// checkIgnoredKeys reports keys that actions of type typeName do not read
+func (l *linter) checkIgnoredKeys(root *yaml.Node, path, typeName string, keys []string) {
+	for _, key := range keys {
+		if line := keyLine(root, key); line > 0 {
+			if key == "outputs" {
+				l.add(RuleOutputParser, SeverityError, path, line, "%s actions have fixed outputs; 'outputs' is ignored", typeName)
+				continue
+			}
+			l.add(RuleActionFields, SeverityWarning, path, line, "'%s' is ignored by %s actions", key, typeName)
+		}
+	}
+}
+
+// lintFileAction checks a file action's templates and templated paths
+func (l *linter) lintFileAction(root *yaml.Node, path string, config action.Config) {
+	if config.TemplatePath != "" {
+		line := keyLine(root, "template_path")
+		if templatePath, ok := l.findTemplate(config.TemplatePath, false); ok {
+			if isBuiltin(templatePath) {
+				l.add(RuleTemplateMissing, SeverityWarning, path, line, "template '%s' is built into darn, which needs use_builtin once the library is installed", config.TemplatePath)
+			}
+			l.parseTemplate(templatePath, line, path)
+		} else {
+			l.add(RuleTemplateMissing, SeverityError, path, line, "template '%s' not found in %s", config.TemplatePath, strings.Join(l.templateDirs, ", "))
+		}
+	}
+
+	if config.TemplateDir != "" {
+		line := keyLine(root, "template_dir")
+		if templateDir, ok := l.findTemplate(config.TemplateDir, true); ok {
+			if isBuiltin(templateDir) {
+				l.add(RuleTemplateMissing, SeverityWarning, path, line, "template directory '%s' is built into darn, which needs use_builtin once the library is installed", config.TemplateDir)
+			}
+			for file := range config.FileConditions {
+				if _, err := vfs.Stat(filepath.Join(templateDir, filepath.FromSlash(file))); err != nil {
+					l.add(RuleTemplateMissing, SeverityWarning, path, keyLine(root, "file_conditions"), "file_conditions names '%s', which is not in template directory '%s'", file, config.TemplateDir)
+				}
+			}
+		} else {
+			l.add(RuleTemplateMissing, SeverityError, path, line, "template directory '%s' not found in %s", config.TemplateDir, strings.Join(l.templateDirs, ", "))
+		}
+	}
+
+	l.checkTemplateString(root, path, "target_path", config.TargetPath)
+	l.checkTemplateString(root, path, "target_dir", config.TargetDir)
+}
+
+// lintCLIAction checks a cli action's templated command and its output parsers
+func (l *linter) lintCLIAction(root *yaml.Node, path string, config action.Config) {
+	l.checkTemplateString(root, path, "command", config.Command)
+	for _, arg := range config.Args {
+		l.checkTemplateString(root, path, "args", arg)
+	}
+
+	if config.Outputs == nil {
+		return
+	}
+	outputsNode := keyValue(root, "outputs")
+	outputs, ok := config.Outputs.(map[string]interface{})
+	if !ok {
+		l.add(RuleOutputParser, SeverityError, path, keyLine(root, "outputs"), "outputs must map output names to parsers")
+		return
+	}
+
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		line := keyLine(outputsNode, name)
+		parser, ok := outputs[name].(map[string]interface{})
+		if !ok {
+			l.add(RuleOutputParser, SeverityError, path, line, "output '%s' must be a parser with a format", name)
+			continue
+		}
+
+		parserNode := keyValue(outputsNode, name)
+		for key := range parser {
+			if !outputKeys[key] {
+				l.add(RuleUnknownKey, SeverityError, path, keyLine(parserNode, key), "unknown key '%s' in output '%s' is ignored", key, name)
+			}
+		}
+
+		switch format, _ := parser["format"].(string); format {
+		case "json":
+			if _, ok := parser["pattern"]; ok {
+				l.add(RuleOutputParser, SeverityWarning, path, keyLine(parserNode, "pattern"), "output '%s' parses JSON, so its 'pattern' is ignored", name)
+			}
+		case "text":
+			if _, ok := parser["path"]; ok {
+				l.add(RuleOutputParser, SeverityWarning, path, keyLine(parserNode, "path"), "output '%s' parses text, so its 'path' is ignored", name)
+			}
+			if pattern, ok := parser["pattern"].(string); ok {
+				if _, err := regexp.Compile(pattern); err != nil {
+					l.add(RuleOutputParser, SeverityError, path, keyLine(parserNode, "pattern"), "output '%s' has an invalid pattern: %v", name, err)
+				}
+			}
+		case "":
+			l.add(RuleOutputParser, SeverityError, path, line, "output '%s' has no format (json or text)", name)
+		default:
+			l.add(RuleOutputParser, SeverityError, path, keyLine(parserNode, "format"), "output '%s' has unknown format '%s' (expected json or text)", name, format)
+		}
+	}
+}
+
+// lintSchema checks an action's parameter schema and the defaults that go with it
+func (l *linter) lintSchema(root *yaml.Node, path string, config action.Config) {
+	line := keyLine(root, "schema")
+	if err := schema.CheckSchema(config.Schema); err != nil {
+		l.add(RuleSchema, SeverityError, path, line, "%v", err)
+		return
+	}
+
+	properties, ok := config.Schema["properties"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if required, ok := config.Schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := properties[name]; !ok {
+					l.add(RuleSchema, SeverityWarning, path, line, "required parameter '%s' has no property", name)
+				}
+			}
+		}
+	}
+	for name := range config.Defaults {
+		if _, ok := properties[name]; !ok {
+			l.add(RuleSchema, SeverityWarning, path, keyLine(root, "defaults"), "default for '%s' has no property in the schema", name)
+		}
+	}
+}
+
+// checkTemplateString reports a templated action field that does not parse
+func (l *linter) checkTemplateString(root *yaml.Node, path, key, text string) {
+	if text == "" {
+		return
+	}
+	if err := template.Check(text, nil); err != nil {
+		l.add(RuleTemplateParse, SeverityError, path, keyLine(root, key), "%s: %v", key, err)
+	}
+}
+
+// findTemplate finds a template file, or directory, in the template directories
+func (l *linter) findTemplate(name string, dir bool) (string, bool) {
+	for _, templatesDir := range l.templateDirs {
+		candidate := filepath.Join(templatesDir, name)
+		if info, err := vfs.Stat(candidate); err == nil && info.IsDir() == dir {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// lintTemplates parses the library's .tmpl files that no action uses
+func (l *linter) lintTemplates() error {
+	templatesDir := l.templateDirs[0]
+	err := filepath.Walk(templatesDir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == templatesDir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".tmpl" {
+			l.parseTemplate(path, 0, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading templates: %w", err)
+	}
+	return nil
+}
+
+// parseTemplate reports a template file that does not parse, once. Problems
+// in another library's template are reported at the line of the action
+// file that uses it.
+func (l *linter) parseTemplate(templatePath string, line int, actionPath string) {
+	if l.parsed == nil {
+		l.parsed = make(map[string]bool)
+	}
+	if l.parsed[templatePath] {
+		return
+	}
+	l.parsed[templatePath] = true
+
+	content, err := vfs.ReadFile(templatePath)
+	if err == nil {
+		err = template.Check(string(content), l.templateDirs)
+	}
+	if err == nil {
+		return
+	}
+
+	if rel, relErr := filepath.Rel(l.root, templatePath); relErr == nil && !strings.HasPrefix(rel, "..") {
+		l.add(RuleTemplateParse, SeverityError, templatePath, templateErrorLine(err), "%v", err)
+	} else {
+		l.add(RuleTemplateParse, SeverityError, actionPath, line, "template %s: %v", templatePath, err)
+	}
+}
+
+// lintMapping checks a mapping file's keys and the actions and mapping files it refers to
+func (l *linter) lintMapping(path string) {
+	root, ok := l.parseYAML(path)
+	if !ok {
+		return
+	}
+
+	var config plan.MappingConfig
+	if err := root.Decode(&config); err != nil {
+		l.add(RuleYAML, SeverityError, path, root.Line, "%v", err)
+		return
+	}
+
+	// Decoding strictly reports the keys no mapping field reads
+	data, _ := os.ReadFile(path)
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	var strict plan.MappingConfig
+	if err := decoder.Decode(&strict); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			for _, message := range typeErr.Errors {
+				line := 0
+				if match := yamlLineRegex.FindStringSubmatch(message); match != nil {
+					line, _ = strconv.Atoi(match[1])
+					message = match[2]
+				}
+				l.add(RuleUnknownKey, SeverityError, path, line, "%s", message)
+			}
+		}
+	}
+
+	mappingsDir := filepath.Join(l.root, "mappings")
+	for _, importPath := range config.Imports {
+		if !mappingExists(importPath, filepath.Dir(path), mappingsDir) {
+			l.add(RuleMappingReference, SeverityError, path, valueLine(root, importPath), "imported mapping '%s' not found", importPath)
+		}
+	}
+
+	var checkRules func(rules []plan.MappingRule)
+	checkRules = func(rules []plan.MappingRule) {
+		for _, rule := range rules {
+			// Namespaced and templated action names are resolved at plan time
+			if rule.Action != "" && !strings.Contains(rule.Action, "/") && !strings.Contains(rule.Action, "{{") {
+				_, known := l.actions[rule.Action]
+				switch {
+				case known || l.dependencies[rule.Action]:
+				case l.builtin[rule.Action]:
+					l.add(RuleMappingReference, SeverityWarning, path, mappingLine(root, "action", rule.Action), "rule '%s' uses built-in action '%s', which needs use_builtin once the library is installed", rule.ID, rule.Action)
+				default:
+					l.add(RuleMappingReference, SeverityError, path, mappingLine(root, "action", rule.Action), "rule '%s' uses unknown action '%s'", rule.ID, rule.Action)
+				}
+			}
+			if rule.MappingRef != "" && !mappingExists(rule.MappingRef, mappingsDir, "") {
+				l.add(RuleMappingReference, SeverityError, path, mappingLine(root, "mapping_ref", rule.MappingRef), "rule '%s' refers to mapping '%s', which was not found", rule.ID, rule.MappingRef)
+			}
+			checkRules(rule.Steps)
+		}
+	}
+	checkRules(config.Mappings)
+}
+
+// parseYAML reads a YAML file that must hold a mapping and returns its root
+func (l *linter) parseYAML(path string) (*yaml.Node, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		l.add(RuleYAML, SeverityError, path, 0, "%v", err)
+		return nil, false
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line := 0
+		message := err.Error()
+		if match := yamlLineRegex.FindStringSubmatch(strings.TrimPrefix(message, "yaml: ")); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = match[2]
+		}
+		l.add(RuleYAML, SeverityError, path, line, "%s", message)
+		return nil, false
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		l.add(RuleYAML, SeverityError, path, 1, "file must hold a YAML mapping")
+		return nil, false
+	}
+	return doc.Content[0], true
+}
+
+// mappingExists reports whether a mapping file can be found relative to
+// baseDir or mappingsDir, or in the embedded defaults
+func mappingExists(name, baseDir, mappingsDir string) bool {
+	if filepath.IsAbs(name) {
+		_, err := os.Stat(name)
+		return err == nil
+	}
+	for _, dir := range []string{baseDir, mappingsDir, filepath.Join(defaults.EmbeddedLibraryPath, "mappings")} {
+		if dir == "" {
+			continue
+		}
+		if _, err := vfs.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlFiles returns the .yaml files in dir, sorted; a missing dir has none
+func yamlFiles(dir string) ([]string, error) {
+	entries, err := vfs.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// yamlKeys returns the yaml keys of a struct type's fields
+func yamlKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// keyValue returns the value of key in a mapping node
+func keyValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// keyLine returns the line of key in a mapping node, or 0 if it is not there
+func keyLine(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return 0
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i].Line
+		}
+	}
+	return 0
+}
+
+// mappingLine returns the line of the first key with the given scalar value anywhere under node
+func mappingLine(node *yaml.Node, key, value string) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key && node.Content[i+1].Value == value {
+				return node.Content[i].Line
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if line := mappingLine(child, key, value); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// valueLine returns the line of the first scalar with the given value anywhere under node
+func valueLine(node *yaml.Node, value string) int {
+	if node == nil {
+		return 0
+	}
+	if node.Kind == yaml.ScalarNode && node.Value == value {
+		return node.Line
+	}
+	for _, child := range node.Content {
+		if line := valueLine(child, value); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// templateLineRegex extracts the line from a text/template parse error, such as "template: template:3: ..."
+var templateLineRegex = regexp.MustCompile(`template: [^:]*:(\d+):`)
+
+// templateErrorLine returns the line a template parse error points at, or 0
+func templateErrorLine(err error) int {
+	match := templateLineRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(match[1])
+	return line
+}
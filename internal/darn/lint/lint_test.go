@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/darn/lint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLibrary(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+	return dir
+}
+
+// findingsByRule groups a report's findings by rule
+func findingsByRule(report *lint.Report) map[string][]lint.Finding {
+	byRule := make(map[string][]lint.Finding)
+	for _, finding := range report.Findings {
+		byRule[finding.Rule] = append(byRule[finding.Rule], finding)
+	}
+	return byRule
+}
+
+func TestLintLibrary_BundledDefaults(t *testing.T) {
+	report, err := lint.LintLibrary("../../defaults")
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings)
+}
+
+func TestLintLibrary_Actions(t *testing.T) {
+	lib := writeLibrary(t, map[string]string{
+		"templates/ok.tmpl":     "Hello {{.name}}\n",
+		"templates/broken.tmpl": "line one\n{{if .x}}\n",
+		"actions/ok.yaml": `apiVersion: darn/v1
+kind: Action
+name: ok
+type: file
+template_path: ok.tmpl
+target_path: "{{.dir}}/OK.md"
+schema:
+  type: object
+  required: [name, dir]
+  properties:
+    name: {type: string}
+    dir: {type: string}
+`,
+		"actions/misnamed.yaml": `name: other
+type: file
+template_path: missing.tmpl
+target_path: OUT
+parameters:
+  - name: x
+outputs:
+  file_path:
+    value: OUT
+`,
+		"actions/run.yaml": `name: run
+type: cli
+command: echo
+target_path: ignored
+outputs:
+  hash:
+    format: text
+    pattern: "([0-9a-f"
+  data:
+    format: xml
+schema:
+  type: object
+  required: [missing]
+  properties:
+    present: {type: string}
+`,
+		"actions/incomplete.yaml": "name: incomplete\ntype: file\n",
+	})
+
+	report, err := lint.LintLibrary(lib)
+	require.NoError(t, err)
+	byRule := findingsByRule(report)
+
+	require.Len(t, byRule["unknown-key"], 1)
+	assert.Equal(t, lint.Finding{Rule: "unknown-key", Severity: lint.SeverityError, File: "actions/misnamed.yaml", Line: 5, Message: "unknown key 'parameters' is ignored"}, byRule["unknown-key"][0])
+
+	// Only the versioned action is current
+	require.Len(t, byRule["action-version"], 3)
+	assert.Equal(t, lint.SeverityWarning, byRule["action-version"][0].Severity)
+
+	require.Len(t, byRule["action-name"], 1)
+	assert.Equal(t, "actions/misnamed.yaml", byRule["action-name"][0].File)
+
+	require.Len(t, byRule["template-missing"], 1)
+	assert.Equal(t, 3, byRule["template-missing"][0].Line)
+
+	require.Len(t, byRule["template-parse"], 1)
+	assert.Equal(t, "templates/broken.tmpl", byRule["template-parse"][0].File)
+
+	require.Len(t, byRule["action-fields"], 2)
+	assert.Contains(t, byRule["action-fields"][0].Message, "template_path is required")
+	assert.Equal(t, lint.SeverityWarning, byRule["action-fields"][1].Severity)
+	assert.Contains(t, byRule["action-fields"][1].Message, "'target_path' is ignored by cli actions")
+
+	// File actions ignore outputs; cli output parsers must be valid
+	require.Len(t, byRule["output-parser"], 3)
+	assert.Contains(t, byRule["output-parser"][0].Message, "file actions have fixed outputs")
+	assert.Contains(t, byRule["output-parser"][1].Message, "invalid pattern")
+	assert.Contains(t, byRule["output-parser"][2].Message, "unknown format 'xml'")
+
+	require.Len(t, byRule["schema-invalid"], 1)
+	assert.Equal(t, lint.SeverityWarning, byRule["schema-invalid"][0].Severity)
+	assert.Contains(t, byRule["schema-invalid"][0].Message, "'missing'")
+
+	assert.True(t, report.HasErrors())
+}
+
+func TestLintLibrary_Mappings(t *testing.T) {
+	lib := writeLibrary(t, map[string]string{
+		"actions/known.yaml": "name: known\ntype: cli\ncommand: true\n",
+		"mappings/rules.yaml": `imports: [missing-import.yaml]
+mappings:
+  - id: good
+    action: known
+  - id: group
+    steps:
+      - id: bad
+        action: unknown
+        parmeters: {}
+  - id: ref
+    mapping_ref: nowhere.yaml
+  - id: qualified
+    action: corp/elsewhere
+  - id: embedded
+    action: add-security-md
+  - id: embedded-ref
+    mapping_ref: security-md-workflow.yaml
+`,
+		"mappings/broken.yaml": "mappings: [\n",
+	})
+
+	report, err := lint.LintLibrary(lib)
+	require.NoError(t, err)
+	byRule := findingsByRule(report)
+
+	require.Len(t, byRule["yaml-parse"], 1)
+	assert.Equal(t, "mappings/broken.yaml", byRule["yaml-parse"][0].File)
+
+	require.Len(t, byRule["unknown-key"], 1)
+	assert.Equal(t, 9, byRule["unknown-key"][0].Line)
+
+	refs := byRule["mapping-reference"]
+	require.Len(t, refs, 4)
+	assert.Equal(t, 1, refs[0].Line)
+	assert.Contains(t, refs[0].Message, "missing-import.yaml")
+	assert.Equal(t, 8, refs[1].Line)
+	assert.Contains(t, refs[1].Message, "unknown action 'unknown'")
+	assert.Equal(t, 11, refs[2].Line)
+	assert.Equal(t, 15, refs[3].Line)
+	assert.Equal(t, lint.SeverityWarning, refs[3].Severity)
+	assert.Contains(t, refs[3].Message, "built-in action 'add-security-md'")
+}
+
+func TestWriteReport(t *testing.T) {
+	report := &lint.Report{
+		Library: "/lib",
+		Findings: []lint.Finding{
+			{Rule: "unknown-key", Severity: lint.SeverityError, File: "actions/a.yaml", Line: 4, Message: "unknown key 'x' is ignored"},
+			{Rule: "schema-invalid", Severity: lint.SeverityWarning, File: "actions/b.yaml", Message: "required parameter 'y' has no property"},
+		},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, lint.WriteReport(&text, report, lint.FormatText))
+	assert.Contains(t, text.String(), "actions/a.yaml:4: error: unknown key 'x' is ignored [unknown-key]\n")
+	assert.Contains(t, text.String(), "actions/b.yaml: warning:")
+	assert.Contains(t, text.String(), "1 errors, 1 warnings in /lib")
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, lint.WriteReport(&jsonOut, report, lint.FormatJSON))
+	var decoded lint.Report
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, *report, decoded)
+
+	var sarif bytes.Buffer
+	require.NoError(t, lint.WriteReport(&sarif, report, lint.FormatSARIF))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, len(lint.AllRules))
+	require.Len(t, log.Runs[0].Results, 2)
+	assert.Equal(t, "warning", log.Runs[0].Results[1].Level)
+	assert.Equal(t, "actions/a.yaml", log.Runs[0].Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 4, log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Nil(t, log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region)
+
+	assert.Error(t, lint.WriteReport(&bytes.Buffer{}, report, "xml"))
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kusari-oss/darn/internal/version"
+)
+
+// Report formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// WriteReport writes a report in the given format (text, json or sarif)
+func WriteReport(w io.Writer, report *Report, format string) error {
+	switch format {
+	case FormatText, "":
+		return writeReportText(w, report)
+	case FormatJSON:
+		return writeJSON(w, report)
+	case FormatSARIF:
+		return writeJSON(w, sarifLog(report))
+	default:
+		return fmt.Errorf("unsupported lint format: %s (expected text, json or sarif)", format)
+	}
+}
+
+// writeReportText writes one line per finding and a summary
+func writeReportText(w io.Writer, report *Report) error {
+	for _, finding := range report.Findings {
+		location := finding.File
+		if finding.Line > 0 {
+			location = fmt.Sprintf("%s:%d", finding.File, finding.Line)
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s: %s [%s]\n", location, finding.Severity, finding.Message, finding.Rule); err != nil {
+			return err
+		}
+	}
+
+	if len(report.Findings) == 0 {
+		_, err := fmt.Fprintf(w, "No problems found in %s\n", report.Library)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d errors, %d warnings in %s\n", report.Count(SeverityError), report.Count(SeverityWarning), report.Library)
+	return err
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling lint report: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// SARIF 2.1.0, the subset code scanning tools read
+
+type sarifReport struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifLog converts a report to SARIF, with file URIs relative to the library
+func sarifLog(report *Report) *sarifReport {
+	rules := make([]sarifRule, 0, len(AllRules))
+	for _, rule := range AllRules {
+		rules = append(rules, sarifRule{ID: rule.ID, ShortDescription: sarifMessage{Text: rule.Description}})
+	}
+
+	results := make([]sarifResult, 0, len(report.Findings))
+	for _, finding := range report.Findings {
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: finding.File},
+		}}
+		if finding.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: finding.Line}
+		}
+		results = append(results, sarifResult{
+			RuleID:    finding.Rule,
+			Level:     string(finding.Severity),
+			Message:   sarifMessage{Text: finding.Message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	return &sarifReport{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "darn",
+				Version:        version.Version,
+				InformationURI: "https://github.com/kusari-oss/darn",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
@@ -15,66 +15,368 @@ import (
 	"path/filepath"
 	"strings"
 
-	"gopkg.in/yaml.v3"
-
 	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/config"
 	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/kusari-oss/darn/internal/core/vfs"
+	"github.com/kusari-oss/darn/internal/defaults"
+)
+
+// Namespaces for action paths that are not configured library sources
+const (
+	LocalNamespace   = "local"   // The project's own actions
+	DefaultNamespace = "default" // The configured library, unless its manifest names it
+	BuiltinNamespace = "builtin" // The defaults embedded in darn, searched last
 )
 
+// builtinActionsPath holds the actions of the defaults embedded in darn
+var builtinActionsPath = filepath.Join(defaults.EmbeddedLibraryPath, "actions")
+
 // Resolver handles finding and loading actions based on configuration
 type Resolver struct {
 	// Paths to search for actions, in order of precedence
 	actionPaths []string
 
+	// Namespace of each action path, for qualified names such as corp/add-security-md
+	namespaces map[string]string
+
+	// Signed directory each action path belongs to: the library root for
+	// library actions, or the actions directory itself for project actions
+	signedRoots map[string]string
+
 	// Factory for creating actions
 	factory *action.Factory
+
+	// When set, the defaults embedded in darn are searched last
+	builtin bool
+
+	// When set, actions are only loaded from directories with a valid signature
+	verifier *signing.Verifier
+	verified map[string]error // Verification result by signed root
+
+	// When set, library actions are only loaded if the libraries match the lock
+	libraryPath string
+	lock        *library.Lock
+	lockedPaths map[string]bool // Action paths that belong to locked libraries
+	lockChecked bool
+	lockErr     error
 }
 
 // NewResolver creates a new action resolver compatible with the new factory
 func NewResolver(factory *action.Factory, projectDir string, useLocal, useGlobal bool, globalFirst bool, localActionsDir, libraryPath string) *Resolver {
 	var actionPaths []string
 
+	globalPath := filepath.Join(libraryPath, "actions")
+	localPath := filepath.Join(projectDir, localActionsDir)
+
 	// Add paths based on configuration and precedence
 	if useLocal && useGlobal {
 		if globalFirst {
 			// Global first, then local
-			actionPaths = append(actionPaths, filepath.Join(libraryPath, "actions"))
-			actionPaths = append(actionPaths, filepath.Join(projectDir, localActionsDir))
+			actionPaths = append(actionPaths, globalPath)
+			actionPaths = append(actionPaths, localPath)
 		} else {
 			// Local first, then global
-			actionPaths = append(actionPaths, filepath.Join(projectDir, localActionsDir))
-			actionPaths = append(actionPaths, filepath.Join(libraryPath, "actions"))
+			actionPaths = append(actionPaths, localPath)
+			actionPaths = append(actionPaths, globalPath)
 		}
 	} else if useLocal {
 		// Only local
-		actionPaths = append(actionPaths, filepath.Join(projectDir, localActionsDir))
+		actionPaths = append(actionPaths, localPath)
 	} else if useGlobal {
 		// Only global
-		actionPaths = append(actionPaths, filepath.Join(libraryPath, "actions"))
+		actionPaths = append(actionPaths, globalPath)
 	}
 
 	return &Resolver{
 		actionPaths: actionPaths,
+		namespaces: map[string]string{
+			localPath:  LocalNamespace,
+			globalPath: libraryNamespace(libraryPath, DefaultNamespace),
+		},
+		signedRoots: map[string]string{localPath: localPath, globalPath: libraryPath},
 		factory:     factory,
+		libraryPath: libraryPath,
+	}
+}
+
+// WithVerifier makes the resolver check signatures before loading actions.
+// Library actions need a valid library signature (see signing.SignLibrary);
+// project actions need their actions directory to be signed the same way.
+func (r *Resolver) WithVerifier(verifier *signing.Verifier) *Resolver {
+	r.verifier = verifier
+	r.verified = make(map[string]error)
+	return r
+}
+
+// WithBuiltinDefaults searches the defaults embedded in darn after every
+// other action path, and falls back to their templates for every action
+// (see config.Config.BuiltinDefaults). They are not signed and not locked,
+// so a resolver that checks signatures or a lock refuses to load them.
+func (r *Resolver) WithBuiltinDefaults() *Resolver {
+	r.builtin = true
+	r.actionPaths = append(r.actionPaths, builtinActionsPath)
+	r.namespaces[builtinActionsPath] = BuiltinNamespace
+	r.signedRoots[builtinActionsPath] = defaults.EmbeddedLibraryPath
+	return r
+}
+
+// libraryNamespace returns the name in the manifest of the library at root, or fallback
+func libraryNamespace(root, fallback string) string {
+	manifest, err := library.LoadManifest(root)
+	if err != nil || manifest == nil {
+		return fallback
+	}
+	return manifest.Name
+}
+
+// WithSources replaces the configured library with an ordered list of
+// library sources, searched in order. The first source must be the
+// configured library (see config.LoadConfig).
+func (r *Resolver) WithSources(sources []config.LibrarySource) *Resolver {
+	if len(sources) == 0 {
+		return r
+	}
+
+	var paths []string
+	for i, source := range sources {
+		path := filepath.Join(source.Path, "actions")
+		namespace := source.Namespace
+		if namespace == "" {
+			// The first source is the configured library
+			fallback := filepath.Base(source.Path)
+			if i == 0 {
+				fallback = DefaultNamespace
+			}
+			namespace = libraryNamespace(source.Path, fallback)
+		}
+		paths = append(paths, path)
+		r.namespaces[path] = namespace
+		r.signedRoots[path] = source.Path
+	}
+
+	r.replaceActionPath(filepath.Join(r.libraryPath, "actions"), paths)
+	return r
+}
+
+// replaceActionPath replaces path with paths in the search order, if path is searched
+func (r *Resolver) replaceActionPath(path string, paths []string) bool {
+	for i, actionPath := range r.actionPaths {
+		if actionPath == path {
+			replaced := append([]string{}, r.actionPaths[:i]...)
+			replaced = append(replaced, paths...)
+			r.actionPaths = append(replaced, r.actionPaths[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// WithLock makes the resolver check the project's library lock, as loaded
+// for the resolver's library, before loading library actions. The actions and templates of the locked
+// dependencies are searched after the library's own.
+func (r *Resolver) WithLock(lock *library.Lock) *Resolver {
+	r.lock = lock
+	globalPath := filepath.Join(r.libraryPath, "actions")
+	r.lockedPaths = map[string]bool{globalPath: true}
+
+	var dependencyPaths, templateDirs []string
+	for _, dep := range lock.Dependencies() {
+		path := filepath.Join(dep.Path, "actions")
+		dependencyPaths = append(dependencyPaths, path)
+		templateDirs = append(templateDirs, filepath.Join(dep.Path, "templates"))
+		r.signedRoots[path] = dep.Path
+		r.lockedPaths[path] = true
+		if _, ok := r.namespaces[path]; !ok {
+			r.namespaces[path] = dep.Name
+		}
+	}
+
+	// Dependencies come right after the library that needs them
+	if r.replaceActionPath(globalPath, append([]string{globalPath}, dependencyPaths...)) {
+		context := r.factory.Context()
+		context.ExtraTemplatesDirs = append(context.ExtraTemplatesDirs, templateDirs...)
+		r.factory.UpdateContext(context)
+	}
+	return r
+}
+
+// verifyActionPath checks the lock and the signature covering an action
+// path, once per signed root. The embedded defaults have neither, so they
+// are refused when either is checked.
+func (r *Resolver) verifyActionPath(path string) error {
+	if path == builtinActionsPath {
+		switch {
+		case r.verifier != nil:
+			return fmt.Errorf("refusing to load built-in actions: they are not signed by a trusted key")
+		case r.lock != nil:
+			return fmt.Errorf("refusing to load built-in actions: they are not part of the locked libraries")
+		}
+		return nil
+	}
+
+	if r.lock != nil && r.lockedPaths[path] {
+		if !r.lockChecked {
+			r.lockChecked = true
+			if err := r.lock.Verify(); err != nil {
+				r.lockErr = fmt.Errorf("refusing to load actions: %w", err)
+			}
+		}
+		if r.lockErr != nil {
+			return r.lockErr
+		}
+	}
+
+	if r.verifier == nil {
+		return nil
+	}
+
+	root := r.signedRoots[path]
+	if root == "" {
+		root = path
+	}
+	if err, done := r.verified[root]; done {
+		return err
+	}
+
+	err := r.verifier.VerifyLibrary(root)
+	if err != nil {
+		err = fmt.Errorf("refusing to load actions: %w", err)
+	}
+	r.verified[root] = err
+	return err
+}
+
+// searchPaths returns the action paths to search for name, and the name
+// without its namespace. A name qualified with a namespace, such as
+// corp/add-security-md, is only searched for in that namespace.
+func (r *Resolver) searchPaths(name string) ([]string, string, error) {
+	namespace, shortName, qualified := strings.Cut(name, "/")
+	if !qualified {
+		namespace, shortName = "", name
+	}
+	if err := validateActionName(shortName); err != nil {
+		return nil, "", fmt.Errorf("invalid action name '%s': %w", name, err)
+	}
+	if !qualified {
+		return r.actionPaths, shortName, nil
+	}
+
+	var paths []string
+	for _, path := range r.actionPaths {
+		if r.namespaces[path] == namespace {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, "", fmt.Errorf("unknown library namespace '%s' in action '%s'", namespace, name)
+	}
+	return paths, shortName, nil
+}
+
+// validateActionName checks that name, without its namespace, is a single
+// file name, so it cannot load a file outside an action path or a hidden
+// file that library signatures and lock hashes do not cover
+func validateActionName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("name is empty")
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("name must not contain path separators")
+	case strings.HasPrefix(name, "."):
+		return fmt.Errorf("name must not start with '.'")
+	}
+	return nil
+}
+
+// actionFile returns the path of the action file for name in an action
+// path, which must stay inside the directory that path is verified as
+func (r *Resolver) actionFile(path, name string) (string, error) {
+	actionPath := filepath.Join(path, name+".yaml")
+
+	root := r.signedRoots[path]
+	if root == "" {
+		root = path
+	}
+	relPath, err := filepath.Rel(root, actionPath)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return "", fmt.Errorf("action file %s is outside %s", actionPath, root)
+	}
+	return actionPath, nil
+}
+
+// createAction creates an action loaded from path. Actions from a library
+// other than the configured one look for templates in their own library
+// first. When signatures are checked, library actions only use templates
+// from their own library, which its signature covers, so unsigned project
+// templates cannot shadow them. With the built-in defaults, and no
+// signatures or lock to check, every action falls back to the built-in
+// templates.
+func (r *Resolver) createAction(actionConfig action.Config, path string) (action.Action, error) {
+	context := r.factory.Context()
+	root := r.signedRoots[path]
+
+	if r.verifier != nil && root != "" && root != path {
+		context.UseLocal = false
+		context.UseGlobal = true
+		context.GlobalTemplatesDir = filepath.Join(root, "templates")
+		context.ExtraTemplatesDirs = nil
+		return r.factory.WithContext(context).Create(actionConfig)
+	}
+
+	if r.builtinTemplates() {
+		context.ExtraTemplatesDirs = append(append([]string{}, context.ExtraTemplatesDirs...),
+			filepath.Join(defaults.EmbeddedLibraryPath, "templates"))
+	}
+
+	if root != "" && root != path && root != r.libraryPath {
+		context.ExtraTemplatesDirs = append([]string{context.GlobalTemplatesDir}, context.ExtraTemplatesDirs...)
+		context.GlobalTemplatesDir = filepath.Join(root, "templates")
 	}
+	return r.factory.WithContext(context).Create(actionConfig)
+}
+
+// builtinTemplates reports whether actions fall back to the built-in templates
+func (r *Resolver) builtinTemplates() bool {
+	return r.builtin && r.verifier == nil && r.lock == nil
+}
+
+// UsesBuiltinDefaults reports whether the defaults embedded in darn may be
+// loaded: they are enabled and no signature or lock check would refuse them
+func (r *Resolver) UsesBuiltinDefaults() bool {
+	return r.builtinTemplates()
 }
 
-// ResolveAction finds and loads an action by name, using the new factory
+// ResolveAction finds and loads an action by name, using the new factory.
+// The name may be qualified with a library namespace.
 func (r *Resolver) ResolveAction(name string) (action.Action, error) {
 	var lastErr error
 
+	paths, shortName, err := r.searchPaths(name)
+	if err != nil {
+		return nil, err
+	}
+
 	// Search for the action in each path
-	for _, path := range r.actionPaths {
-		actionPath := filepath.Join(path, name+".yaml")
+	for _, path := range paths {
+		actionPath, err := r.actionFile(path, shortName)
+		if err != nil {
+			return nil, err
+		}
 
 		// Check if file exists
-		_, err := os.Stat(actionPath)
-		if err != nil {
+		if _, err := vfs.Stat(actionPath); err != nil {
 			lastErr = err
 			continue
 		}
 
-		// File found, load the action
+		// File found; its directory must match the lock and be signed when those checks are on
+		if err := r.verifyActionPath(path); err != nil {
+			return nil, err
+		}
+
+		// Load the action
 		actionConfig, err := LoadActionConfig(actionPath)
 		if err != nil {
 			lastErr = err
@@ -82,7 +384,7 @@ func (r *Resolver) ResolveAction(name string) (action.Action, error) {
 		}
 
 		// Create the action using the factory
-		action, err := r.factory.Create(*actionConfig)
+		action, err := r.createAction(*actionConfig, path)
 		if err != nil {
 			lastErr = err
 			continue
@@ -98,24 +400,57 @@ func (r *Resolver) ResolveAction(name string) (action.Action, error) {
 	return nil, fmt.Errorf("action '%s' not found in any configured location", name)
 }
 
+// ActionSource is an action definition found in one of the resolver's paths
+type ActionSource struct {
+	Config    action.Config
+	Namespace string
+	Path      string // Path of the action file
+}
+
+// QualifiedName returns the action's name qualified with its namespace
+func (s ActionSource) QualifiedName() string {
+	return s.Namespace + "/" + s.Config.Name
+}
+
 // ListAvailableActions lists all available actions from all configured locations
 func (r *Resolver) ListAvailableActions() (map[string]action.Config, error) {
-	actions := make(map[string]action.Config)
+	sources, err := r.ListActionSources()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make(map[string]action.Config, len(sources))
+	for name, definitions := range sources {
+		actions[name] = definitions[0].Config
+	}
+	return actions, nil
+}
+
+// ListActionSources lists every definition of every action by name, in
+// order of precedence: the first definition is the one an unqualified name
+// resolves to, and it shadows the others.
+func (r *Resolver) ListActionSources() (map[string][]ActionSource, error) {
+	sources := make(map[string][]ActionSource)
 
 	for _, path := range r.actionPaths {
 		// Skip if path doesn't exist
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := vfs.Stat(path); os.IsNotExist(err) {
 			continue
 		}
 
+		if err := r.verifyActionPath(path); err != nil {
+			return nil, err
+		}
+
 		// List all YAML files in the directory
-		entries, err := os.ReadDir(path)
+		entries, err := vfs.ReadDir(path)
 		if err != nil {
 			continue // Skip directories we can't read
 		}
 
 		for _, entry := range entries {
-			if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			// Hidden files cannot be resolved by name (see validateActionName)
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" || strings.HasPrefix(entry.Name(), ".") {
 				continue
 			}
 
@@ -131,67 +466,29 @@ func (r *Resolver) ListAvailableActions() (map[string]action.Config, error) {
 				actionConfig.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
 			}
 
-			// Add to map if not already present (respects precedence order)
-			if _, exists := actions[actionConfig.Name]; !exists {
-				actions[actionConfig.Name] = *actionConfig
-			}
+			// Paths are searched in order of precedence
+			sources[actionConfig.Name] = append(sources[actionConfig.Name], ActionSource{
+				Config:    *actionConfig,
+				Namespace: r.namespaces[path],
+				Path:      actionPath,
+			})
 		}
 	}
 
-	return actions, nil
+	return sources, nil
 }
 
+// LoadActionConfig loads the action definition at path (see action.ParseConfig)
 func LoadActionConfig(path string) (*action.Config, error) {
 	// Read the action file
-	data, err := os.ReadFile(path)
+	data, err := vfs.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error reading action file: %w", err)
 	}
 
-	// First convert YAML to a generic map
-	var rawMap map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawMap); err != nil {
-		return nil, fmt.Errorf("error parsing action file: %w", err)
-	}
-
-	// Use the helper recursive conversion function
-	sanitizedInterface := sanitizeMapKeys(rawMap)
-
-	// Perform type assertion
-	sanitizedMap, ok := sanitizedInterface.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("error: sanitized map is not of expected type")
-	}
-
-	// Create the config manually from the sanitized map
-	actionConfig := &action.Config{
-		Name:         getStringValue(sanitizedMap, "name"),
-		Type:         getStringValue(sanitizedMap, "type"),
-		Description:  getStringValue(sanitizedMap, "description"),
-		TemplatePath: getStringValue(sanitizedMap, "template_path"),
-		TargetPath:   getStringValue(sanitizedMap, "target_path"),
-		CreateDirs:   getBoolValue(sanitizedMap, "create_dirs"),
-		Command:      getStringValue(sanitizedMap, "command"),
-		Args:         getStringSlice(sanitizedMap, "args"),
-		Schema:       getMap(sanitizedMap, "schema"),
-		Defaults:     getMap(sanitizedMap, "defaults"),
-		Outputs:      getValue(sanitizedMap, "outputs"),
-	}
-
-	// Handle labels specifically
-	if labelsMap, ok := sanitizedMap["labels"].(map[string]interface{}); ok {
-		actionConfig.Labels = make(map[string][]string)
-		for key, value := range labelsMap {
-			if valueSlice, ok := value.([]interface{}); ok {
-				strSlice := make([]string, 0, len(valueSlice))
-				for _, v := range valueSlice {
-					if strVal, ok := v.(string); ok {
-						strSlice = append(strSlice, strVal)
-					}
-				}
-				actionConfig.Labels[key] = strSlice
-			}
-		}
+	actionConfig, err := action.ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error loading action %s: %w", path, err)
 	}
 
 	// If no name is specified, use the filename (without extension)
@@ -199,96 +496,7 @@ func LoadActionConfig(path string) (*action.Config, error) {
 		actionConfig.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 	}
 
-	// TODO: Handle debug logging
-	/*
-		fmt.Printf("DEBUG: Loaded action '%s', type: %s, template_path: %s\n",
-			actionConfig.Name, actionConfig.Type, actionConfig.TemplatePath)*/
-
-	return actionConfig, nil
-}
-
-// NOTE: I wonder if something like reflection or using some sort of tagging with types would be better here.
-// Helper functions to extract values from the map
-
-func getStringValue(m map[string]interface{}, key string) string {
-	if v, ok := m[key]; ok {
-		if s, ok := v.(string); ok {
-			return s
-		}
-	}
-	return ""
-}
-
-func getBoolValue(m map[string]interface{}, key string) bool {
-	if v, ok := m[key]; ok {
-		if b, ok := v.(bool); ok {
-			return b
-		}
-	}
-	return false
-}
-
-func getStringSlice(m map[string]interface{}, key string) []string {
-	if v, ok := m[key]; ok {
-		if slice, ok := v.([]interface{}); ok {
-			result := make([]string, 0, len(slice))
-			for _, item := range slice {
-				if s, ok := item.(string); ok {
-					result = append(result, s)
-				}
-			}
-			return result
-		}
-	}
-	return nil
-}
-
-func getMap(m map[string]interface{}, key string) map[string]interface{} {
-	if v, ok := m[key]; ok {
-		if mapVal, ok := v.(map[string]interface{}); ok {
-			return mapVal
-		}
-	}
-	return nil
-}
-
-func getValue(m map[string]interface{}, key string) interface{} {
-	if v, ok := m[key]; ok {
-		return v
-	}
-	return nil
-}
-
-// NOTE: I do not know if I need this, but couldn't figure out a better way to do this.
-// Helper function to convert map[interface{}]interface{} to map[string]interface{}
-func sanitizeMapKeys(i interface{}) interface{} {
-	switch v := i.(type) {
-	case map[interface{}]interface{}:
-		// Convert map[interface{}]interface{} to map[string]interface{}
-		result := make(map[string]interface{})
-		for k, val := range v {
-			// Convert key to string
-			strKey := fmt.Sprintf("%v", k)
-			// Recursively sanitize the value
-			result[strKey] = sanitizeMapKeys(val)
-		}
-		return result
-	case map[string]interface{}:
-		// Recursively sanitize map values
-		for k, val := range v {
-			v[k] = sanitizeMapKeys(val)
-		}
-		return v
-	case []interface{}:
-		// Recursively sanitize slice elements
-		for i, val := range v {
-			v[i] = sanitizeMapKeys(val)
-		}
-		return v
-	default:
-		// Return as is for other types
-		return v
-	}
+	return &actionConfig, nil
 }
 
 // ResolveTemplatePath resolves a template path based on configuration
@@ -314,10 +522,13 @@ func (r *Resolver) ResolveTemplatePath(templatePath string, projectDir string, u
 		// Only global
 		templatePaths = append(templatePaths, filepath.Join(libraryPath, "templates", templatePath))
 	}
+	if r.builtinTemplates() {
+		templatePaths = append(templatePaths, filepath.Join(defaults.EmbeddedLibraryPath, "templates", templatePath))
+	}
 
 	// Check each path
 	for _, path := range templatePaths {
-		if _, err := os.Stat(path); err == nil {
+		if _, err := vfs.Stat(path); err == nil {
 			return path, nil
 		}
 	}
@@ -330,18 +541,30 @@ func (r *Resolver) ResolveTemplatePath(templatePath string, projectDir string, u
 func (r *Resolver) GetActionConfig(name string) (*action.Config, error) {
 	var lastErr error
 
+	paths, shortName, err := r.searchPaths(name)
+	if err != nil {
+		return nil, err
+	}
+
 	// Search for the action in each path
-	for _, path := range r.actionPaths {
-		actionPath := filepath.Join(path, name+".yaml")
+	for _, path := range paths {
+		actionPath, err := r.actionFile(path, shortName)
+		if err != nil {
+			return nil, err
+		}
 
 		// Check if file exists
-		_, err := os.Stat(actionPath)
-		if err != nil {
+		if _, err := vfs.Stat(actionPath); err != nil {
 			lastErr = err
 			continue
 		}
 
-		// File found, load the action config
+		// File found; its directory must match the lock and be signed when those checks are on
+		if err := r.verifyActionPath(path); err != nil {
+			return nil, err
+		}
+
+		// Load the action config
 		actionConfig, err := LoadActionConfig(actionPath)
 		if err != nil {
 			lastErr = err
@@ -409,15 +632,15 @@ func hasAnyMatchingValue(actionValues, selectorValues []string) bool {
 // ValidateLibraryPaths validates that all configured library paths exist and are accessible
 func (r *Resolver) ValidateLibraryPaths() []error {
 	var errors []error
-	
+
 	for _, path := range r.actionPaths {
-		if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := vfs.Stat(path); os.IsNotExist(err) {
 			errors = append(errors, fmt.Errorf("action path does not exist: %s", path))
 		} else if err != nil {
 			errors = append(errors, fmt.Errorf("cannot access action path %s: %w", path, err))
 		}
 	}
-	
+
 	return errors
 }
 
@@ -426,11 +649,11 @@ func (r *Resolver) ValidateActionCommand(actionConfig *action.Config) error {
 	if actionConfig.Type != "cli" {
 		return nil // Only validate CLI commands
 	}
-	
+
 	if actionConfig.Command == "" {
 		return fmt.Errorf("action '%s' has empty command", actionConfig.Name)
 	}
-	
+
 	// Use library manager for validation
 	manager := library.NewManager("", "", false)
 	return manager.ValidateShellCommand(actionConfig.Command)
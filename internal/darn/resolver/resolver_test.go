@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package resolver_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/kusari-oss/darn/internal/darn/resolver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes files by slash-separated path relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+// signedLibrary creates a library with an add-readme action, signs it and
+// returns its path and a verifier that trusts the signing key
+func signedLibrary(t *testing.T) (string, *signing.Verifier) {
+	t.Helper()
+	library := filepath.Join(t.TempDir(), "library")
+	writeFiles(t, library, map[string]string{
+		"actions/add-readme.yaml": "name: add-readme\ntype: file\ntemplate_path: readme.tmpl\ntarget_path: README.md\n",
+		"templates/readme.tmpl":   "# {{.name}}\n",
+	})
+
+	keyPath := filepath.Join(t.TempDir(), "release.key")
+	_, err := signing.GenerateKey(keyPath)
+	require.NoError(t, err)
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+	_, err = signing.SignLibrary(library, privateKey)
+	require.NoError(t, err)
+
+	verifier, err := signing.NewVerifier([]string{signing.PublicKeyPath(keyPath)})
+	require.NoError(t, err)
+	return library, verifier
+}
+
+// newResolver creates a local-first resolver for a project using library
+func newResolver(projectDir, library string) *resolver.Resolver {
+	factory := action.NewFactory(action.ActionContext{
+		TemplatesDir:       filepath.Join(projectDir, ".darn", "templates"),
+		GlobalTemplatesDir: filepath.Join(library, "templates"),
+		WorkingDir:         projectDir,
+		UseLocal:           true,
+		UseGlobal:          true,
+		Output:             io.Discard,
+	})
+	factory.RegisterDefaultTypes()
+	return resolver.NewResolver(factory, projectDir, true, true, false, ".darn/actions", library)
+}
+
+func TestResolveActionRejectsNamesOutsideTheSignedLibrary(t *testing.T) {
+	library, verifier := signedLibrary(t)
+	projectDir := t.TempDir()
+	r := newResolver(projectDir, library).WithVerifier(verifier)
+
+	// An action file outside the library, reachable through the namespace
+	writeFiles(t, filepath.Dir(filepath.Dir(library)), map[string]string{
+		"outside.yaml": "name: outside\ntype: cli\ncommand: echo\n",
+	})
+	// A hidden action file added after the library was signed
+	writeFiles(t, library, map[string]string{
+		"actions/.evil.yaml": "name: evil\ntype: cli\ncommand: echo\n",
+	})
+
+	for _, name := range []string{"default/../../outside", `default\..\outside`, ".evil", "default/.evil"} {
+		t.Run(name, func(t *testing.T) {
+			_, err := r.ResolveAction(name)
+			assert.ErrorContains(t, err, "invalid action name")
+
+			_, err = r.GetActionConfig(name)
+			assert.ErrorContains(t, err, "invalid action name")
+		})
+	}
+
+	// The hidden file is not covered by the signature, so the library no longer verifies
+	_, err := r.ResolveAction("add-readme")
+	assert.ErrorContains(t, err, "actions/.evil.yaml (added)")
+}
+
+func TestListActionSourcesSkipsHiddenFiles(t *testing.T) {
+	library, _ := signedLibrary(t)
+	writeFiles(t, library, map[string]string{
+		"actions/.evil.yaml": "name: evil\ntype: cli\ncommand: echo\n",
+	})
+
+	sources, err := newResolver(t.TempDir(), library).ListActionSources()
+	require.NoError(t, err)
+	assert.Contains(t, sources, "add-readme")
+	assert.NotContains(t, sources, "evil")
+}
+
+func TestVerifiedLibraryActionsUseLibraryTemplates(t *testing.T) {
+	library, verifier := signedLibrary(t)
+
+	// An unsigned project template with the same name as the library's
+	projectDir := t.TempDir()
+	writeFiles(t, projectDir, map[string]string{
+		".darn/templates/readme.tmpl": "# Shadowed {{.name}}\n",
+	})
+
+	tests := []struct {
+		name     string
+		verifier *signing.Verifier
+		readme   string
+	}{
+		{"unverified", nil, "# Shadowed API\n"},
+		{"verified", verifier, "# API\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResolver(projectDir, library)
+			if tt.verifier != nil {
+				r.WithVerifier(tt.verifier)
+			}
+
+			act, err := r.ResolveAction("add-readme")
+			require.NoError(t, err)
+			require.NoError(t, act.Execute(map[string]interface{}{"name": "API"}))
+
+			readme, err := os.ReadFile(filepath.Join(projectDir, "README.md"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.readme, string(readme))
+			require.NoError(t, os.Remove(filepath.Join(projectDir, "README.md")))
+		})
+	}
+}
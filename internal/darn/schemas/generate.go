@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemas
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MetaSchema is the JSON schema draft the exported schemas follow. Draft 7
+// is the newest draft that YAML language servers and gojsonschema both read.
+const MetaSchema = "http://json-schema.org/draft-07/schema#"
+
+// field documents a struct field in the exported schemas
+type field struct {
+	description string
+	enum        []interface{}          // Allowed values
+	schema      map[string]interface{} // Replaces the schema generated from the Go type
+}
+
+// generator builds a JSON schema from Go types, naming properties after
+// their yaml tags. Structs other than the root become definitions so that
+// recursive types such as mapping steps can refer to themselves.
+type generator struct {
+	definitions map[string]interface{}
+}
+
+// generate returns the schema of a struct type, with the definitions it uses
+func generate(t reflect.Type) (map[string]interface{}, error) {
+	g := &generator{definitions: make(map[string]interface{})}
+	root, err := g.structSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = MetaSchema
+	if len(g.definitions) > 0 {
+		root["definitions"] = g.definitions
+	}
+	return root, nil
+}
+
+// typeSchema returns the schema of a field's type
+func (g *generator) typeSchema(t reflect.Type) (map[string]interface{}, error) {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		// Any value
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]interface{}{"type": "object"}, nil
+		}
+		values, err := g.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.definitions[name]; !ok {
+			// Reserve the name first, as the struct may refer to itself
+			g.definitions[name] = nil
+			definition, err := g.structSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			g.definitions[name] = definition
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// structSchema returns the object schema of a struct type. Every field must
+// be documented in fields, so the schemas cannot fall behind the types.
+func (g *generator) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	typeName := t.String()
+	properties := make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name := strings.Split(structField.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !structField.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(structField.Name)
+		}
+
+		key := typeName + "." + name
+		doc, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("no description for field %s", key)
+		}
+
+		property := doc.schema
+		if property == nil {
+			var err error
+			if property, err = g.typeSchema(structField.Type); err != nil {
+				return nil, fmt.Errorf("error generating schema for field %s: %w", key, err)
+			}
+		}
+		property = copySchema(property)
+		if _, isRef := property["$ref"]; isRef {
+			// Draft 7 ignores keywords next to $ref, so describe the reference in allOf
+			property = map[string]interface{}{"allOf": []interface{}{property}}
+		}
+		property["description"] = doc.description
+		if doc.enum != nil {
+			property["enum"] = doc.enum
+		}
+		properties[name] = property
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if description, ok := types[typeName]; ok {
+		schema["description"] = description
+	}
+	if required := requiredFields[typeName]; len(required) > 0 {
+		sorted := append([]string(nil), required...)
+		sort.Strings(sorted)
+		schema["required"] = sorted
+	}
+	return schema, nil
+}
+
+// copySchema returns a shallow copy of a schema, so that documenting a
+// property does not change the shared schema it came from
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		copied[key] = value
+	}
+	return copied
+}
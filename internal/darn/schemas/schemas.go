@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package schemas generates JSON schemas for the files darn reads, so that
+// editors with a YAML language server can validate and complete them.
+package schemas
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+)
+
+// Schema describes one of the file formats darn reads
+type Schema struct {
+	Name      string   // Name used on the command line
+	Title     string   // Title of the generated schema
+	FileMatch []string // Globs of the files the format is used for
+	Type      reflect.Type
+	extra     map[string]interface{} // Keywords added to the root of the schema
+}
+
+// FileName is the name export writes the schema to
+func (s Schema) FileName() string {
+	return s.Name + ".schema.json"
+}
+
+// Generate returns the JSON schema for the format
+func (s Schema) Generate() (map[string]interface{}, error) {
+	schema, err := generate(s.Type)
+	if err != nil {
+		return nil, fmt.Errorf("error generating %s schema: %w", s.Name, err)
+	}
+	schema["title"] = s.Title
+	for key, value := range s.extra {
+		schema[key] = value
+	}
+	return schema, nil
+}
+
+// All lists the exported schemas
+var All = []Schema{
+	{
+		Name:      "action",
+		Title:     "darn action",
+		FileMatch: []string{"**/actions/*.yaml"},
+		Type:      reflect.TypeOf(action.Config{}),
+		extra: map[string]interface{}{
+			// The fields each type needs, as action.Config.Validate checks them
+			"allOf": []interface{}{
+				map[string]interface{}{
+					"if":   map[string]interface{}{"properties": map[string]interface{}{"type": map[string]interface{}{"const": "cli"}}},
+					"then": map[string]interface{}{"required": []interface{}{"command"}},
+				},
+				map[string]interface{}{
+					"if": map[string]interface{}{"properties": map[string]interface{}{"type": map[string]interface{}{"const": "file"}}},
+					"then": map[string]interface{}{
+						"anyOf": []interface{}{
+							map[string]interface{}{"required": []interface{}{"template_path", "target_path"}},
+							map[string]interface{}{"required": []interface{}{"template_dir", "target_dir"}},
+						},
+						"not": map[string]interface{}{"required": []interface{}{"outputs"}},
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:      "mapping",
+		Title:     "darnit mapping",
+		FileMatch: []string{"**/mappings/*.yaml"},
+		Type:      reflect.TypeOf(plan.MappingConfig{}),
+	},
+	{
+		Name:      "config",
+		Title:     "darn configuration",
+		FileMatch: []string{"**/.darn/config.yaml"},
+		Type:      reflect.TypeOf(config.Config{}),
+	},
+	{
+		Name:      "plan",
+		Title:     "darnit remediation plan",
+		FileMatch: []string{"**/*plan*.yaml", "**/*plan*.json"},
+		Type:      reflect.TypeOf(models.RemediationPlan{}),
+	},
+}
+
+// Lookup returns the schema with the given name
+func Lookup(name string) (Schema, error) {
+	names := make([]string, 0, len(All))
+	for _, schema := range All {
+		if schema.Name == name {
+			return schema, nil
+		}
+		names = append(names, schema.Name)
+	}
+	return Schema{}, fmt.Errorf("unknown schema '%s' (expected %s)", name, strings.Join(names, ", "))
+}
+
+// outputParser is the schema of a cli action's output parser
+var outputParser = map[string]interface{}{
+	"type":                 "object",
+	"description":          "How to read an output from the command's standard output",
+	"additionalProperties": false,
+	"required":             []interface{}{"format"},
+	"properties": map[string]interface{}{
+		"format": map[string]interface{}{
+			"description": "Parse the output as JSON, or read it as text",
+			"enum":        []interface{}{"json", "text"},
+		},
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "Dot-separated path to the value in JSON output, such as data.id",
+		},
+		"pattern": map[string]interface{}{
+			"type":        "string",
+			"description": "Regular expression applied to text output; the value is the first capture group, or the whole match",
+		},
+	},
+}
+
+// types describes the structs in the schemas, by Go type
+var types = map[string]string{
+	"action.Config":          "An action that writes files from templates or runs a command",
+	"plan.MappingConfig":     "Rules that map findings to the actions of a remediation plan",
+	"plan.MappingRule":       "A rule that adds an action, a group of steps or another mapping file's rules to the plan",
+	"plan.MappingOverride":   "A patch applied to an imported rule",
+	"config.Config":          "darn configuration, in .darn/config.yaml or ~/.darn/config.yaml",
+	"config.LibrarySource":   "A library in the ordered list of library sources",
+	"models.RemediationPlan": "A remediation plan generated by 'darnit plan generate'",
+	"models.RemediationStep": "A step of a remediation plan",
+	"models.StepConflict":    "A step left out of the plan by conflict resolution",
+	"models.PlanApproval":    "An approver's sign-off on the plan's steps",
+}
+
+// requiredFields lists the fields each struct must set, by Go type
+var requiredFields = map[string][]string{
+	"action.Config":          {"name", "type"},
+	"plan.MappingRule":       {"id"},
+	"plan.MappingOverride":   {"id"},
+	"config.LibrarySource":   {"path"},
+	"models.RemediationPlan": {"steps"},
+	"models.RemediationStep": {"id", "action_name"},
+	"models.PlanApproval":    {"approver", "approved_at", "steps_hash", "key_id", "signature"},
+}
+
+// fields documents every field in the schemas, by Go type and yaml name
+var fields = map[string]field{
+	// Actions
+	"action.Config.apiVersion":      {description: "Version of the action format; run 'darn action migrate' on definitions without one", enum: []interface{}{action.APIVersion}},
+	"action.Config.kind":            {description: "Kind of definition", enum: []interface{}{action.KindAction}},
+	"action.Config.name":            {description: "Name of the action; must match the file name without .yaml"},
+	"action.Config.type":            {description: "Whether the action writes files from templates or runs a command", enum: []interface{}{"file", "cli"}},
+	"action.Config.description":     {description: "What the action does"},
+	"action.Config.labels":          {description: "Labels such as platform or framework, each with a list of values"},
+	"action.Config.template_path":   {description: "File actions: template to render, relative to the library's templates directory"},
+	"action.Config.target_path":     {description: "File actions: file to write; may use template syntax"},
+	"action.Config.create_dirs":     {description: "File actions: create missing parent directories of the target"},
+	"action.Config.on_exists":       {description: "File actions: what to do when the target exists with different content (default overwrite)", enum: []interface{}{action.OnExistsOverwrite, action.OnExistsSkip, action.OnExistsBackup, action.OnExistsFail, action.OnExistsMergeMarkers, action.OnExistsDiffOnly}},
+	"action.Config.template_dir":    {description: "File actions: directory of templates to render, relative to the library's templates directory"},
+	"action.Config.target_dir":      {description: "File actions: directory template_dir is rendered into; may use template syntax"},
+	"action.Config.file_conditions": {description: "File actions: template paths in template_dir mapped to a condition that must render true for the file to be written"},
+	"action.Config.command":         {description: "CLI actions: command to run"},
+	"action.Config.args":            {description: "CLI actions: command arguments; may use template syntax"},
+	"action.Config.schema":          {description: "JSON schema the action's parameters are validated against"},
+	"action.Config.defaults":        {description: "Default parameter values"},
+	"action.Config.outputs": {
+		description: "CLI actions: outputs read from the command's output, by name",
+		schema:      map[string]interface{}{"type": "object", "additionalProperties": outputParser},
+	},
+
+	// Mappings
+	"plan.MappingConfig.imports":   {description: "Mapping files whose rules are included before this file's rules"},
+	"plan.MappingConfig.overrides": {description: "Patches applied to imported rules"},
+	"plan.MappingConfig.mappings":  {description: "Rules, in order"},
+
+	"plan.MappingRule.id":              {description: "Unique ID of the rule, used as the step ID"},
+	"plan.MappingRule.condition":       {description: "CEL expression over the findings and parameters; the rule applies when it is true"},
+	"plan.MappingRule.mapping_ref":     {description: "Mapping file whose rules are applied in place of this rule"},
+	"plan.MappingRule.action":          {description: "Action the step runs, optionally qualified by a library namespace as in corp/add-security-md"},
+	"plan.MappingRule.reason":          {description: "Why the step is in the plan"},
+	"plan.MappingRule.labels":          {description: "Labels such as platform or framework, each with a list of values"},
+	"plan.MappingRule.parameters":      {description: "Parameters passed to the action; values may use template syntax"},
+	"plan.MappingRule.depends_on":      {description: "IDs of steps that must run first"},
+	"plan.MappingRule.depends_on_expr": {description: "CEL expression returning the IDs of steps that must run first"},
+	"plan.MappingRule.once":            {description: "Add the action to the plan at most once"},
+	"plan.MappingRule.steps":           {description: "Rules applied as a group when this rule's condition is true"},
+	"plan.MappingRule.for_each":        {description: "CEL list expression; the rule expands once per element"},
+	"plan.MappingRule.item_var":        {description: "Variable holding the current element of for_each (default item)"},
+	"plan.MappingRule.for_each_key":    {description: "CEL expression for a unique step ID suffix (default: the element's index)"},
+	"plan.MappingRule.once_key":        {description: "CEL expression to deduplicate on instead of the action; implies once"},
+	"plan.MappingRule.priority":        {description: "Higher priority wins conflicts (default 0)"},
+	"plan.MappingRule.exclusive_group": {description: "At most one step per group is kept"},
+	"plan.MappingRule.conflicts_with":  {description: "Step IDs that cannot be in the plan together with this one"},
+
+	"plan.MappingOverride.id":         {description: "ID of the imported rule to patch"},
+	"plan.MappingOverride.condition":  {description: "Replaces the rule's condition; an empty string removes it"},
+	"plan.MappingOverride.parameters": {description: "Merged into the rule's parameters; a null value removes the parameter"},
+	"plan.MappingOverride.disabled":   {description: "Removes the rule"},
+
+	// Configuration
+	"config.Config.templates_dir":   {description: "Templates directory, relative to the library"},
+	"config.Config.actions_dir":     {description: "Actions directory, relative to the library"},
+	"config.Config.configs_dir":     {description: "Configs directory, relative to the library"},
+	"config.Config.mappings_dir":    {description: "Mappings directory, relative to the library"},
+	"config.Config.library_path":    {description: "Path to the library; ~ is expanded"},
+	"config.Config.use_global":      {description: "Use the global library"},
+	"config.Config.use_local":       {description: "Use the project's local library"},
+	"config.Config.global_first":    {description: "Search the global library before the local one"},
+	"config.Config.use_builtin":     {description: "Search the defaults built into darn after the configured libraries; they are also searched when the global library does not exist"},
+	"config.Config.library_sources": {description: "Ordered library sources; the first takes the place of library_path and earlier sources shadow later ones"},
+	"config.Config.trusted_keys":    {description: "Paths to trusted ed25519 public keys; when set, plans and libraries must be signed by one of them"},
+	"config.Config.signing_key":     {description: "Default private key for signing commands"},
+
+	"config.LibrarySource.path":      {description: "Path to the library; ~ is expanded"},
+	"config.LibrarySource.namespace": {description: "Prefix that selects this source's actions, as in corp/add-security-md (default: the name in the library's manifest)"},
+
+	// Plans
+	"models.RemediationPlan.project_name": {description: "Name of the project the plan is for"},
+	"models.RemediationPlan.repository":   {description: "Path to the repository the plan is for"},
+	"models.RemediationPlan.steps":        {description: "Steps, in the order they run"},
+	"models.RemediationPlan.conflicts":    {description: "Steps dropped by conflict resolution"},
+	"models.RemediationPlan.approvals":    {description: "Sign-offs recorded by 'darnit plan approve'"},
+
+	"models.RemediationStep.id":          {description: "Unique ID of the step"},
+	"models.RemediationStep.action_name": {description: "Action the step runs"},
+	"models.RemediationStep.params":      {description: "Parameters passed to the action"},
+	"models.RemediationStep.reason":      {description: "Why the step is in the plan"},
+	"models.RemediationStep.depends_on":  {description: "IDs of steps that must run first"},
+	"models.RemediationStep.outputs":     {description: "Outputs captured from the step when it ran"},
+	"models.RemediationStep.status":      {description: "Execution status", enum: []interface{}{"pending", "running", "success", "failure"}},
+	"models.RemediationStep.error":       {description: "Error message when the step failed"},
+	"models.RemediationStep.output_refs": {description: "Parameters set from an earlier step's output, as step_id.output_name"},
+
+	"models.StepConflict.step_id":   {description: "ID of the dropped step"},
+	"models.StepConflict.action":    {description: "Action of the dropped step"},
+	"models.StepConflict.priority":  {description: "Priority of the dropped step's rule"},
+	"models.StepConflict.winner_id": {description: "ID of the step that was kept instead"},
+	"models.StepConflict.reason":    {description: "Why the steps conflicted"},
+
+	"models.PlanApproval.approver":    {description: "Who approved the plan"},
+	"models.PlanApproval.approved_at": {description: "When the plan was approved (RFC 3339)", schema: map[string]interface{}{"type": "string", "format": "date-time"}},
+	"models.PlanApproval.steps_hash":  {description: "SHA-256 of the approved steps"},
+	"models.PlanApproval.comment":     {description: "Approver's comment"},
+	"models.PlanApproval.key_id":      {description: "ID of the key that signed the approval"},
+	"models.PlanApproval.signature":   {description: "Base64 ed25519 signature over the approver, time, steps hash and comment"},
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schemas_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/core/schema"
+	"github.com/kusari-oss/darn/internal/darn/schemas"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// validateFile validates a YAML or JSON file against a generated schema
+func validateFile(t *testing.T, generated map[string]interface{}, path string) error {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var document map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(data, &document))
+	return schema.ValidateParams(generated, document)
+}
+
+func generate(t *testing.T, name string) map[string]interface{} {
+	t.Helper()
+	s, err := schemas.Lookup(name)
+	require.NoError(t, err)
+	generated, err := s.Generate()
+	require.NoError(t, err)
+	return generated
+}
+
+func TestGenerate_AllValid(t *testing.T) {
+	for _, s := range schemas.All {
+		generated, err := s.Generate()
+		require.NoError(t, err, s.Name)
+		assert.NoError(t, schema.CheckSchema(generated), s.Name)
+		assert.Equal(t, schemas.MetaSchema, generated["$schema"])
+	}
+}
+
+func TestGenerate_BundledDefaults(t *testing.T) {
+	actionSchema := generate(t, "action")
+	actions, err := filepath.Glob("../../defaults/actions/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, actions)
+	for _, path := range actions {
+		assert.NoError(t, validateFile(t, actionSchema, path), path)
+	}
+
+	mappingSchema := generate(t, "mapping")
+	mappings, err := filepath.Glob("../../defaults/mappings/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, mappings)
+	for _, path := range mappings {
+		assert.NoError(t, validateFile(t, mappingSchema, path), path)
+	}
+
+	assert.NoError(t, validateFile(t, generate(t, "config"), "../../defaults/configs/config.yaml"))
+}
+
+func TestGenerate_Action(t *testing.T) {
+	actionSchema := generate(t, "action")
+
+	properties := actionSchema["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"file", "cli"}, properties["type"].(map[string]interface{})["enum"])
+	assert.NotEmpty(t, properties["name"].(map[string]interface{})["description"])
+
+	tests := []struct {
+		name   string
+		action map[string]interface{}
+		valid  bool
+	}{
+		{
+			name:   "cli action",
+			action: map[string]interface{}{"name": "run", "type": "cli", "command": "echo", "outputs": map[string]interface{}{"out": map[string]interface{}{"format": "text"}}},
+			valid:  true,
+		},
+		{
+			name:   "unknown type",
+			action: map[string]interface{}{"name": "run", "type": "shell", "command": "echo"},
+		},
+		{
+			name:   "unknown field",
+			action: map[string]interface{}{"name": "run", "type": "cli", "command": "echo", "parameters": []interface{}{}},
+		},
+		{
+			name:   "cli action without command",
+			action: map[string]interface{}{"name": "run", "type": "cli"},
+		},
+		{
+			name:   "unknown output format",
+			action: map[string]interface{}{"name": "run", "type": "cli", "command": "echo", "outputs": map[string]interface{}{"out": map[string]interface{}{"format": "xml"}}},
+		},
+		{
+			name:   "file action with template directory",
+			action: map[string]interface{}{"name": "dir", "type": "file", "template_dir": "service", "target_dir": "out", "on_exists": "skip"},
+			valid:  true,
+		},
+		{
+			name:   "file action without target",
+			action: map[string]interface{}{"name": "f", "type": "file", "template_path": "a.tmpl"},
+		},
+		{
+			name:   "file action with outputs",
+			action: map[string]interface{}{"name": "f", "type": "file", "template_path": "a.tmpl", "target_path": "A", "outputs": map[string]interface{}{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateParams(actionSchema, tt.action)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerate_Plan(t *testing.T) {
+	plan := models.RemediationPlan{
+		ProjectName: "demo",
+		Steps: []models.RemediationStep{
+			{ID: "step-1", ActionName: "add-security-md", Params: map[string]interface{}{"name": "demo"}, Status: "success"},
+		},
+		Approvals: []models.PlanApproval{{Approver: "alice", ApprovedAt: "2024-01-02T03:04:05Z", StepsHash: "abc"}},
+	}
+	data, err := json.Marshal(plan)
+	require.NoError(t, err)
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &document))
+
+	planSchema := generate(t, "plan")
+	assert.NoError(t, schema.ValidateParams(planSchema, document))
+
+	document["steps"].([]interface{})[0].(map[string]interface{})["status"] = "done"
+	assert.Error(t, schema.ValidateParams(planSchema, document))
+}
+
+func TestLookup(t *testing.T) {
+	s, err := schemas.Lookup("mapping")
+	require.NoError(t, err)
+	assert.Equal(t, "mapping.schema.json", s.FileName())
+
+	_, err = schemas.Lookup("findings")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected action, mapping, config, plan")
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/core/signing"
+)
+
+// approvedStep holds the parts of a step an approval covers. Execution state
+// (status, error, outputs) changes when a plan runs and is left out.
+type approvedStep struct {
+	ID         string                 `json:"id"`
+	ActionName string                 `json:"action_name"`
+	Params     map[string]interface{} `json:"params"`
+	Reason     string                 `json:"reason"`
+	DependsOn  []string               `json:"depends_on"`
+	OutputRefs map[string]string      `json:"output_refs"`
+}
+
+// StepsHash returns the SHA-256 of a plan's steps, as recorded in approvals.
+// The hash covers each step's ID, action, parameters, reason, dependencies
+// and output references, in plan order.
+func StepsHash(plan *models.RemediationPlan) (string, error) {
+	steps := make([]approvedStep, len(plan.Steps))
+	for i, step := range plan.Steps {
+		steps[i] = approvedStep{
+			ID:         step.ID,
+			ActionName: step.ActionName,
+			Params:     step.Params,
+			Reason:     step.Reason,
+			DependsOn:  step.DependsOn,
+			OutputRefs: step.OutputRefs,
+		}
+	}
+
+	// Map keys are marshaled in sorted order, so the encoding is stable
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return "", fmt.Errorf("error encoding plan steps: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// approvalPayload returns the data an approval's signature covers
+func approvalPayload(approval models.PlanApproval) ([]byte, error) {
+	approval.KeyID = ""
+	approval.Signature = ""
+	data, err := json.Marshal(approval)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding approval: %w", err)
+	}
+	return data, nil
+}
+
+// ApprovePlan records approver's approval of the plan's current steps,
+// signed with privateKey. An earlier approval by the same approver is
+// replaced; approving steps that the approver has already approved is an
+// error.
+func ApprovePlan(plan *models.RemediationPlan, approver, comment string, now time.Time, privateKey ed25519.PrivateKey) (*models.PlanApproval, error) {
+	if approver == "" {
+		return nil, fmt.Errorf("approver is required")
+	}
+	if err := ValidatePlan(plan); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+
+	hash, err := StepsHash(plan)
+	if err != nil {
+		return nil, err
+	}
+
+	approval := models.PlanApproval{
+		Approver:   approver,
+		ApprovedAt: now.UTC().Format(time.RFC3339),
+		StepsHash:  hash,
+		Comment:    comment,
+	}
+
+	payload, err := approvalPayload(approval)
+	if err != nil {
+		return nil, err
+	}
+	signature := signing.Sign(payload, privateKey)
+	approval.KeyID = signature.KeyID
+	approval.Signature = signature.Signature
+
+	approvals := make([]models.PlanApproval, 0, len(plan.Approvals)+1)
+	for _, existing := range plan.Approvals {
+		if existing.Approver != approver {
+			approvals = append(approvals, existing)
+			continue
+		}
+		if existing.StepsHash == hash {
+			return nil, fmt.Errorf("plan already approved by %s at %s", approver, existing.ApprovedAt)
+		}
+	}
+	plan.Approvals = append(approvals, approval)
+
+	return &approval, nil
+}
+
+// CheckApprovals returns an error unless approvals signed by at least
+// required distinct trusted keys match the plan's current steps. Approvals of
+// earlier versions of the steps, and approvals that do not verify against
+// verifier, do not count.
+func CheckApprovals(plan *models.RemediationPlan, required int, verifier *signing.Verifier) error {
+	if required <= 0 {
+		return nil
+	}
+	if verifier == nil {
+		return fmt.Errorf("approvals cannot be verified: no trusted_keys are configured")
+	}
+
+	hash, err := StepsHash(plan)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]bool)
+	var stale, unverified []string
+	for _, approval := range plan.Approvals {
+		if approval.StepsHash != hash {
+			stale = append(stale, approval.Approver)
+			continue
+		}
+
+		payload, err := approvalPayload(approval)
+		if err != nil {
+			return err
+		}
+		signature := &signing.Signature{KeyID: approval.KeyID, Signature: approval.Signature}
+		if err := verifier.Verify(payload, signature); err != nil {
+			unverified = append(unverified, fmt.Sprintf("%s (%v)", approval.Approver, err))
+			continue
+		}
+		keys[approval.KeyID] = true
+	}
+
+	if len(keys) >= required {
+		return nil
+	}
+
+	msg := fmt.Sprintf("plan has %d of %d required approvals", len(keys), required)
+	if len(stale) > 0 {
+		msg += fmt.Sprintf("; steps changed after approval by %s", strings.Join(stale, ", "))
+	}
+	if len(unverified) > 0 {
+		msg += fmt.Sprintf("; unverified approval by %s", strings.Join(unverified, ", "))
+	}
+	return fmt.Errorf("%s", msg)
+}
@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit_test
+
+import (
+	"crypto/ed25519"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func approvalTestPlan() *models.RemediationPlan {
+	return &models.RemediationPlan{
+		ProjectName: "api",
+		Steps: []models.RemediationStep{
+			{ID: "readme", ActionName: "add-readme", Params: map[string]interface{}{"name": "API"}, Reason: "Add docs"},
+		},
+	}
+}
+
+// approvalKey generates a key pair and returns the private key and the path
+// of the public key
+func approvalKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	keyPath := filepath.Join(t.TempDir(), "approver.key")
+	_, err := signing.GenerateKey(keyPath)
+	require.NoError(t, err)
+
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+	return privateKey, signing.PublicKeyPath(keyPath)
+}
+
+func TestApprovePlan(t *testing.T) {
+	plan := approvalTestPlan()
+	approvedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	aliceKey, alicePub := approvalKey(t)
+	bobKey, bobPub := approvalKey(t)
+	verifier, err := signing.NewVerifier([]string{alicePub, bobPub})
+	require.NoError(t, err)
+
+	require.ErrorContains(t, darnit.CheckApprovals(plan, 1, verifier), "plan has 0 of 1 required approvals")
+	assert.NoError(t, darnit.CheckApprovals(plan, 0, verifier))
+
+	approval, err := darnit.ApprovePlan(plan, "alice@example.com", "Looks good", approvedAt, aliceKey)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-01T12:00:00Z", approval.ApprovedAt)
+	hash, err := darnit.StepsHash(plan)
+	require.NoError(t, err)
+	assert.Equal(t, hash, approval.StepsHash)
+	assert.NotEmpty(t, approval.KeyID)
+	assert.NotEmpty(t, approval.Signature)
+	assert.NoError(t, darnit.CheckApprovals(plan, 1, verifier))
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 2, verifier), "plan has 1 of 2 required approvals")
+
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", approvedAt, aliceKey)
+	assert.ErrorContains(t, err, "already approved by alice@example.com")
+
+	// Execution state does not affect the hash
+	plan.Steps[0].Status = "success"
+	plan.Steps[0].Outputs = map[string]interface{}{"file_path": "README.md"}
+	assert.NoError(t, darnit.CheckApprovals(plan, 1, verifier))
+
+	// Changing a step invalidates the approval until it is renewed
+	plan.Steps[0].Params["name"] = "Other"
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 1, verifier), "steps changed after approval by alice@example.com")
+
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", approvedAt.Add(time.Hour), aliceKey)
+	require.NoError(t, err)
+	_, err = darnit.ApprovePlan(plan, "bob@example.com", "", approvedAt.Add(time.Hour), bobKey)
+	require.NoError(t, err)
+	assert.Len(t, plan.Approvals, 2)
+	assert.NoError(t, darnit.CheckApprovals(plan, 2, verifier))
+}
+
+func TestCheckApprovalsRequiresTrustedSignatures(t *testing.T) {
+	plan := approvalTestPlan()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	aliceKey, alicePub := approvalKey(t)
+	malloryKey, _ := approvalKey(t)
+	verifier, err := signing.NewVerifier([]string{alicePub})
+	require.NoError(t, err)
+
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", now, aliceKey)
+	require.NoError(t, err)
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 1, nil), "no trusted_keys are configured")
+
+	// One key counts once, whatever approver names it signs
+	_, err = darnit.ApprovePlan(plan, "bob@example.com", "", now, aliceKey)
+	require.NoError(t, err)
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 2, verifier), "plan has 1 of 2 required approvals")
+
+	// Approvals signed by untrusted keys do not count
+	plan.Approvals = nil
+	_, err = darnit.ApprovePlan(plan, "mallory@example.com", "", now, malloryKey)
+	require.NoError(t, err)
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 1, verifier), "unverified approval by mallory@example.com (signed with untrusted key")
+
+	// Editing a signed approval breaks its signature
+	plan.Approvals = nil
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", now, aliceKey)
+	require.NoError(t, err)
+	plan.Approvals[0].Approver = "mallory@example.com"
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 1, verifier), "signature does not match the content")
+
+	// Approvals without a signature do not count
+	plan.Approvals = []models.PlanApproval{{Approver: "alice@example.com", ApprovedAt: "2025-03-01T12:00:00Z", StepsHash: plan.Approvals[0].StepsHash}}
+	assert.ErrorContains(t, darnit.CheckApprovals(plan, 1, verifier), "unverified approval by alice@example.com")
+}
+
+func TestApprovalSurvivesSaveAndLoad(t *testing.T) {
+	plan := approvalTestPlan()
+	privateKey, publicKeyPath := approvalKey(t)
+	verifier, err := signing.NewVerifier([]string{publicKeyPath})
+	require.NoError(t, err)
+
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", time.Now(), privateKey)
+	require.NoError(t, err)
+
+	for _, name := range []string{"plan.json", "plan.yaml"} {
+		planFile := filepath.Join(t.TempDir(), name)
+		require.NoError(t, darnit.SavePlanToFile(plan, planFile))
+
+		loaded, err := darnit.LoadPlanFile(planFile)
+		require.NoError(t, err)
+		assert.NoError(t, darnit.CheckApprovals(loaded, 1, verifier), name)
+	}
+}
+
+func TestExecutePlanRequiresApproval(t *testing.T) {
+	setupExecutionLibrary(t)
+	repo := t.TempDir()
+	plan := approvalTestPlan()
+
+	options := models.ExecutionOptions{WorkingDir: repo, RequiredApprovals: 1, Output: io.Discard}
+	err := darnit.ExecutePlan(plan, options)
+	require.ErrorContains(t, err, "plan is not approved")
+	assert.NoFileExists(t, filepath.Join(repo, "README.md"))
+
+	// Dry runs are allowed so reviewers can inspect the plan
+	options.DryRun = true
+	require.NoError(t, darnit.ExecutePlan(plan, options))
+
+	// Approvals only count when they verify against the trusted keys
+	options.DryRun = false
+	untrustedKey, _ := approvalKey(t)
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", time.Now(), untrustedKey)
+	require.NoError(t, err)
+	require.ErrorContains(t, darnit.ExecutePlan(plan, options), "no trusted_keys are configured")
+
+	privateKey := trustNewKey(t)
+	require.ErrorContains(t, darnit.ExecutePlan(plan, options), "unverified approval by alice@example.com")
+
+	_, err = signing.SignLibrary(filepath.Join(os.Getenv("DARN_HOME"), ".darn", "library"), privateKey)
+	require.NoError(t, err)
+	plan.Approvals = nil
+	_, err = darnit.ApprovePlan(plan, "alice@example.com", "", time.Now(), privateKey)
+	require.NoError(t, err)
+	require.NoError(t, darnit.ExecutePlan(plan, options))
+
+	readme, err := os.ReadFile(filepath.Join(repo, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# API\n", string(readme))
+}
@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+)
+
+// BatchExecutionOptions contains options for executing the plans in a batch index
+type BatchExecutionOptions struct {
+	// Base options for every plan. WorkingDir and Output are set per repository.
+	Execution models.ExecutionOptions
+
+	// Maximum number of plans executed at the same time (below one means all at once)
+	Workers int
+
+	// Directory for per-repository logs (defaults to "logs" next to the index)
+	LogDir string
+}
+
+// RepoExecutionResult holds the outcome of executing one repository's plan
+type RepoExecutionResult struct {
+	Name     string
+	RepoPath string
+	PlanFile string
+	LogFile  string
+	Steps    []models.RemediationStep // Steps with their final status
+	Err      error
+}
+
+// BatchExecutionResult holds the outcome of executing a batch index
+type BatchExecutionResult struct {
+	Repos []RepoExecutionResult
+}
+
+// Failed returns the number of repositories whose plan did not complete
+func (r *BatchExecutionResult) Failed() int {
+	failed := 0
+	for _, repo := range r.Repos {
+		if repo.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// LoadBatchIndex loads an index written by batch plan generation
+func LoadBatchIndex(filePath string) (*models.BatchIndex, error) {
+	var index models.BatchIndex
+	if err := format.ParseFile(filePath, &index); err != nil {
+		return nil, fmt.Errorf("error parsing batch index: %w", err)
+	}
+
+	return &index, nil
+}
+
+// IsBatchIndexFile reports whether the file looks like a batch index rather than a single plan
+func IsBatchIndexFile(filePath string) bool {
+	var data map[string]interface{}
+	if err := format.ParseFile(filePath, &data); err != nil {
+		return false
+	}
+
+	_, hasEntries := data["entries"]
+	_, hasSteps := data["steps"]
+	return hasEntries && !hasSteps
+}
+
+// ExecuteBatch executes every plan in a batch index. Each plan runs with its
+// repository as the working directory and writes its output to its own log
+// file, so plans can run concurrently without interleaving. A failure in one
+// repository is recorded in its result and does not stop the others.
+func ExecuteBatch(indexPath string, options BatchExecutionOptions) (*BatchExecutionResult, error) {
+	index, err := LoadBatchIndex(indexPath)
+	if err != nil {
+		return nil, err
+	}
+
+	indexDir := filepath.Dir(indexPath)
+	logDir := options.LogDir
+	if logDir == "" {
+		logDir = filepath.Join(indexDir, "logs")
+	}
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating log directory: %w", err)
+	}
+
+	result := &BatchExecutionResult{
+		Repos: make([]RepoExecutionResult, len(index.Entries)),
+	}
+	if len(index.Entries) == 0 {
+		return result, nil
+	}
+
+	workers := options.Workers
+	if workers < 1 || workers > len(index.Entries) {
+		workers = len(index.Entries)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result.Repos[i] = executeBatchEntry(index.Entries[i], indexDir, logDir, options.Execution)
+			}
+		}()
+	}
+
+	for i := range index.Entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return result, nil
+}
+
+// executeBatchEntry executes a single repository's plan, logging to its own file
+func executeBatchEntry(entry models.BatchIndexEntry, indexDir, logDir string, options models.ExecutionOptions) RepoExecutionResult {
+	repoPath := entry.RepoPath
+	if repoPath != "" && !filepath.IsAbs(repoPath) {
+		repoPath = filepath.Join(indexDir, repoPath)
+	}
+
+	result := RepoExecutionResult{
+		Name:     entry.Name,
+		RepoPath: repoPath,
+	}
+
+	if entry.Error != "" {
+		result.Err = fmt.Errorf("plan generation failed: %s", entry.Error)
+		return result
+	}
+	// The name becomes the log file's name, so it must not lead out of the log directory
+	if !isFileNameSegment(entry.Name) {
+		result.Err = fmt.Errorf("invalid repository name %q in index: names must be a single file name", entry.Name)
+		return result
+	}
+	if entry.PlanFile == "" {
+		result.Err = fmt.Errorf("no plan file recorded in index")
+		return result
+	}
+
+	result.PlanFile = entry.PlanFile
+	if !filepath.IsAbs(result.PlanFile) {
+		result.PlanFile = filepath.Join(indexDir, result.PlanFile)
+	}
+
+	if err := VerifyPlanSignature(result.PlanFile); err != nil {
+		result.Err = err
+		return result
+	}
+
+	plan, err := LoadPlanFile(result.PlanFile)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Steps = plan.Steps
+
+	result.LogFile = filepath.Join(logDir, entry.Name+".log")
+	logFile, err := os.Create(result.LogFile)
+	if err != nil {
+		result.Err = fmt.Errorf("error creating log file: %w", err)
+		return result
+	}
+	defer logFile.Close()
+
+	fmt.Fprintf(logFile, "Executing plan %s in %s\n", result.PlanFile, repoPath)
+
+	options.WorkingDir = repoPath
+	options.Output = logFile
+	if err := ExecutePlan(plan, options); err != nil {
+		fmt.Fprintf(logFile, "Error executing plan: %v\n", err)
+		result.Err = err
+	}
+	result.Steps = plan.Steps
+
+	return result
+}
+
+// isFileNameSegment reports whether name is a single local path segment
+func isFileNameSegment(name string) bool {
+	return filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`)
+}
+
+// WriteStatusMatrix writes a table of repository × step status. Steps that are
+// not part of a repository's plan are shown as "-", and steps that never ran
+// as "pending".
+func WriteStatusMatrix(w io.Writer, result *BatchExecutionResult) error {
+	// Columns are the distinct step IDs in order of first appearance
+	var stepIDs []string
+	seen := make(map[string]bool)
+	for _, repo := range result.Repos {
+		for _, step := range repo.Steps {
+			if !seen[step.ID] {
+				seen[step.ID] = true
+				stepIDs = append(stepIDs, step.ID)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := append(append([]string{"REPOSITORY"}, stepIDs...), "RESULT")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, repo := range result.Repos {
+		statuses := make(map[string]string, len(repo.Steps))
+		for _, step := range repo.Steps {
+			status := step.Status
+			if status == "" {
+				status = "pending"
+			}
+			statuses[step.ID] = status
+		}
+
+		row := []string{repo.Name}
+		for _, stepID := range stepIDs {
+			if status, ok := statuses[stepID]; ok {
+				row = append(row, status)
+			} else {
+				row = append(row, "-")
+			}
+		}
+
+		if repo.Err != nil {
+			row = append(row, "failed: "+repo.Err.Error())
+		} else {
+			row = append(row, "ok")
+		}
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupExecutionLibrary creates a global library with a file action and two CLI
+// actions and points DARN_HOME at it
+func setupExecutionLibrary(t *testing.T) {
+	darnHome := t.TempDir()
+	libraryDir := filepath.Join(darnHome, ".darn", "library")
+
+	files := map[string]string{
+		"templates/readme.tmpl": "# {{.name}}\n",
+		"actions/add-readme.yaml": `name: add-readme
+type: file
+template_path: readme.tmpl
+target_path: README.md`,
+		"actions/touch-marker.yaml": `name: touch-marker
+type: cli
+command: touch
+args: ["marker.txt"]`,
+		"actions/always-fail.yaml": `name: always-fail
+type: cli
+command: "false"`,
+	}
+	for name, content := range files {
+		path := filepath.Join(libraryDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Setenv("DARN_HOME", darnHome)
+}
+
+func TestExecuteBatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows")
+	}
+	setupExecutionLibrary(t)
+
+	dir := t.TempDir()
+	apiRepo := filepath.Join(dir, "api")
+	webRepo := filepath.Join(dir, "web")
+	require.NoError(t, os.MkdirAll(apiRepo, 0755))
+	require.NoError(t, os.MkdirAll(webRepo, 0755))
+
+	apiPlan := &models.RemediationPlan{
+		ProjectName: "api",
+		Steps: []models.RemediationStep{
+			{ID: "readme", ActionName: "add-readme", Params: map[string]interface{}{"name": "API"}},
+			{ID: "marker", ActionName: "touch-marker", Params: map[string]interface{}{}},
+		},
+	}
+	webPlan := &models.RemediationPlan{
+		ProjectName: "web",
+		Steps: []models.RemediationStep{
+			{ID: "readme", ActionName: "add-readme", Params: map[string]interface{}{"name": "Web"}},
+			{ID: "fail", ActionName: "always-fail", Params: map[string]interface{}{}},
+			{ID: "marker", ActionName: "touch-marker", Params: map[string]interface{}{}},
+		},
+	}
+	require.NoError(t, format.WriteFile(filepath.Join(dir, "api.plan.json"), apiPlan))
+	require.NoError(t, format.WriteFile(filepath.Join(dir, "web.plan.json"), webPlan))
+
+	indexPath := filepath.Join(dir, "index.json")
+	require.NoError(t, format.WriteFile(indexPath, &models.BatchIndex{
+		Entries: []models.BatchIndexEntry{
+			{Name: "api", RepoPath: apiRepo, PlanFile: "api.plan.json", StepCount: 2},
+			{Name: "web", RepoPath: "web", PlanFile: "web.plan.json", StepCount: 3},
+			{Name: "broken", RepoPath: apiRepo, Error: "error parsing report file"},
+		},
+	}))
+	assert.True(t, darnit.IsBatchIndexFile(indexPath))
+	assert.False(t, darnit.IsBatchIndexFile(filepath.Join(dir, "api.plan.json")))
+
+	result, err := darnit.ExecuteBatch(indexPath, darnit.BatchExecutionOptions{Workers: 2})
+	require.NoError(t, err)
+	require.Len(t, result.Repos, 3)
+	assert.Equal(t, 2, result.Failed())
+
+	// Each plan ran inside its own repository
+	api, web, broken := result.Repos[0], result.Repos[1], result.Repos[2]
+	assert.NoError(t, api.Err)
+	readme, err := os.ReadFile(filepath.Join(apiRepo, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# API\n", string(readme))
+	assert.FileExists(t, filepath.Join(apiRepo, "marker.txt"))
+
+	assert.Error(t, web.Err)
+	readme, err = os.ReadFile(filepath.Join(webRepo, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Web\n", string(readme))
+	assert.NoFileExists(t, filepath.Join(webRepo, "marker.txt"))
+	assert.Equal(t, "failure", web.Steps[1].Status)
+	assert.Empty(t, web.Steps[2].Status)
+
+	assert.ErrorContains(t, broken.Err, "plan generation failed")
+	assert.Empty(t, broken.LogFile)
+
+	// Output goes to per-repository logs
+	apiLog, err := os.ReadFile(api.LogFile)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "logs", "api.log"), api.LogFile)
+	assert.Contains(t, string(apiLog), "Created file: "+filepath.Join(apiRepo, "README.md"))
+	assert.Contains(t, string(apiLog), "Executing: touch marker.txt")
+
+	webLog, err := os.ReadFile(web.LogFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(webLog), "Error executing plan")
+	assert.NotContains(t, string(webLog), "touch marker.txt")
+
+	var matrix bytes.Buffer
+	require.NoError(t, darnit.WriteStatusMatrix(&matrix, result))
+	assert.Regexp(t, `REPOSITORY\s+readme\s+marker\s+fail\s+RESULT`, matrix.String())
+	assert.Regexp(t, `api\s+success\s+success\s+-\s+ok`, matrix.String())
+	assert.Regexp(t, `web\s+success\s+pending\s+failure\s+failed:`, matrix.String())
+	assert.Regexp(t, `broken\s+-\s+-\s+-\s+failed: plan generation failed`, matrix.String())
+}
+
+func TestExecuteBatchRejectsUnsafeNames(t *testing.T) {
+	setupExecutionLibrary(t)
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "api")
+	require.NoError(t, os.MkdirAll(repo, 0755))
+	require.NoError(t, format.WriteFile(filepath.Join(dir, "api.plan.json"), &models.RemediationPlan{
+		ProjectName: "api",
+		Steps:       []models.RemediationStep{{ID: "marker", ActionName: "touch-marker"}},
+	}))
+
+	indexDir := filepath.Join(dir, "batch")
+	indexPath := filepath.Join(indexDir, "index.json")
+	require.NoError(t, os.MkdirAll(indexDir, 0755))
+	var entries []models.BatchIndexEntry
+	for _, name := range []string{"../../escape", "nested/api", `..\escape`, "..", ""} {
+		entries = append(entries, models.BatchIndexEntry{Name: name, RepoPath: repo, PlanFile: "../api.plan.json"})
+	}
+	require.NoError(t, format.WriteFile(indexPath, &models.BatchIndex{Entries: entries}))
+
+	result, err := darnit.ExecuteBatch(indexPath, darnit.BatchExecutionOptions{})
+	require.NoError(t, err)
+	for _, entry := range result.Repos {
+		assert.ErrorContains(t, entry.Err, "invalid repository name", entry.Name)
+		assert.Empty(t, entry.LogFile)
+	}
+	assert.NoFileExists(t, filepath.Join(repo, "marker.txt"))
+	assert.NoFileExists(t, filepath.Join(filepath.Dir(dir), "escape.log"))
+}
@@ -4,11 +4,15 @@ package condition
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
 	"github.com/google/cel-go/common/types"
 	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
 )
 
 // CELEvaluator handles evaluation of CEL expressions
@@ -94,6 +98,38 @@ func (e *CELEvaluator) EvaluateExpression(expression string, data map[string]any
 	return result.Value().(bool), nil
 }
 
+// ReferencedVariables returns the sorted top-level variable names an expression
+// refers to. Comprehension variables (e.g. x in list.exists(x, ...)) are excluded.
+func (e *CELEvaluator) ReferencedVariables(expression string) ([]string, error) {
+	parsed, issues := e.baseEnv.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("error parsing expression: %w", issues.Err())
+	}
+
+	root := celast.NavigateAST(parsed.NativeRep())
+
+	// Collect variables bound by comprehensions so they aren't reported
+	bound := make(map[string]bool)
+	for _, comp := range celast.MatchDescendants(root, celast.KindMatcher(celast.ComprehensionKind)) {
+		bound[comp.AsComprehension().IterVar()] = true
+		bound[comp.AsComprehension().AccuVar()] = true
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, ident := range celast.MatchDescendants(root, celast.KindMatcher(celast.IdentKind)) {
+		name := ident.AsIdent()
+		if bound[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
 // createDynamicEnv creates a CEL environment with variables for all data keys
 func (e *CELEvaluator) createDynamicEnv(data map[string]any) (*cel.Env, error) {
 	// Start with base environment options
@@ -204,3 +240,79 @@ func (e *CELEvaluator) EvaluateStringArrayExpression(expression string, data map
 		return nil, fmt.Errorf("expression did not evaluate to a string array or string, got: %T", val)
 	}
 }
+
+// EvaluateListExpression evaluates a CEL expression that returns a list. The
+// elements are returned as native Go values (maps as map[string]interface{}).
+func (e *CELEvaluator) EvaluateListExpression(expression string, data map[string]any) ([]any, error) {
+	result, err := e.evaluate(expression, data)
+	if err != nil {
+		return nil, err
+	}
+
+	lister, ok := result.(traits.Lister)
+	if !ok {
+		return nil, fmt.Errorf("expression did not evaluate to a list, got: %s", result.Type().TypeName())
+	}
+
+	size, ok := lister.Size().(types.Int)
+	if !ok {
+		return nil, fmt.Errorf("error determining list size")
+	}
+
+	items := make([]any, 0, int(size))
+	for i := types.Int(0); i < size; i++ {
+		items = append(items, nativeValue(lister.Get(i)))
+	}
+
+	return items, nil
+}
+
+// EvaluateStringExpression evaluates a CEL expression and formats its result as a string
+func (e *CELEvaluator) EvaluateStringExpression(expression string, data map[string]any) (string, error) {
+	result, err := e.evaluate(expression, data)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%v", nativeValue(result)), nil
+}
+
+// evaluate parses, checks and evaluates an expression against data
+func (e *CELEvaluator) evaluate(expression string, data map[string]any) (ref.Val, error) {
+	dynamicEnv, err := e.createDynamicEnv(data)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic environment: %w", err)
+	}
+
+	checked, issues := dynamicEnv.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("error compiling expression: %w", issues.Err())
+	}
+
+	program, err := dynamicEnv.Program(checked)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling expression: %w", err)
+	}
+
+	result, _, err := program.Eval(data)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating expression: %w", err)
+	}
+
+	return result, nil
+}
+
+// nativeValue converts a CEL value to a plain Go value
+func nativeValue(val ref.Val) any {
+	switch val.Type() {
+	case types.MapType:
+		if native, err := val.ConvertToNative(reflect.TypeOf(map[string]any{})); err == nil {
+			return native
+		}
+	case types.ListType:
+		if native, err := val.ConvertToNative(reflect.TypeOf([]any{})); err == nil {
+			return native
+		}
+	}
+	return val.Value()
+}
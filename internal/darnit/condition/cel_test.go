@@ -228,3 +228,104 @@ func TestEvaluateStringArrayExpression(t *testing.T) {
 		})
 	}
 }
+
+func TestReferencedVariables(t *testing.T) {
+	evaluator, err := condition.NewCELEvaluator()
+	require.NoError(t, err, "Error creating CEL evaluator")
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   []string
+		wantErr    bool
+	}{
+		{
+			name:       "simple comparison",
+			expression: "security_policy == 'missing'",
+			expected:   []string{"security_policy"},
+		},
+		{
+			name:       "multiple and repeated variables",
+			expression: "mfa_status == 'disabled' && (org_size > 10 || mfa_status == 'partial')",
+			expected:   []string{"mfa_status", "org_size"},
+		},
+		{
+			name:       "field selection reports the root variable",
+			expression: "repo.visibility == 'public'",
+			expected:   []string{"repo"},
+		},
+		{
+			name:       "comprehension variables are excluded",
+			expression: "failed_controls.exists(c, c.startsWith('OSPS-GV'))",
+			expected:   []string{"failed_controls"},
+		},
+		{
+			name:       "constant expression",
+			expression: "true",
+			expected:   nil,
+		},
+		{
+			name:       "invalid syntax",
+			expression: "a ==",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := evaluator.ReferencedVariables(tt.expression)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestEvaluateListExpression(t *testing.T) {
+	evaluator, err := condition.NewCELEvaluator()
+	require.NoError(t, err)
+
+	data := map[string]interface{}{
+		"repos": []interface{}{
+			map[string]interface{}{"name": "api", "public": true},
+			map[string]interface{}{"name": "web", "public": false},
+		},
+		"name": "api",
+	}
+
+	items, err := evaluator.EvaluateListExpression("repos.filter(r, r.public)", data)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "api", "public": true}}, items)
+
+	items, err = evaluator.EvaluateListExpression("repos.map(r, r.name)", data)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"api", "web"}, items)
+
+	_, err = evaluator.EvaluateListExpression("name", data)
+	assert.Error(t, err)
+}
+
+func TestEvaluateStringExpression(t *testing.T) {
+	evaluator, err := condition.NewCELEvaluator()
+	require.NoError(t, err)
+
+	data := map[string]interface{}{
+		"repo":  map[string]interface{}{"name": "api", "stars": 3},
+		"count": 3,
+	}
+
+	value, err := evaluator.EvaluateStringExpression("repo.name + '-docs'", data)
+	require.NoError(t, err)
+	assert.Equal(t, "api-docs", value)
+
+	// Scalars are formatted as strings
+	value, err = evaluator.EvaluateStringExpression("count", data)
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+
+	_, err = evaluator.EvaluateStringExpression("repo.missing", data)
+	assert.Error(t, err)
+}
@@ -4,12 +4,14 @@ package darnit
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/kusari-oss/darn/internal/core/action"
 	"github.com/kusari-oss/darn/internal/core/config"
 	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/library"
 	"github.com/kusari-oss/darn/internal/core/models"
 	"github.com/kusari-oss/darn/internal/darn/resolver"
 	"github.com/kusari-oss/darn/internal/darnit/executor"
@@ -30,6 +32,7 @@ type GenerateOptions struct {
 	SkipRepoInference bool
 	NonInteractive    bool
 	VerboseLogging    bool
+	LibraryPath       string // Library to load actions from instead of the configured one
 }
 
 // ParseReportFile reads and parses a report file (supports both YAML and JSON)
@@ -63,8 +66,16 @@ func LoadPlanFile(filePath string) (*models.RemediationPlan, error) {
 
 // ExecutePlan executes a remediation plan
 func ExecutePlan(plan *models.RemediationPlan, options models.ExecutionOptions) error {
-	// Create action factory and resolver
-	factory, resolver, err := CreateActionResolver(options.WorkingDir)
+	// Refuse to run plans that lack the required approvals. Dry runs change
+	// nothing and are how reviewers inspect a plan, so they are allowed.
+	if !options.DryRun && options.RequiredApprovals > 0 {
+		if err := checkPlanApprovals(plan, options.RequiredApprovals); err != nil {
+			return fmt.Errorf("plan is not approved: %w", err)
+		}
+	}
+
+	// Create action factory and resolver bound to the plan's working directory and output
+	factory, resolver, err := createActionResolver(options.WorkingDir, "", options.Output)
 	if err != nil {
 		return fmt.Errorf("error creating action resolver: %w", err)
 	}
@@ -82,6 +93,18 @@ func ExecutePlan(plan *models.RemediationPlan, options models.ExecutionOptions)
 
 // CreateActionResolver creates the action factory and resolver
 func CreateActionResolver(workingDir string) (*action.Factory, *resolver.Resolver, error) {
+	return createActionResolver(workingDir, "", nil)
+}
+
+// CreateLibraryActionResolver creates the action factory and resolver for
+// libraryPath instead of the configured library, sending action output to output
+func CreateLibraryActionResolver(workingDir, libraryPath string, output io.Writer) (*action.Factory, *resolver.Resolver, error) {
+	return createActionResolver(workingDir, libraryPath, output)
+}
+
+// createActionResolver creates the action factory and resolver, sending action
+// output to output. A libraryPath replaces the configured library and sources.
+func createActionResolver(workingDir, libraryPath string, output io.Writer) (*action.Factory, *resolver.Resolver, error) {
 	// If working directory not specified, use current directory
 	if workingDir == "" {
 		var err error
@@ -91,14 +114,10 @@ func CreateActionResolver(workingDir string) (*action.Factory, *resolver.Resolve
 		}
 	}
 
-	// Load configuration
-	// For darnit's CreateActionResolver, cmdLineLibraryPath is implicitly handled by LoadConfig if rootCmd's PersistentPreRunE
-	// has already loaded a config influenced by the --library-path flag.
-	// If this function is called independently, or before such a flag-aware LoadConfig has populated a shared/global config instance,
-	// then cmdLineLibraryPath would typically be empty here.
-	// globalConfigPathOverride is also not directly exposed here.
-	// The projectDir argument has been removed from LoadConfig.
-	cfg, err := config.LoadConfig("", "") // Assuming no direct override at this specific function call
+	// Load configuration. A libraryPath takes the place of a command-line
+	// library path, so the library's own actions and templates are used.
+	// globalConfigPathOverride is not exposed here.
+	cfg, err := config.LoadConfig(libraryPath, "")
 	if err != nil {
 		return nil, nil, fmt.Errorf("error loading configuration: %w", err)
 	}
@@ -112,6 +131,7 @@ func CreateActionResolver(workingDir string) (*action.Factory, *resolver.Resolve
 		UseLocal:           cfg.UseLocal,
 		UseGlobal:          cfg.UseGlobal,
 		GlobalFirst:        cfg.GlobalFirst,
+		Output:             output,
 	}
 
 	// Create action factory with context
@@ -129,7 +149,30 @@ func CreateActionResolver(workingDir string) (*action.Factory, *resolver.Resolve
 		cfg.GlobalFirst,
 		cfg.ActionsDir,
 		cfg.LibraryPath,
-	)
+	).WithSources(cfg.LibrarySources)
+
+	// Fall back to the defaults built into darn when there is no library
+	if cfg.BuiltinDefaults() {
+		resolver.WithBuiltinDefaults()
+	}
+
+	// Only load signed actions when trusted keys are configured
+	verifier, err := cfg.Verifier()
+	if err != nil {
+		return nil, nil, err
+	}
+	if verifier != nil {
+		resolver.WithVerifier(verifier)
+	}
+
+	// Only load library actions that match the project's lock, if it has one
+	lock, err := library.LoadLock(workingDir, cfg.LibraryPath, config.ExpandPathWithTilde(config.DefaultCacheDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	if lock != nil {
+		resolver.WithLock(lock)
+	}
 
 	return factory, resolver, nil
 }
@@ -137,23 +180,7 @@ func CreateActionResolver(workingDir string) (*action.Factory, *resolver.Resolve
 // LoadDefaultParameters loads default parameters from config (supports YAML and JSON)
 func LoadDefaultParameters(configPath string) (map[string]any, error) {
 	if configPath == "" {
-		// Look in standard locations (try both YAML and JSON extensions)
-		candidates := []string{
-			"./params.yaml",
-			"./params.json",
-			"./.darn/params.yaml",
-			"./.darn/params.json",
-			os.ExpandEnv("$HOME/.darn/params.yaml"),
-			os.ExpandEnv("$HOME/.darn/params.json"),
-		}
-
-		for _, path := range candidates {
-			if _, err := os.Stat(path); err == nil {
-				configPath = path
-				break
-			}
-		}
-
+		configPath = FindDefaultParameters(".")
 		if configPath == "" {
 			return make(map[string]any), nil
 		}
@@ -170,6 +197,28 @@ func LoadDefaultParameters(configPath string) (map[string]any, error) {
 	return config.DefaultParameters, nil
 }
 
+// FindDefaultParameters returns the default parameters file for the project
+// at dir: params.yaml or params.json in dir or its .darn directory, falling
+// back to the user's ~/.darn. It returns "" when there is none.
+func FindDefaultParameters(dir string) string {
+	// Look in standard locations (try both YAML and JSON extensions)
+	candidates := []string{
+		filepath.Join(dir, "params.yaml"),
+		filepath.Join(dir, "params.json"),
+		filepath.Join(dir, ".darn", "params.yaml"),
+		filepath.Join(dir, ".darn", "params.json"),
+		os.ExpandEnv("$HOME/.darn/params.yaml"),
+		os.ExpandEnv("$HOME/.darn/params.json"),
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
 // PromptForMissingParameters asks the user for any missing required parameters
 func PromptForMissingParameters(data map[string]any, requiredParams []string) error {
 	for _, param := range requiredParams {
@@ -5,6 +5,8 @@ package executor
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/action"
@@ -28,16 +30,24 @@ func NewStepExecutor(resolver *r.Resolver, options models.ExecutionOptions) *Ste
 	}
 }
 
+// output returns the destination for execution output
+func (e *StepExecutor) output() io.Writer {
+	if e.options.Output == nil {
+		return os.Stdout
+	}
+	return e.options.Output
+}
+
 // ExecuteStep executes a single step in the plan
 func (e *StepExecutor) ExecuteStep(step *models.RemediationStep) error {
 	// Update status
 	step.Status = "running"
 
 	if e.options.VerboseLogging {
-		fmt.Printf("Executing step: %s (Action: %s)\n", step.ID, step.ActionName)
-		fmt.Printf("Reason: %s\n", step.Reason)
+		fmt.Fprintf(e.output(), "Executing step: %s (Action: %s)\n", step.ID, step.ActionName)
+		fmt.Fprintf(e.output(), "Reason: %s\n", step.Reason)
 	} else {
-		fmt.Printf("Executing step: %s\n", step.ID)
+		fmt.Fprintf(e.output(), "Executing step: %s\n", step.ID)
 	}
 
 	// Process any parameter references from previous steps
@@ -45,10 +55,9 @@ func (e *StepExecutor) ExecuteStep(step *models.RemediationStep) error {
 		return err
 	}
 
-	// In dry-run mode, just print the parameters
+	// In dry-run mode, preview the step instead of executing it
 	if e.options.DryRun {
-		e.dryRunStep(step)
-		return nil
+		return e.dryRunStep(step)
 	}
 
 	// Get the action
@@ -58,9 +67,9 @@ func (e *StepExecutor) ExecuteStep(step *models.RemediationStep) error {
 		step.Error = fmt.Sprintf("error resolving action: %v", err)
 
 		if e.options.VerboseLogging {
-			fmt.Printf("Error resolving action '%s': %v\n", step.ActionName, err)
+			fmt.Fprintf(e.output(), "Error resolving action '%s': %v\n", step.ActionName, err)
 		} else {
-			fmt.Printf("Error: %v\n", err)
+			fmt.Fprintf(e.output(), "Error: %v\n", err)
 		}
 
 		return fmt.Errorf("error resolving action '%s': %w", step.ActionName, err)
@@ -118,7 +127,7 @@ func (e *StepExecutor) processOutputReferences(step *models.RemediationStep) err
 		step.Params[paramName] = outputValue
 
 		if e.options.VerboseLogging {
-			fmt.Printf("  Setting parameter %s to value from %s.%s (type: %T)\n",
+			fmt.Fprintf(e.output(), "  Setting parameter %s to value from %s.%s (type: %T)\n",
 				paramName, sourceStepID, outputName, outputValue)
 		}
 	}
@@ -126,11 +135,31 @@ func (e *StepExecutor) processOutputReferences(step *models.RemediationStep) err
 	return nil
 }
 
-// dryRunStep simulates step execution in dry-run mode
-func (e *StepExecutor) dryRunStep(step *models.RemediationStep) {
-	paramsJSON, _ := json.MarshalIndent(step.Params, "  ", "  ")
-	fmt.Printf("  Would execute action '%s' with parameters:\n  %s\n",
-		step.ActionName, string(paramsJSON))
+// dryRunStep simulates step execution in dry-run mode. Actions that support
+// previews show their real effect, such as file diffs or the templated
+// command line; others show their parameters.
+func (e *StepExecutor) dryRunStep(step *models.RemediationStep) error {
+	act, err := e.resolver.ResolveAction(step.ActionName)
+	if err != nil {
+		e.handleExecutionError(step, fmt.Errorf("error resolving action: %w", err))
+		return fmt.Errorf("error resolving action '%s': %w", step.ActionName, err)
+	}
+
+	previewAct, ok := act.(action.PreviewAction)
+	if !ok || e.options.VerboseLogging {
+		paramsJSON, _ := json.MarshalIndent(step.Params, "  ", "  ")
+		fmt.Fprintf(e.output(), "  Would execute action '%s' with parameters:\n  %s\n",
+			step.ActionName, string(paramsJSON))
+	}
+
+	if ok {
+		preview, err := previewAct.Preview(step.Params)
+		if err != nil {
+			e.handleExecutionError(step, fmt.Errorf("preview failed: %w", err))
+			return err
+		}
+		fmt.Fprintf(e.output(), "  Would execute action '%s':\n%s", step.ActionName, indentLines(preview, "    "))
+	}
 
 	// In dry-run, simulate outputs for next steps
 	if step.Outputs != nil {
@@ -139,6 +168,19 @@ func (e *StepExecutor) dryRunStep(step *models.RemediationStep) {
 
 	// Mark step as successful in dry-run mode
 	step.Status = "success"
+	return nil
+}
+
+// indentLines prefixes each line of text with prefix
+func indentLines(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		if line != "" {
+			b.WriteString(prefix + line)
+		}
+	}
+	return b.String()
 }
 
 // executeAction executes the action and processes its outputs
@@ -181,10 +223,10 @@ func (e *StepExecutor) executeAction(step *models.RemediationStep, act action.Ac
 	step.Status = "success"
 
 	if e.options.VerboseLogging {
-		fmt.Printf("Step completed successfully\n")
+		fmt.Fprintf(e.output(), "Step completed successfully\n")
 		if len(e.stepOutputs[step.ID]) > 0 {
 			outputJSON, _ := json.MarshalIndent(e.stepOutputs[step.ID], "  ", "  ")
-			fmt.Printf("  Outputs: %s\n", string(outputJSON))
+			fmt.Fprintf(e.output(), "  Outputs: %s\n", string(outputJSON))
 		}
 	}
 
@@ -197,9 +239,9 @@ func (e *StepExecutor) handleExecutionError(step *models.RemediationStep, err er
 	step.Error = fmt.Sprintf("execution failed: %v", err)
 
 	if e.options.VerboseLogging {
-		fmt.Printf("Error executing action '%s': %v\n", step.ActionName, err)
+		fmt.Fprintf(e.output(), "Error executing action '%s': %v\n", step.ActionName, err)
 	} else {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(e.output(), "Error: %v\n", err)
 	}
 }
 
@@ -223,6 +265,11 @@ func NewPlanExecutor(factory *action.Factory, resolver *r.Resolver, options mode
 	}
 }
 
+// output returns the destination for execution output
+func (e *PlanExecutor) output() io.Writer {
+	return e.stepExecutor.output()
+}
+
 // ExecutePlan executes a remediation plan
 func (e *PlanExecutor) ExecutePlan(plan *models.RemediationPlan) error {
 	successCount := 0
@@ -233,7 +280,7 @@ func (e *PlanExecutor) ExecutePlan(plan *models.RemediationPlan) error {
 		// Get a pointer to the step to allow modifications
 		step := &plan.Steps[i]
 
-		fmt.Printf("Executing step %d/%d: %s\n", i+1, len(plan.Steps), step.ID)
+		fmt.Fprintf(e.output(), "Executing step %d/%d: %s\n", i+1, len(plan.Steps), step.ID)
 
 		err := e.stepExecutor.ExecuteStep(step)
 
@@ -248,7 +295,7 @@ func (e *PlanExecutor) ExecutePlan(plan *models.RemediationPlan) error {
 	}
 
 	// Print summary
-	fmt.Printf("\nExecution summary: %d successful, %d failed (out of %d total steps)\n",
+	fmt.Fprintf(e.output(), "\nExecution summary: %d successful, %d failed (out of %d total steps)\n",
 		successCount, failedCount, len(plan.Steps))
 
 	if failedCount > 0 && !e.options.ContinueOnError {
@@ -9,28 +9,39 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
-// InferParametersFromRepo extracts information from a Git repository
+// RepoInferenceResult holds the parameters inferred for a single repository
+type RepoInferenceResult struct {
+	RepoPath string
+	Params   map[string]interface{}
+	Err      error
+}
+
+// InferParametersFromRepo extracts information from a Git repository.
+// The repository is addressed by its root path; the process working directory
+// is never changed, so this is safe to call from multiple goroutines.
 func InferParametersFromRepo(repoPath string) (map[string]interface{}, error) {
-	params := make(map[string]interface{})
+	if repoPath == "" {
+		repoPath = "."
+	}
 
-	// Change to repo directory if provided
-	currentDir, err := os.Getwd()
+	info, err := os.Stat(repoPath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error accessing repo directory: %w", err)
 	}
-
-	if repoPath != "" {
-		if err := os.Chdir(repoPath); err != nil {
-			return nil, fmt.Errorf("error changing to repo directory: %w", err)
-		}
-		defer os.Chdir(currentDir)
+	if !info.IsDir() {
+		return nil, fmt.Errorf("error accessing repo directory: %s is not a directory", repoPath)
 	}
 
+	params := make(map[string]interface{})
+
 	// Get remote URL
 	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
+	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err == nil {
 		remoteURL := strings.TrimSpace(string(output))
@@ -49,8 +60,9 @@ func InferParametersFromRepo(repoPath string) (map[string]interface{}, error) {
 
 	// Get project name from package.json, go.mod, etc.
 	// This is just an example for one file type
-	if _, err := os.Stat("package.json"); err == nil {
-		data, err := os.ReadFile("package.json")
+	packageJSONPath := filepath.Join(repoPath, "package.json")
+	if _, err := os.Stat(packageJSONPath); err == nil {
+		data, err := os.ReadFile(packageJSONPath)
 		if err == nil {
 			var pkg struct {
 				Name string `json:"name"`
@@ -63,3 +75,44 @@ func InferParametersFromRepo(repoPath string) (map[string]interface{}, error) {
 
 	return params, nil
 }
+
+// InferParametersFromRepos infers parameters for many repositories concurrently.
+// At most maxWorkers repositories are inspected at a time (a value below one
+// means one worker per repository). Results are returned in the same order as
+// repoPaths; a failure for one repository is reported in its result and does
+// not stop the others.
+func InferParametersFromRepos(repoPaths []string, maxWorkers int) []RepoInferenceResult {
+	results := make([]RepoInferenceResult, len(repoPaths))
+	if len(repoPaths) == 0 {
+		return results
+	}
+
+	if maxWorkers < 1 || maxWorkers > len(repoPaths) {
+		maxWorkers = len(repoPaths)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < maxWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				params, err := InferParametersFromRepo(repoPaths[i])
+				results[i] = RepoInferenceResult{
+					RepoPath: repoPaths[i],
+					Params:   params,
+					Err:      err,
+				}
+			}
+		}()
+	}
+
+	for i := range repoPaths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
@@ -161,5 +161,60 @@ func TestInferParametersFromNonExistentDirectory(t *testing.T) {
 	params, err := darnit.InferParametersFromRepo("/path/that/does/not/exist")
 	assert.Error(t, err, "Error should be returned for non-existent directory")
 	assert.Nil(t, params, "Parameters should be nil for non-existent directory")
-	assert.Contains(t, err.Error(), "error changing to repo directory", "Error message should indicate directory issue")
+	assert.Contains(t, err.Error(), "error accessing repo directory", "Error message should indicate directory issue")
+}
+
+func TestInferParametersFromRepoKeepsWorkingDirectory(t *testing.T) {
+	// Skip if git is not available
+	_, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("Git not available, skipping test")
+	}
+
+	repoPath := setupGitRepo(t)
+
+	originalWd, err := os.Getwd()
+	require.NoError(t, err)
+
+	_, err = darnit.InferParametersFromRepo(repoPath)
+	require.NoError(t, err)
+
+	currentWd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, originalWd, currentWd, "Inference should not change the process working directory")
+}
+
+func TestInferParametersFromRepos(t *testing.T) {
+	// Skip if git is not available
+	_, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("Git not available, skipping test")
+	}
+
+	// Set up several repositories and one invalid path
+	repoPaths := []string{
+		setupGitRepo(t),
+		setupGitRepo(t),
+		"/path/that/does/not/exist",
+		setupGitRepo(t),
+	}
+
+	results := darnit.InferParametersFromRepos(repoPaths, 2)
+	require.Len(t, results, len(repoPaths), "Should return one result per repository")
+
+	for i, result := range results {
+		assert.Equal(t, repoPaths[i], result.RepoPath, "Results should keep input order")
+		if i == 2 {
+			assert.Error(t, result.Err, "Non-existent directory should report an error")
+			continue
+		}
+		require.NoError(t, result.Err)
+		assert.Equal(t, "test-repo", result.Params["repo_name"], "Repository name incorrect")
+		assert.Equal(t, "test-project", result.Params["project_name"], "Project name incorrect")
+	}
+}
+
+func TestInferParametersFromReposEmpty(t *testing.T) {
+	results := darnit.InferParametersFromRepos(nil, 4)
+	assert.Empty(t, results, "No results expected for no repositories")
 }
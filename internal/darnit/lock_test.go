@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutePlanWithLockedLibraries(t *testing.T) {
+	setupExecutionLibrary(t)
+	darnDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn")
+	libraryDir := filepath.Join(darnDir, "library")
+
+	files := map[string]string{
+		"library/library.yaml":         "name: default\nversion: 1.0.0\ndependencies:\n  - name: base\n    version: ^1.0.0\n",
+		"base/library.yaml":            "name: base\nversion: 1.2.0\n",
+		"base/templates/notice.tmpl":   "Copyright {{.owner}}\n",
+		"base/actions/add-notice.yaml": "name: add-notice\ntype: file\ntemplate_path: notice.tmpl\ntarget_path: NOTICE\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(darnDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	repo := t.TempDir()
+	lock, err := library.NewLock(libraryDir, "dev", "")
+	require.NoError(t, err)
+	_, err = lock.Save(repo)
+	require.NoError(t, err)
+
+	plan := &models.RemediationPlan{
+		ProjectName: "api",
+		Steps: []models.RemediationStep{
+			{ID: "readme", ActionName: "add-readme", Params: map[string]interface{}{"name": "API"}},
+			{ID: "notice", ActionName: "add-notice", Params: map[string]interface{}{"owner": "Example"}},
+		},
+	}
+	options := models.ExecutionOptions{WorkingDir: repo, Output: io.Discard}
+
+	// The dependency's actions and templates are available through the lock
+	require.NoError(t, darnit.ExecutePlan(plan, options))
+	notice, err := os.ReadFile(filepath.Join(repo, "NOTICE"))
+	require.NoError(t, err)
+	assert.Equal(t, "Copyright Example\n", string(notice))
+
+	// A locked library that changed is refused
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "base", "templates", "notice.tmpl"), []byte("changed\n"), 0644))
+	plan.Steps = plan.Steps[1:]
+	plan.Steps[0].Status = ""
+	err = darnit.ExecutePlan(plan, options)
+	require.ErrorContains(t, err, "refusing to load actions")
+	assert.ErrorContains(t, err, "base 1.2.0: content changed since it was locked")
+}
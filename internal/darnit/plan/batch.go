@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+	. "github.com/kusari-oss/darn/internal/darnit"
+)
+
+// DefaultBatchIndexFile is the name of the index written next to batch plans
+const DefaultBatchIndexFile = "index.json"
+
+// BatchEntry describes one repository in a batch manifest
+type BatchEntry struct {
+	Name        string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	RepoPath    string                 `yaml:"repo" json:"repo"`
+	ReportFile  string                 `yaml:"report" json:"report"`
+	MappingFile string                 `yaml:"mappings,omitempty" json:"mappings,omitempty"`
+	ParamsFile  string                 `yaml:"params_file,omitempty" json:"params_file,omitempty"`
+	Params      map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+}
+
+// BatchManifest lists the repositories to generate plans for
+type BatchManifest struct {
+	// Mapping file used for entries that do not set their own
+	MappingFile string `yaml:"mappings,omitempty" json:"mappings,omitempty"`
+
+	// Parameters shared by every entry (entry parameters take precedence)
+	Params map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+
+	Repositories []BatchEntry `yaml:"repositories" json:"repositories"`
+}
+
+// BatchOptions contains options for batch plan generation
+type BatchOptions struct {
+	OutputDir   string
+	MappingFile string // Used for entries that do not set their own, instead of the manifest mapping file
+	Workers     int
+	Generate    GenerateOptions // Base options applied to every entry
+}
+
+// LoadBatchManifest loads a batch manifest (YAML or JSON). Relative paths in
+// the manifest are resolved against the manifest's directory.
+func LoadBatchManifest(filePath string) (*BatchManifest, error) {
+	var manifest BatchManifest
+	if err := format.ParseFile(filePath, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing batch manifest: %w", err)
+	}
+
+	if len(manifest.Repositories) == 0 {
+		return nil, fmt.Errorf("batch manifest %s lists no repositories", filePath)
+	}
+
+	baseDir := filepath.Dir(filePath)
+	manifest.MappingFile = resolveManifestPath(baseDir, manifest.MappingFile)
+	for i := range manifest.Repositories {
+		entry := &manifest.Repositories[i]
+		if entry.RepoPath == "" {
+			return nil, fmt.Errorf("batch manifest entry %d has no repo path", i+1)
+		}
+		if entry.ReportFile == "" {
+			return nil, fmt.Errorf("batch manifest entry %d (%s) has no report", i+1, entry.RepoPath)
+		}
+		entry.RepoPath = resolveManifestPath(baseDir, entry.RepoPath)
+		entry.ReportFile = resolveManifestPath(baseDir, entry.ReportFile)
+		entry.MappingFile = resolveManifestPath(baseDir, entry.MappingFile)
+		entry.ParamsFile = resolveManifestPath(baseDir, entry.ParamsFile)
+	}
+
+	return &manifest, nil
+}
+
+// resolveManifestPath makes a manifest path absolute relative to baseDir
+func resolveManifestPath(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if abs, err := filepath.Abs(filepath.Join(baseDir, path)); err == nil {
+		return abs
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// GenerateBatch generates one plan per manifest entry using a pool of workers.
+// Each plan is written to the output directory along with an index file. A
+// failure for one entry is recorded in the index and does not stop the batch.
+func GenerateBatch(manifest *BatchManifest, options BatchOptions) (*models.BatchIndex, error) {
+	if options.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required for batch generation")
+	}
+	if err := os.MkdirAll(options.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	names := batchEntryNames(manifest.Repositories)
+	entries := make([]models.BatchIndexEntry, len(manifest.Repositories))
+
+	workers := options.Workers
+	if workers < 1 || workers > len(manifest.Repositories) {
+		workers = len(manifest.Repositories)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entries[i] = generateBatchEntry(manifest, manifest.Repositories[i], names[i], options)
+			}
+		}()
+	}
+
+	for i := range manifest.Repositories {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	index := &models.BatchIndex{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		Entries:     entries,
+	}
+
+	indexPath := filepath.Join(options.OutputDir, DefaultBatchIndexFile)
+	if err := format.WriteFile(indexPath, index); err != nil {
+		return nil, fmt.Errorf("error writing batch index: %w", err)
+	}
+
+	return index, nil
+}
+
+// generateBatchEntry generates and saves the plan for a single manifest entry
+func generateBatchEntry(manifest *BatchManifest, entry BatchEntry, name string, options BatchOptions) models.BatchIndexEntry {
+	result := models.BatchIndexEntry{
+		Name:       name,
+		RepoPath:   entry.RepoPath,
+		ReportFile: entry.ReportFile,
+	}
+
+	mappingFile := options.MappingFile
+	if entry.MappingFile != "" {
+		mappingFile = entry.MappingFile
+	} else if mappingFile == "" {
+		mappingFile = manifest.MappingFile
+	}
+	if mappingFile == "" {
+		result.Error = "no mapping file configured"
+		return result
+	}
+
+	// Parameters: base options < manifest < params file < entry
+	extraParams := make(map[string]interface{})
+	for k, v := range options.Generate.ExtraParams {
+		extraParams[k] = v
+	}
+	for k, v := range manifest.Params {
+		extraParams[k] = v
+	}
+	if entry.ParamsFile != "" {
+		var fileParams map[string]interface{}
+		if err := format.ParseFile(entry.ParamsFile, &fileParams); err != nil {
+			result.Error = fmt.Sprintf("error loading params file: %v", err)
+			return result
+		}
+		for k, v := range fileParams {
+			extraParams[k] = v
+		}
+	}
+	for k, v := range entry.Params {
+		extraParams[k] = v
+	}
+
+	report, err := ParseReportFile(entry.ReportFile)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	generateOptions := options.Generate
+	generateOptions.RepoPath = entry.RepoPath
+	// Default parameters come from each repository, not the working directory
+	if generateOptions.DefaultsPath == "" {
+		generateOptions.DefaultsPath = FindDefaultParameters(entry.RepoPath)
+		generateOptions.SkipDefaults = generateOptions.SkipDefaults || generateOptions.DefaultsPath == ""
+	}
+	generateOptions.ExtraParams = extraParams
+	// Prompts and verbose logs cannot be shared between concurrent workers
+	generateOptions.NonInteractive = true
+	generateOptions.VerboseLogging = false
+
+	plan, err := GenerateRemediationPlan(report, mappingFile, generateOptions)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	planFile := name + ".plan.json"
+	if err := format.WriteFile(filepath.Join(options.OutputDir, planFile), plan); err != nil {
+		result.Error = fmt.Sprintf("error writing plan: %v", err)
+		return result
+	}
+
+	result.PlanFile = planFile
+	result.StepCount = len(plan.Steps)
+	result.Actions = planActionNames(plan)
+	return result
+}
+
+// batchEntryNames derives a unique, file-system safe name for each entry.
+// Names are compared ignoring case, since App and app name the same plan and
+// log files on case-insensitive file systems.
+func batchEntryNames(entries []BatchEntry) []string {
+	names := make([]string, len(entries))
+	seen := make(map[string]bool) // By lowercase name
+
+	for i, entry := range entries {
+		name := entry.Name
+		if name == "" {
+			name = filepath.Base(filepath.Clean(entry.RepoPath))
+		}
+		name = sanitizeName(name, "repo")
+
+		// A numbered name may itself be taken by another entry
+		unique := name
+		for n := 2; seen[strings.ToLower(unique)]; n++ {
+			unique = fmt.Sprintf("%s-%d", name, n)
+		}
+		seen[strings.ToLower(unique)] = true
+		names[i] = unique
+	}
+
+	return names
+}
+
+// sanitizeName replaces characters that are unsafe in file names and step IDs,
+// returning fallback if nothing usable remains
+func sanitizeName(name, fallback string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		name = fallback
+	}
+	return name
+}
+
+// planActionNames returns the sorted, distinct action names used by a plan
+func planActionNames(plan *models.RemediationPlan) []string {
+	seen := make(map[string]bool)
+	var actions []string
+	for _, step := range plan.Steps {
+		if !seen[step.ActionName] {
+			seen[step.ActionName] = true
+			actions = append(actions, step.ActionName)
+		}
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// SummarizeBatchActions maps each action name to the entries whose plans use it
+func SummarizeBatchActions(index *models.BatchIndex) map[string][]string {
+	summary := make(map[string][]string)
+	for _, entry := range index.Entries {
+		for _, actionName := range entry.Actions {
+			summary[actionName] = append(summary[actionName], entry.Name)
+		}
+	}
+	for actionName := range summary {
+		sort.Strings(summary[actionName])
+	}
+	return summary
+}
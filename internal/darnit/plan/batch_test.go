@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestLoadBatchManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifestFile := filepath.Join(dir, "batch.yaml")
+	writeTestFile(t, manifestFile, `mappings: mappings.yaml
+params:
+  organization: test-org
+repositories:
+  - name: api
+    repo: repos/api
+    report: reports/api.json
+    params:
+      project_name: API
+  - repo: /abs/web
+    report: reports/web.json`)
+
+	manifest, err := plan.LoadBatchManifest(manifestFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, "mappings.yaml"), manifest.MappingFile)
+	assert.Equal(t, "test-org", manifest.Params["organization"])
+	require.Len(t, manifest.Repositories, 2)
+	assert.Equal(t, "api", manifest.Repositories[0].Name)
+	assert.Equal(t, filepath.Join(dir, "repos", "api"), manifest.Repositories[0].RepoPath)
+	assert.Equal(t, filepath.Join(dir, "reports", "api.json"), manifest.Repositories[0].ReportFile)
+	assert.Equal(t, "API", manifest.Repositories[0].Params["project_name"])
+	assert.Equal(t, "/abs/web", manifest.Repositories[1].RepoPath)
+}
+
+func TestLoadBatchManifestInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	empty := filepath.Join(dir, "empty.yaml")
+	writeTestFile(t, empty, "repositories: []\n")
+	_, err := plan.LoadBatchManifest(empty)
+	assert.ErrorContains(t, err, "lists no repositories")
+
+	noReport := filepath.Join(dir, "no-report.yaml")
+	writeTestFile(t, noReport, "repositories:\n  - repo: api\n")
+	_, err = plan.LoadBatchManifest(noReport)
+	assert.ErrorContains(t, err, "has no report")
+}
+
+func TestGenerateBatch(t *testing.T) {
+	testLibrary := setupTestLibrary(t)
+	t.Setenv("DARN_HOME", testLibrary)
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "mappings.yaml"), `mappings:
+  - id: "security-policy-remediation"
+    condition: "security_policy == 'missing'"
+    action: "add-security-md"
+    reason: "Add security documentation"
+    parameters:
+      name: "{{.project_name}}"
+      emails: ["{{.security_email}}"]
+  - id: "mfa-remediation"
+    condition: "mfa_status == 'disabled'"
+    action: "enable-mfa"
+    reason: "Enable MFA for organization"
+    parameters:
+      organization: "{{.organization}}"`)
+	writeTestFile(t, filepath.Join(dir, "reports", "api.json"), `{"security_policy": "missing", "mfa_status": "disabled"}`)
+	writeTestFile(t, filepath.Join(dir, "reports", "web.json"), `{"security_policy": "present", "mfa_status": "disabled"}`)
+	for _, repo := range []string{"api", "web"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "repos", repo), 0755))
+	}
+
+	manifestFile := filepath.Join(dir, "batch.yaml")
+	writeTestFile(t, manifestFile, `mappings: mappings.yaml
+params:
+  organization: test-org
+  security_email: security@example.com
+repositories:
+  - repo: repos/api
+    report: reports/api.json
+    params:
+      project_name: API
+  - repo: repos/web
+    report: reports/web.json
+    params:
+      project_name: Web
+  - name: broken
+    repo: repos/web
+    report: reports/missing.json`)
+
+	manifest, err := plan.LoadBatchManifest(manifestFile)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(dir, "plans")
+	index, err := plan.GenerateBatch(manifest, plan.BatchOptions{
+		OutputDir: outputDir,
+		Workers:   2,
+		Generate: darnit.GenerateOptions{
+			MappingsDir:       filepath.Join(testLibrary, ".darn", "library", "mappings"),
+			SkipDefaults:      true,
+			SkipRepoInference: true,
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, index.Entries, 3)
+
+	// Entries keep manifest order
+	api, web, broken := index.Entries[0], index.Entries[1], index.Entries[2]
+
+	assert.Equal(t, "api", api.Name)
+	assert.Empty(t, api.Error)
+	assert.Equal(t, 2, api.StepCount)
+	assert.Equal(t, []string{"add-security-md", "enable-mfa"}, api.Actions)
+
+	assert.Equal(t, "web", web.Name)
+	assert.Empty(t, web.Error)
+	assert.Equal(t, 1, web.StepCount)
+	assert.Equal(t, []string{"enable-mfa"}, web.Actions)
+
+	assert.Equal(t, "broken", broken.Name)
+	assert.NotEmpty(t, broken.Error)
+	assert.Empty(t, broken.PlanFile)
+
+	// Plans and the index are written to the output directory
+	apiPlan, err := darnit.LoadPlanFile(filepath.Join(outputDir, api.PlanFile))
+	require.NoError(t, err)
+	require.Len(t, apiPlan.Steps, 2)
+	assert.Equal(t, "API", apiPlan.ProjectName)
+
+	var written models.BatchIndex
+	require.NoError(t, format.ParseFile(filepath.Join(outputDir, plan.DefaultBatchIndexFile), &written))
+	require.Len(t, written.Entries, 3)
+	assert.Equal(t, "api.plan.json", written.Entries[0].PlanFile)
+	assert.Equal(t, "web.plan.json", written.Entries[1].PlanFile)
+
+	summary := plan.SummarizeBatchActions(index)
+	assert.Equal(t, []string{"api", "web"}, summary["enable-mfa"])
+	assert.Equal(t, []string{"api"}, summary["add-security-md"])
+}
+
+func TestGenerateBatchMappingPrecedence(t *testing.T) {
+	testLibrary := setupTestLibrary(t)
+	t.Setenv("DARN_HOME", testLibrary)
+
+	dir := t.TempDir()
+	for _, action := range []string{"enable-mfa", "add-security-md"} {
+		writeTestFile(t, filepath.Join(dir, action+".yaml"), "mappings:\n  - id: \""+action+"\"\n    action: \""+action+"\"\n    reason: \"test\"\n")
+	}
+	writeTestFile(t, filepath.Join(dir, "manifest-level.yaml"), "mappings: []\n")
+	writeTestFile(t, filepath.Join(dir, "report.json"), `{}`)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "repo"), 0755))
+
+	manifestFile := filepath.Join(dir, "batch.yaml")
+	writeTestFile(t, manifestFile, `mappings: manifest-level.yaml
+params:
+  organization: test-org
+repositories:
+  - name: own
+    repo: repo
+    report: report.json
+    mappings: enable-mfa.yaml
+  - name: default
+    repo: repo
+    report: report.json`)
+
+	manifest, err := plan.LoadBatchManifest(manifestFile)
+	require.NoError(t, err)
+
+	// An entry's own mappings win over the option, which wins over the manifest's
+	index, err := plan.GenerateBatch(manifest, plan.BatchOptions{
+		OutputDir:   filepath.Join(dir, "plans"),
+		MappingFile: filepath.Join(dir, "add-security-md.yaml"),
+		Generate: darnit.GenerateOptions{
+			MappingsDir:       filepath.Join(testLibrary, ".darn", "library", "mappings"),
+			SkipDefaults:      true,
+			SkipRepoInference: true,
+			ExtraParams:       map[string]interface{}{"name": "Test", "emails": []interface{}{"security@example.com"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, index.Entries, 2)
+	assert.Empty(t, index.Entries[0].Error)
+	assert.Equal(t, []string{"enable-mfa"}, index.Entries[0].Actions)
+	assert.Empty(t, index.Entries[1].Error)
+	assert.Equal(t, []string{"add-security-md"}, index.Entries[1].Actions)
+}
+
+func TestGenerateBatchLoadsDefaultsPerRepository(t *testing.T) {
+	testLibrary := setupTestLibrary(t)
+	t.Setenv("DARN_HOME", testLibrary)
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "mappings.yaml"), `mappings:
+  - id: "mfa"
+    action: "enable-mfa"
+    reason: "Enable MFA"
+    parameters:
+      organization: "{{.organization}}"`)
+	writeTestFile(t, filepath.Join(dir, "report.json"), `{}`)
+	writeTestFile(t, filepath.Join(dir, "api", "params.yaml"), "default_parameters:\n  organization: api-org\n")
+	writeTestFile(t, filepath.Join(dir, "web", ".darn", "params.yaml"), "default_parameters:\n  organization: web-org\n")
+
+	manifestFile := filepath.Join(dir, "batch.yaml")
+	writeTestFile(t, manifestFile, `mappings: mappings.yaml
+repositories:
+  - repo: api
+    report: report.json
+  - repo: web
+    report: report.json`)
+
+	manifest, err := plan.LoadBatchManifest(manifestFile)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(dir, "plans")
+	index, err := plan.GenerateBatch(manifest, plan.BatchOptions{
+		OutputDir: outputDir,
+		Generate: darnit.GenerateOptions{
+			MappingsDir:       filepath.Join(testLibrary, ".darn", "library", "mappings"),
+			SkipRepoInference: true,
+		},
+	})
+	require.NoError(t, err)
+
+	for i, organization := range []string{"api-org", "web-org"} {
+		entry := index.Entries[i]
+		require.Empty(t, entry.Error)
+		generated, err := darnit.LoadPlanFile(filepath.Join(outputDir, entry.PlanFile))
+		require.NoError(t, err)
+		require.Len(t, generated.Steps, 1)
+		assert.Equal(t, organization, generated.Steps[0].Params["organization"], entry.Name)
+	}
+}
+
+func TestGenerateBatchUniqueNames(t *testing.T) {
+	dir := t.TempDir()
+	manifestFile := filepath.Join(dir, "batch.yaml")
+	writeTestFile(t, manifestFile, `repositories:
+  - repo: repos/app
+    report: reports/app.json
+  - name: app
+    repo: repos/other
+    report: reports/other.json
+  - name: app-2
+    repo: repos/third
+    report: reports/third.json
+  - name: App
+    repo: repos/fourth
+    report: reports/fourth.json`)
+
+	manifest, err := plan.LoadBatchManifest(manifestFile)
+	require.NoError(t, err)
+
+	// Entries without a mapping file fail, but still get a name
+	index, err := plan.GenerateBatch(manifest, plan.BatchOptions{OutputDir: filepath.Join(dir, "plans")})
+	require.NoError(t, err)
+	require.Len(t, index.Entries, 4)
+	assert.Equal(t, "app", index.Entries[0].Name)
+	assert.Equal(t, "app-2", index.Entries[1].Name)
+	assert.Equal(t, "app-2-2", index.Entries[2].Name)
+	// Names that differ only in case would share files on case-insensitive file systems
+	assert.Equal(t, "App-3", index.Entries[3].Name)
+}
+
+func TestSummarizeBatchActions(t *testing.T) {
+	index := &models.BatchIndex{
+		Entries: []models.BatchIndexEntry{
+			{Name: "b", Actions: []string{"enable-mfa"}},
+			{Name: "a", Actions: []string{"enable-mfa", "add-security-md"}},
+			{Name: "c", Error: "failed"},
+		},
+	}
+
+	summary := plan.SummarizeBatchActions(index)
+	assert.Len(t, summary, 2)
+	assert.Equal(t, []string{"a", "b"}, summary["enable-mfa"])
+}
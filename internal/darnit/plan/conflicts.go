@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	. "github.com/kusari-oss/darn/internal/darnit"
+)
+
+// stepCandidate is a step added to the plan along with the rule settings used
+// to resolve conflicts between steps
+type stepCandidate struct {
+	stepID         string
+	action         string
+	priority       int
+	exclusiveGroup string
+	conflictsWith  []string
+	order          int      // Position in which the step was added; earlier wins ties
+	onceKeys       []string // The "once" keys the step takes (see processMappingRule)
+	trace          *RuleTrace
+}
+
+// onceDuplicate is a step of a "once" rule whose key an earlier step had
+// already taken. It is kept aside in case that step loses a conflict.
+type onceDuplicate struct {
+	candidate *stepCandidate
+	step      models.RemediationStep
+	onceKey   string
+}
+
+// conflictSet collects the candidates for conflict resolution. A nil
+// conflictSet records nothing, which leaves the plan unresolved.
+type conflictSet struct {
+	candidates []*stepCandidate
+	duplicates []onceDuplicate
+}
+
+// newCandidate returns the candidate for the step a rule adds
+func newCandidate(rule MappingRule, trace *RuleTrace, onceKeys []string) *stepCandidate {
+	return &stepCandidate{
+		stepID:         rule.ID,
+		action:         rule.Action,
+		priority:       rule.Priority,
+		exclusiveGroup: rule.ExclusiveGroup,
+		conflictsWith:  rule.ConflictsWith,
+		onceKeys:       onceKeys,
+		trace:          trace,
+	}
+}
+
+// add records the step a rule added to the plan, which takes onceKeys
+func (c *conflictSet) add(rule MappingRule, trace *RuleTrace, onceKeys []string) {
+	if c == nil {
+		return
+	}
+	candidate := newCandidate(rule, trace, onceKeys)
+	candidate.order = len(c.candidates)
+	c.candidates = append(c.candidates, candidate)
+}
+
+// addDuplicate records the step of a "once" rule that was left out because
+// an earlier step took onceKey
+func (c *conflictSet) addDuplicate(rule MappingRule, trace *RuleTrace, step models.RemediationStep, onceKey string, onceKeys []string) {
+	c.duplicates = append(c.duplicates, onceDuplicate{
+		candidate: newCandidate(rule, trace, onceKeys),
+		step:      step,
+		onceKey:   onceKey,
+	})
+}
+
+// conflictReason returns why two candidates cannot both be in the plan, or ""
+// if they can
+func conflictReason(a, b *stepCandidate) string {
+	if a.exclusiveGroup != "" && a.exclusiveGroup == b.exclusiveGroup {
+		return fmt.Sprintf("exclusive_group %s", a.exclusiveGroup)
+	}
+	for _, id := range a.conflictsWith {
+		if id == b.stepID {
+			return fmt.Sprintf("%s conflicts_with %s", a.stepID, b.stepID)
+		}
+	}
+	for _, id := range b.conflictsWith {
+		if id == a.stepID {
+			return fmt.Sprintf("%s conflicts_with %s", b.stepID, a.stepID)
+		}
+	}
+	return ""
+}
+
+// resolveConflicts removes steps that conflict with a step from a
+// higher-priority rule. Steps are considered by descending priority and then
+// in the order they were added, so the result does not depend on map
+// iteration or worker scheduling. Dependencies on a removed step are moved to
+// the step that replaced it, which is an error when that makes the steps
+// depend on each other in a cycle. The removed steps are recorded in
+// plan.Conflicts.
+//
+// A "once" step left out because an earlier step took its key is added when
+// that step was removed, no kept step took the key and it conflicts with none
+// of the kept steps.
+func resolveConflicts(plan *models.RemediationPlan, set *conflictSet) error {
+	if set == nil || len(set.candidates) == 0 {
+		return nil
+	}
+
+	ordered := make([]*stepCandidate, len(set.candidates))
+	copy(ordered, set.candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].priority != ordered[j].priority {
+			return ordered[i].priority > ordered[j].priority
+		}
+		return ordered[i].order < ordered[j].order
+	})
+
+	var kept []*stepCandidate
+	replacedBy := make(map[string]string)
+	// keep adds candidate unless it conflicts with a kept step
+	keep := func(candidate *stepCandidate) bool {
+		for _, winner := range kept {
+			reason := conflictReason(candidate, winner)
+			if reason == "" {
+				continue
+			}
+
+			plan.Conflicts = append(plan.Conflicts, models.StepConflict{
+				StepID:   candidate.stepID,
+				Action:   candidate.action,
+				Priority: candidate.priority,
+				WinnerID: winner.stepID,
+				Reason:   reason,
+			})
+			replacedBy[candidate.stepID] = winner.stepID
+			candidate.trace.recordConflictLoss(candidate.stepID, winner.stepID, reason)
+			return false
+		}
+		kept = append(kept, candidate)
+		return true
+	}
+	for _, candidate := range ordered {
+		keep(candidate)
+	}
+
+	if len(replacedBy) == 0 {
+		return nil
+	}
+
+	taken := make(map[string]bool)
+	for _, candidate := range kept {
+		for _, key := range candidate.onceKeys {
+			taken[key] = true
+		}
+	}
+	for _, duplicate := range set.duplicates {
+		if taken[duplicate.onceKey] || !keep(duplicate.candidate) {
+			continue
+		}
+		for _, key := range duplicate.candidate.onceKeys {
+			taken[key] = true
+		}
+		plan.Steps = append(plan.Steps, duplicate.step)
+		duplicate.candidate.trace.recordStep(duplicate.step.ID)
+	}
+
+	steps := make([]models.RemediationStep, 0, len(plan.Steps)-len(replacedBy))
+	for _, step := range plan.Steps {
+		if _, removed := replacedBy[step.ID]; removed {
+			continue
+		}
+		step.DependsOn = replaceDependencies(step.ID, step.DependsOn, replacedBy)
+		steps = append(steps, step)
+	}
+	plan.Steps = steps
+
+	if err := DetectCycles(plan.Steps); err != nil {
+		return fmt.Errorf("replacing conflicting steps: %w", err)
+	}
+	return nil
+}
+
+// replaceDependencies moves the dependencies of step stepID on removed steps
+// to their replacements, dropping duplicates. A step that replaced one of
+// its own dependencies no longer depends on it.
+func replaceDependencies(stepID string, dependsOn []string, replacedBy map[string]string) []string {
+	if len(dependsOn) == 0 {
+		return dependsOn
+	}
+
+	seen := make(map[string]bool, len(dependsOn))
+	result := make([]string, 0, len(dependsOn))
+	for _, dep := range dependsOn {
+		if winner, ok := replacedBy[dep]; ok {
+			dep = winner
+		}
+		if dep == stepID {
+			continue
+		}
+		if !seen[dep] {
+			seen[dep] = true
+			result = append(result, dep)
+		}
+	}
+	return result
+}
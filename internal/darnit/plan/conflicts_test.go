@@ -0,0 +1,292 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conflictTestOptions() darnit.GenerateOptions {
+	return darnit.GenerateOptions{
+		SkipDefaults:      true,
+		SkipRepoInference: true,
+		NonInteractive:    true,
+	}
+}
+
+func planStepIDs(p *models.RemediationPlan) []string {
+	ids := make([]string, 0, len(p.Steps))
+	for _, step := range p.Steps {
+		ids = append(ids, step.ID)
+	}
+	return ids
+}
+
+func TestExclusiveGroupKeepsHighestPriority(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "license-mit"
+    exclusive_group: "license"
+    action: "add-security-md"
+    parameters:
+      name: "MIT"
+      emails: ["a@example.com"]
+  - id: "license-apache"
+    exclusive_group: "license"
+    priority: 10
+    action: "add-security-md"
+    parameters:
+      name: "Apache-2.0"
+      emails: ["a@example.com"]
+  - id: "license-bsd"
+    exclusive_group: "license"
+    action: "add-security-md"
+    parameters:
+      name: "BSD"
+      emails: ["a@example.com"]
+  - id: "mfa"
+    action: "enable-mfa"
+    depends_on: ["license-mit"]
+    parameters:
+      organization: "test-org"`)
+
+	report := &darnit.Report{Findings: map[string]any{}}
+	remediationPlan, explanation, err := plan.GenerateRemediationPlanWithExplanation(report, mappingFile, conflictTestOptions())
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"license-apache", "mfa"}, planStepIDs(remediationPlan))
+
+	// Losers are reported in the order they were resolved
+	require.Len(t, remediationPlan.Conflicts, 2)
+	assert.Equal(t, models.StepConflict{StepID: "license-mit", Action: "add-security-md",
+		WinnerID: "license-apache", Reason: "exclusive_group license"}, remediationPlan.Conflicts[0])
+	assert.Equal(t, "license-bsd", remediationPlan.Conflicts[1].StepID)
+
+	// Dependencies on a dropped step move to the step that replaced it
+	for _, step := range remediationPlan.Steps {
+		if step.ID == "mfa" {
+			assert.Equal(t, []string{"license-apache"}, step.DependsOn)
+		}
+	}
+
+	assert.Equal(t, plan.OutcomeConflictLost, explanation.Rules[0].Outcome)
+	assert.Equal(t, plan.OutcomeAdded, explanation.Rules[1].Outcome)
+
+	var tree bytes.Buffer
+	require.NoError(t, plan.WriteExplanation(&tree, explanation, plan.ExplainFormatTree))
+	assert.Contains(t, tree.String(), "= license-mit: step license-mit dropped, kept license-apache instead (exclusive_group license)")
+}
+
+func TestConflictsWithTieKeepsFirstRule(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "docs"
+    action: "add-security-md"
+    parameters:
+      name: "Docs"
+      emails: ["a@example.com"]
+  - id: "mfa"
+    action: "enable-mfa"
+    conflicts_with: ["docs"]
+    parameters:
+      organization: "test-org"`)
+
+	remediationPlan, err := plan.GenerateRemediationPlan(&darnit.Report{Findings: map[string]any{}}, mappingFile, conflictTestOptions())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"docs"}, planStepIDs(remediationPlan))
+	require.Len(t, remediationPlan.Conflicts, 1)
+	assert.Equal(t, "mfa", remediationPlan.Conflicts[0].StepID)
+	assert.Equal(t, "mfa conflicts_with docs", remediationPlan.Conflicts[0].Reason)
+}
+
+func TestOnceKey(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "mfa"
+    for_each: "orgs"
+    once_key: "item"
+    action: "enable-mfa"
+    parameters:
+      organization: "{{.item}}"`)
+
+	report := &darnit.Report{Findings: map[string]any{"orgs": []any{"acme", "tools", "acme"}}}
+	remediationPlan, explanation, err := plan.GenerateRemediationPlanWithExplanation(report, mappingFile, conflictTestOptions())
+	require.NoError(t, err)
+
+	// The action repeats, only the duplicate organization is skipped
+	assert.Equal(t, []string{"mfa-0", "mfa-1"}, planStepIDs(remediationPlan))
+
+	children := explanation.Rules[0].Children
+	require.Len(t, children, 3)
+	assert.Equal(t, plan.OutcomeOnceSkipped, children[2].Outcome)
+	assert.Equal(t, `once_key "acme" is already in the plan`, children[2].Detail)
+}
+
+func TestOnceRuleReplacesStepThatLostConflict(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "docs"
+    once: true
+    exclusive_group: "policy"
+    action: "add-security-md"
+    parameters:
+      name: "Docs"
+      emails: ["a@example.com"]
+  - id: "mfa"
+    exclusive_group: "policy"
+    priority: 10
+    action: "enable-mfa"
+    parameters:
+      organization: "test-org"
+  - id: "docs-again"
+    once: true
+    action: "add-security-md"
+    depends_on: ["mfa"]
+    parameters:
+      name: "Docs again"
+      emails: ["a@example.com"]
+  - id: "docs-third"
+    once: true
+    action: "add-security-md"
+    parameters:
+      name: "Docs third"
+      emails: ["a@example.com"]`)
+
+	report := &darnit.Report{Findings: map[string]any{}}
+	remediationPlan, explanation, err := plan.GenerateRemediationPlanWithExplanation(report, mappingFile, conflictTestOptions())
+	require.NoError(t, err)
+
+	// The first instance lost its conflict, so the next one takes its place
+	assert.Equal(t, []string{"mfa", "docs-again"}, planStepIDs(remediationPlan))
+	require.Len(t, remediationPlan.Conflicts, 1)
+	assert.Equal(t, "docs", remediationPlan.Conflicts[0].StepID)
+
+	require.Len(t, explanation.Rules, 4)
+	assert.Equal(t, plan.OutcomeConflictLost, explanation.Rules[0].Outcome)
+	assert.Equal(t, plan.OutcomeAdded, explanation.Rules[2].Outcome)
+	assert.Equal(t, plan.OutcomeOnceSkipped, explanation.Rules[3].Outcome)
+}
+
+func TestOnceRuleThatLosesConflictIsExplained(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "docs"
+    once: true
+    exclusive_group: "policy"
+    action: "add-security-md"
+    parameters:
+      name: "Docs"
+      emails: ["a@example.com"]
+  - id: "mfa"
+    exclusive_group: "policy"
+    priority: 10
+    action: "enable-mfa"
+    parameters:
+      organization: "test-org"
+  - id: "docs-again"
+    once: true
+    exclusive_group: "policy"
+    action: "add-security-md"
+    parameters:
+      name: "Docs again"
+      emails: ["a@example.com"]`)
+
+	report := &darnit.Report{Findings: map[string]any{}}
+	remediationPlan, explanation, err := plan.GenerateRemediationPlanWithExplanation(report, mappingFile, conflictTestOptions())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mfa"}, planStepIDs(remediationPlan))
+
+	// The duplicate was reconsidered for the plan and lost the same conflict
+	require.Len(t, explanation.Rules, 3)
+	assert.Equal(t, plan.OutcomeConflictLost, explanation.Rules[2].Outcome)
+	assert.Equal(t, "docs-again", explanation.Rules[2].StepID)
+
+	var tree bytes.Buffer
+	require.NoError(t, plan.WriteExplanation(&tree, explanation, plan.ExplainFormatTree))
+	assert.Contains(t, tree.String(), "docs-again: step docs-again dropped, kept mfa instead (exclusive_group policy)")
+}
+
+func TestConflictsWithInReferencedMapping(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingsDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(mappingsDir, "policy.yaml"), []byte(`mappings:
+  - id: "policy"
+    steps:
+      - id: "docs"
+        action: "add-security-md"
+        parameters:
+          name: "Docs"
+          emails: ["a@example.com"]
+      - id: "mfa"
+        action: "enable-mfa"
+        priority: 5
+        conflicts_with: ["docs"]
+        parameters:
+          organization: "test-org"
+  - id: "scan"
+    action: "enable-mfa"
+    conflicts_with: ["mfa"]
+    parameters:
+      organization: "other-org"`), 0644))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "protect"
+    mapping_ref: "policy.yaml"`)
+
+	options := conflictTestOptions()
+	options.MappingsDir = mappingsDir
+	remediationPlan, err := plan.GenerateRemediationPlan(&darnit.Report{Findings: map[string]any{}}, mappingFile, options)
+	require.NoError(t, err)
+
+	// mfa replaced docs, the step it followed, so it no longer depends on it
+	require.Equal(t, []string{"protect-mfa"}, planStepIDs(remediationPlan))
+	assert.Empty(t, remediationPlan.Steps[0].DependsOn)
+
+	require.Len(t, remediationPlan.Conflicts, 2)
+	assert.Equal(t, "protect-mfa conflicts_with protect-docs", remediationPlan.Conflicts[0].Reason)
+	assert.Equal(t, "protect-scan conflicts_with protect-mfa", remediationPlan.Conflicts[1].Reason)
+}
+
+func TestReplacingConflictingStepRejectsCycles(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	// Moving setup's dependency on legacy to modern makes modern and setup depend on each other
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "modern"
+    exclusive_group: "mfa"
+    priority: 10
+    action: "enable-mfa"
+    depends_on: ["setup"]
+    parameters:
+      organization: "test-org"
+  - id: "legacy"
+    exclusive_group: "mfa"
+    action: "enable-mfa"
+    parameters:
+      organization: "test-org"
+  - id: "setup"
+    action: "add-security-md"
+    depends_on: ["legacy"]
+    parameters:
+      name: "Setup"
+      emails: ["a@example.com"]`)
+
+	_, err := plan.GenerateRemediationPlan(&darnit.Report{Findings: map[string]any{}}, mappingFile, conflictTestOptions())
+	assert.ErrorContains(t, err, "replacing conflicting steps: circular dependency detected")
+}
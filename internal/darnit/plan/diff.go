@@ -0,0 +1,391 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+)
+
+// Supported plan diff output formats
+const (
+	DiffFormatText     = "text"
+	DiffFormatJSON     = "json"
+	DiffFormatMarkdown = "markdown"
+)
+
+// ValueChange records the old and new value of a field
+type ValueChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// ParamChange records a change to a single step parameter
+type ParamChange struct {
+	Name string      `json:"name"`
+	Kind string      `json:"kind"` // added, removed or changed
+	Old  interface{} `json:"old"`  // nil when added
+	New  interface{} `json:"new"`  // nil when removed
+}
+
+// DependencyChange records dependencies added to or removed from a step
+type DependencyChange struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// StepDiff describes how a step present in both plans changed
+type StepDiff struct {
+	ID        string            `json:"id"`
+	Action    *ValueChange      `json:"action,omitempty"`
+	Reason    *ValueChange      `json:"reason,omitempty"`
+	Params    []ParamChange     `json:"params,omitempty"`
+	DependsOn *DependencyChange `json:"depends_on,omitempty"`
+}
+
+// PlanDiff describes the differences between two remediation plans
+type PlanDiff struct {
+	ProjectName *ValueChange             `json:"project_name,omitempty"`
+	Repository  *ValueChange             `json:"repository,omitempty"`
+	Added       []models.RemediationStep `json:"added,omitempty"`
+	Removed     []models.RemediationStep `json:"removed,omitempty"`
+	Changed     []StepDiff               `json:"changed,omitempty"`
+}
+
+// HasChanges reports whether the two plans differ
+func (d *PlanDiff) HasChanges() bool {
+	return d.ProjectName != nil || d.Repository != nil ||
+		len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// DiffPlans compares two plans step by step, matching steps by ID. Added and
+// changed steps are listed in the order of the new plan, removed steps in the
+// order of the old plan. Execution state (status, error, outputs) is ignored.
+func DiffPlans(oldPlan, newPlan *models.RemediationPlan) *PlanDiff {
+	diff := &PlanDiff{}
+
+	if oldPlan.ProjectName != newPlan.ProjectName {
+		diff.ProjectName = &ValueChange{Old: oldPlan.ProjectName, New: newPlan.ProjectName}
+	}
+	if oldPlan.Repository != newPlan.Repository {
+		diff.Repository = &ValueChange{Old: oldPlan.Repository, New: newPlan.Repository}
+	}
+
+	oldSteps := make(map[string]models.RemediationStep, len(oldPlan.Steps))
+	for _, step := range oldPlan.Steps {
+		oldSteps[step.ID] = step
+	}
+	newSteps := make(map[string]bool, len(newPlan.Steps))
+
+	for _, newStep := range newPlan.Steps {
+		newSteps[newStep.ID] = true
+
+		oldStep, exists := oldSteps[newStep.ID]
+		if !exists {
+			diff.Added = append(diff.Added, newStep)
+			continue
+		}
+
+		if stepDiff := diffSteps(oldStep, newStep); stepDiff != nil {
+			diff.Changed = append(diff.Changed, *stepDiff)
+		}
+	}
+
+	for _, oldStep := range oldPlan.Steps {
+		if !newSteps[oldStep.ID] {
+			diff.Removed = append(diff.Removed, oldStep)
+		}
+	}
+
+	return diff
+}
+
+// diffSteps compares two versions of the same step, returning nil if they match
+func diffSteps(oldStep, newStep models.RemediationStep) *StepDiff {
+	stepDiff := &StepDiff{ID: newStep.ID}
+	changed := false
+
+	if oldStep.ActionName != newStep.ActionName {
+		stepDiff.Action = &ValueChange{Old: oldStep.ActionName, New: newStep.ActionName}
+		changed = true
+	}
+
+	if oldStep.Reason != newStep.Reason {
+		stepDiff.Reason = &ValueChange{Old: oldStep.Reason, New: newStep.Reason}
+		changed = true
+	}
+
+	if params := diffParams(oldStep.Params, newStep.Params); len(params) > 0 {
+		stepDiff.Params = params
+		changed = true
+	}
+
+	if deps := diffDependencies(oldStep.DependsOn, newStep.DependsOn); deps != nil {
+		stepDiff.DependsOn = deps
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return stepDiff
+}
+
+// diffParams compares step parameters by name
+func diffParams(oldParams, newParams map[string]interface{}) []ParamChange {
+	names := make(map[string]bool)
+	for name := range oldParams {
+		names[name] = true
+	}
+	for name := range newParams {
+		names[name] = true
+	}
+
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	var changes []ParamChange
+	for _, name := range sortedNames {
+		oldValue, inOld := oldParams[name]
+		newValue, inNew := newParams[name]
+
+		switch {
+		case !inOld:
+			changes = append(changes, ParamChange{Name: name, Kind: "added", New: newValue})
+		case !inNew:
+			changes = append(changes, ParamChange{Name: name, Kind: "removed", Old: oldValue})
+		case !paramValuesEqual(oldValue, newValue):
+			changes = append(changes, ParamChange{Name: name, Kind: "changed", Old: oldValue, New: newValue})
+		}
+	}
+
+	return changes
+}
+
+// paramValuesEqual compares parameter values after normalizing them through
+// JSON, so plans loaded from YAML and JSON compare equal (e.g. int vs float64)
+func paramValuesEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeValue(a), normalizeValue(b))
+}
+
+// normalizeValue round-trips a value through JSON
+func normalizeValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// diffDependencies compares dependency lists, ignoring order
+func diffDependencies(oldDeps, newDeps []string) *DependencyChange {
+	oldSet := make(map[string]bool, len(oldDeps))
+	for _, dep := range oldDeps {
+		oldSet[dep] = true
+	}
+	newSet := make(map[string]bool, len(newDeps))
+	for _, dep := range newDeps {
+		newSet[dep] = true
+	}
+
+	change := &DependencyChange{}
+	for _, dep := range newDeps {
+		if !oldSet[dep] {
+			change.Added = append(change.Added, dep)
+		}
+	}
+	for _, dep := range oldDeps {
+		if !newSet[dep] {
+			change.Removed = append(change.Removed, dep)
+		}
+	}
+
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil
+	}
+	return change
+}
+
+// WritePlanDiff writes a plan diff in the given format (text, json or markdown)
+func WritePlanDiff(w io.Writer, diff *PlanDiff, format string) error {
+	switch format {
+	case DiffFormatText, "":
+		return writePlanDiffText(w, diff)
+	case DiffFormatJSON:
+		data, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling plan diff: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case DiffFormatMarkdown:
+		return writePlanDiffMarkdown(w, diff)
+	default:
+		return fmt.Errorf("unsupported diff format: %s (expected text, json or markdown)", format)
+	}
+}
+
+// writePlanDiffText writes a human-readable diff
+func writePlanDiffText(w io.Writer, diff *PlanDiff) error {
+	if !diff.HasChanges() {
+		_, err := io.WriteString(w, "Plans are identical\n")
+		return err
+	}
+
+	var b strings.Builder
+
+	if diff.ProjectName != nil {
+		fmt.Fprintf(&b, "Project name: %s -> %s\n", diff.ProjectName.Old, diff.ProjectName.New)
+	}
+	if diff.Repository != nil {
+		fmt.Fprintf(&b, "Repository: %s -> %s\n", diff.Repository.Old, diff.Repository.New)
+	}
+
+	for _, step := range diff.Added {
+		fmt.Fprintf(&b, "+ %s (%s)\n", step.ID, step.ActionName)
+	}
+	for _, step := range diff.Removed {
+		fmt.Fprintf(&b, "- %s (%s)\n", step.ID, step.ActionName)
+	}
+	for _, step := range diff.Changed {
+		fmt.Fprintf(&b, "~ %s\n", step.ID)
+		if step.Action != nil {
+			fmt.Fprintf(&b, "    action: %s -> %s\n", step.Action.Old, step.Action.New)
+		}
+		if step.Reason != nil {
+			fmt.Fprintf(&b, "    reason: %q -> %q\n", step.Reason.Old, step.Reason.New)
+		}
+		for _, param := range step.Params {
+			switch param.Kind {
+			case "added":
+				fmt.Fprintf(&b, "    + param %s: %s\n", param.Name, formatDiffValue(param.New))
+			case "removed":
+				fmt.Fprintf(&b, "    - param %s: %s\n", param.Name, formatDiffValue(param.Old))
+			default:
+				fmt.Fprintf(&b, "    ~ param %s: %s -> %s\n", param.Name, formatDiffValue(param.Old), formatDiffValue(param.New))
+			}
+		}
+		if step.DependsOn != nil {
+			for _, dep := range step.DependsOn.Added {
+				fmt.Fprintf(&b, "    + depends on %s\n", dep)
+			}
+			for _, dep := range step.DependsOn.Removed {
+				fmt.Fprintf(&b, "    - depends on %s\n", dep)
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%d added, %d removed, %d changed\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writePlanDiffMarkdown writes a diff suitable for pull request comments
+func writePlanDiffMarkdown(w io.Writer, diff *PlanDiff) error {
+	var b strings.Builder
+
+	b.WriteString("## Remediation plan changes\n\n")
+	if !diff.HasChanges() {
+		b.WriteString("No changes.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	fmt.Fprintf(&b, "**%d added, %d removed, %d changed**\n\n", len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+	if diff.ProjectName != nil {
+		fmt.Fprintf(&b, "- Project name: `%s` → `%s`\n", diff.ProjectName.Old, diff.ProjectName.New)
+	}
+	if diff.Repository != nil {
+		fmt.Fprintf(&b, "- Repository: `%s` → `%s`\n", diff.Repository.Old, diff.Repository.New)
+	}
+	if diff.ProjectName != nil || diff.Repository != nil {
+		b.WriteString("\n")
+	}
+
+	if len(diff.Added) > 0 {
+		b.WriteString("### Added steps\n\n| Step | Action | Reason |\n| --- | --- | --- |\n")
+		for _, step := range diff.Added {
+			fmt.Fprintf(&b, "| `%s` | `%s` | %s |\n", step.ID, step.ActionName, escapeMarkdownCell(step.Reason))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(diff.Removed) > 0 {
+		b.WriteString("### Removed steps\n\n| Step | Action | Reason |\n| --- | --- | --- |\n")
+		for _, step := range diff.Removed {
+			fmt.Fprintf(&b, "| `%s` | `%s` | %s |\n", step.ID, step.ActionName, escapeMarkdownCell(step.Reason))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(diff.Changed) > 0 {
+		b.WriteString("### Changed steps\n\n| Step | Field | Old | New |\n| --- | --- | --- | --- |\n")
+		for _, step := range diff.Changed {
+			if step.Action != nil {
+				fmt.Fprintf(&b, "| `%s` | action | `%s` | `%s` |\n", step.ID, step.Action.Old, step.Action.New)
+			}
+			if step.Reason != nil {
+				fmt.Fprintf(&b, "| `%s` | reason | %s | %s |\n", step.ID,
+					escapeMarkdownCell(fmt.Sprint(step.Reason.Old)), escapeMarkdownCell(fmt.Sprint(step.Reason.New)))
+			}
+			for _, param := range step.Params {
+				fmt.Fprintf(&b, "| `%s` | param `%s` | %s | %s |\n", step.ID, param.Name,
+					markdownDiffValue(param.Kind != "added", param.Old), markdownDiffValue(param.Kind != "removed", param.New))
+			}
+			if step.DependsOn != nil {
+				fmt.Fprintf(&b, "| `%s` | depends_on | %s | %s |\n", step.ID,
+					markdownList(step.DependsOn.Removed, "removed"), markdownList(step.DependsOn.Added, "added"))
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatDiffValue renders a parameter value compactly as JSON
+func formatDiffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// markdownDiffValue renders a parameter value for a markdown table cell
+func markdownDiffValue(present bool, v interface{}) string {
+	if !present {
+		return "_(none)_"
+	}
+	return "`" + escapeMarkdownCell(formatDiffValue(v)) + "`"
+}
+
+// markdownList renders dependency changes for a markdown table cell
+func markdownList(items []string, label string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	return label + ": `" + strings.Join(items, "`, `") + "`"
+}
+
+// escapeMarkdownCell keeps a value from breaking a markdown table row
+func escapeMarkdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
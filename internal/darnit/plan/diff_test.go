@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func diffTestPlans() (*models.RemediationPlan, *models.RemediationPlan) {
+	oldPlan := &models.RemediationPlan{
+		ProjectName: "test-project",
+		Repository:  "test-repo",
+		Steps: []models.RemediationStep{
+			{ID: "security-md", ActionName: "add-security-md", Reason: "Add security documentation",
+				Params: map[string]interface{}{"name": "Test", "emails": []interface{}{"a@example.com"}, "count": 1}},
+			{ID: "mfa", ActionName: "enable-mfa", Reason: "Enable MFA",
+				Params: map[string]interface{}{"organization": "test-org"}, DependsOn: []string{"security-md"}},
+			{ID: "old-step", ActionName: "remove-me", Params: map[string]interface{}{}},
+		},
+	}
+
+	newPlan := &models.RemediationPlan{
+		ProjectName: "test-project",
+		Repository:  "test-repo",
+		Steps: []models.RemediationStep{
+			{ID: "security-md", ActionName: "add-security-md", Reason: "Add SECURITY.md",
+				Params: map[string]interface{}{"name": "Test", "emails": []interface{}{"b@example.com"}, "count": 1.0, "force": true}},
+			{ID: "mfa", ActionName: "enable-mfa", Reason: "Enable MFA",
+				Params: map[string]interface{}{"organization": "test-org"}, DependsOn: []string{"branch-protection"}},
+			{ID: "branch-protection", ActionName: "protect-branch", Reason: "Protect main",
+				Params: map[string]interface{}{"branch": "main"}},
+		},
+	}
+
+	return oldPlan, newPlan
+}
+
+func TestDiffPlans(t *testing.T) {
+	oldPlan, newPlan := diffTestPlans()
+
+	diff := plan.DiffPlans(oldPlan, newPlan)
+	require.True(t, diff.HasChanges())
+	assert.Nil(t, diff.ProjectName)
+	assert.Nil(t, diff.Repository)
+
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, "branch-protection", diff.Added[0].ID)
+
+	require.Len(t, diff.Removed, 1)
+	assert.Equal(t, "old-step", diff.Removed[0].ID)
+
+	require.Len(t, diff.Changed, 2)
+
+	securityMD := diff.Changed[0]
+	assert.Equal(t, "security-md", securityMD.ID)
+	assert.Nil(t, securityMD.Action)
+	require.NotNil(t, securityMD.Reason)
+	assert.Equal(t, "Add security documentation", securityMD.Reason.Old)
+	assert.Equal(t, "Add SECURITY.md", securityMD.Reason.New)
+	// count changes only in numeric type, so it is not reported
+	require.Len(t, securityMD.Params, 2)
+	assert.Equal(t, plan.ParamChange{Name: "emails", Kind: "changed",
+		Old: []interface{}{"a@example.com"}, New: []interface{}{"b@example.com"}}, securityMD.Params[0])
+	assert.Equal(t, plan.ParamChange{Name: "force", Kind: "added", New: true}, securityMD.Params[1])
+	assert.Nil(t, securityMD.DependsOn)
+
+	mfa := diff.Changed[1]
+	assert.Equal(t, "mfa", mfa.ID)
+	assert.Empty(t, mfa.Params)
+	require.NotNil(t, mfa.DependsOn)
+	assert.Equal(t, []string{"branch-protection"}, mfa.DependsOn.Added)
+	assert.Equal(t, []string{"security-md"}, mfa.DependsOn.Removed)
+}
+
+func TestDiffPlansIdentical(t *testing.T) {
+	oldPlan, _ := diffTestPlans()
+	newPlan, _ := diffTestPlans()
+
+	// Execution state does not count as a change
+	newPlan.Steps[0].Status = "success"
+	newPlan.Steps[1].DependsOn = []string{"security-md"}
+
+	diff := plan.DiffPlans(oldPlan, newPlan)
+	assert.False(t, diff.HasChanges())
+
+	var out bytes.Buffer
+	require.NoError(t, plan.WritePlanDiff(&out, diff, plan.DiffFormatText))
+	assert.Equal(t, "Plans are identical\n", out.String())
+}
+
+func TestWritePlanDiffFormats(t *testing.T) {
+	oldPlan, newPlan := diffTestPlans()
+	newPlan.Repository = "other-repo"
+	diff := plan.DiffPlans(oldPlan, newPlan)
+
+	var text bytes.Buffer
+	require.NoError(t, plan.WritePlanDiff(&text, diff, plan.DiffFormatText))
+	assert.Contains(t, text.String(), "Repository: test-repo -> other-repo")
+	assert.Contains(t, text.String(), "+ branch-protection (protect-branch)")
+	assert.Contains(t, text.String(), "- old-step (remove-me)")
+	assert.Contains(t, text.String(), `~ param emails: ["a@example.com"] -> ["b@example.com"]`)
+	assert.Contains(t, text.String(), "+ depends on branch-protection")
+	assert.Contains(t, text.String(), "1 added, 1 removed, 2 changed")
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, plan.WritePlanDiff(&jsonOut, diff, plan.DiffFormatJSON))
+	var decoded plan.PlanDiff
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Len(t, decoded.Added, 1)
+	assert.Len(t, decoded.Removed, 1)
+	assert.Len(t, decoded.Changed, 2)
+
+	var markdown bytes.Buffer
+	require.NoError(t, plan.WritePlanDiff(&markdown, diff, plan.DiffFormatMarkdown))
+	assert.Contains(t, markdown.String(), "### Added steps")
+	assert.Contains(t, markdown.String(), "| `branch-protection` | `protect-branch` | Protect main |")
+	assert.Contains(t, markdown.String(), "| `security-md` | param `force` | _(none)_ | `true` |")
+
+	assert.Error(t, plan.WritePlanDiff(&bytes.Buffer{}, diff, "xml"))
+}
+
+func TestWritePlanDiffJSONKeepsEmptyValues(t *testing.T) {
+	oldPlan := &models.RemediationPlan{Steps: []models.RemediationStep{
+		{ID: "docs", ActionName: "add-docs", Params: map[string]interface{}{"owner": "alice", "retries": 3, "note": nil}},
+	}}
+	newPlan := &models.RemediationPlan{Steps: []models.RemediationStep{
+		{ID: "docs", ActionName: "add-docs", Params: map[string]interface{}{"owner": "", "retries": 0, "note": "x", "extra": nil}},
+	}}
+
+	var out bytes.Buffer
+	require.NoError(t, plan.WritePlanDiff(&out, plan.DiffPlans(oldPlan, newPlan), plan.DiffFormatJSON))
+
+	var decoded struct {
+		Changed []struct {
+			Params []map[string]interface{} `json:"params"`
+		} `json:"changed"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded.Changed, 1)
+
+	params := make(map[string]map[string]interface{})
+	for _, param := range decoded.Changed[0].Params {
+		params[param["name"].(string)] = param
+	}
+	require.Len(t, params, 4)
+
+	// Both sides are present even when they are empty
+	for name, param := range params {
+		assert.Contains(t, param, "old", name)
+		assert.Contains(t, param, "new", name)
+	}
+	assert.Equal(t, "", params["owner"]["new"])
+	assert.Equal(t, float64(0), params["retries"]["new"])
+	assert.Nil(t, params["note"]["old"])
+	assert.Equal(t, "changed", params["note"]["kind"])
+	assert.Equal(t, "added", params["extra"]["kind"])
+}
@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/darnit/condition"
+)
+
+// Supported explanation output formats
+const (
+	ExplainFormatTree = "tree"
+	ExplainFormatJSON = "json"
+)
+
+// Rule outcomes recorded in a RuleTrace
+const (
+	OutcomeAdded          = "added"           // The rule produced a plan step
+	OutcomeConditionFalse = "condition-false" // The rule's condition did not match
+	OutcomeOnceSkipped    = "once-skipped"    // A "once" rule whose action (or once_key) was already in the plan
+	OutcomeExpanded       = "expanded"        // The rule expanded into child rules (steps or mapping_ref)
+	OutcomeError          = "error"           // Processing the rule failed
+	OutcomeConflictLost   = "conflict-lost"   // The rule's step lost to a higher-priority conflicting step
+)
+
+// PlanExplanation is the decision trace for every rule in a mapping file
+type PlanExplanation struct {
+	MappingFile string       `json:"mapping_file"`
+	Rules       []*RuleTrace `json:"rules"`
+}
+
+// RuleTrace records how a single mapping rule was evaluated
+type RuleTrace struct {
+	RuleID           string                 `json:"rule_id"`
+	Condition        string                 `json:"condition,omitempty"`
+	Variables        map[string]interface{} `json:"variables,omitempty"`         // Values of the variables the condition references
+	MissingVariables []string               `json:"missing_variables,omitempty"` // Referenced variables with no value
+	Matched          bool                   `json:"matched"`
+	Action           string                 `json:"action,omitempty"`
+	MappingRef       string                 `json:"mapping_ref,omitempty"`
+	Outcome          string                 `json:"outcome"`
+	Detail           string                 `json:"detail,omitempty"`
+	StepID           string                 `json:"step_id,omitempty"` // ID of the step added to the plan
+	Children         []*RuleTrace           `json:"children,omitempty"`
+}
+
+// newRuleTrace starts the trace for a top-level rule. It returns nil when no
+// explanation is being collected; every RuleTrace method accepts a nil receiver.
+func (e *PlanExplanation) newRuleTrace() *RuleTrace {
+	if e == nil {
+		return nil
+	}
+	trace := &RuleTrace{}
+	e.Rules = append(e.Rules, trace)
+	return trace
+}
+
+// child starts the trace for a rule expanded from this one
+func (t *RuleTrace) child() *RuleTrace {
+	if t == nil {
+		return nil
+	}
+	trace := &RuleTrace{}
+	t.Children = append(t.Children, trace)
+	return trace
+}
+
+// recordRule records the rule and the values of the variables its condition references
+func (t *RuleTrace) recordRule(rule MappingRule, data map[string]interface{}) {
+	if t == nil {
+		return
+	}
+
+	t.RuleID = rule.ID
+	t.Condition = rule.Condition
+	t.Action = rule.Action
+	t.MappingRef = rule.MappingRef
+
+	if rule.Condition == "" {
+		return
+	}
+
+	evaluator, err := condition.NewCELEvaluator()
+	if err != nil {
+		return
+	}
+	names, err := evaluator.ReferencedVariables(rule.Condition)
+	if err != nil {
+		return
+	}
+
+	for _, name := range names {
+		value, ok := data[name]
+		if !ok {
+			t.MissingVariables = append(t.MissingVariables, name)
+			continue
+		}
+		if t.Variables == nil {
+			t.Variables = make(map[string]interface{})
+		}
+		t.Variables[name] = value
+	}
+}
+
+// recordMatch records the result of the rule's condition
+func (t *RuleTrace) recordMatch(matched bool) {
+	if t == nil {
+		return
+	}
+	t.Matched = matched
+	if !matched {
+		t.Outcome = OutcomeConditionFalse
+	}
+}
+
+// recordExpansion records that the rule expanded into child rules
+func (t *RuleTrace) recordExpansion(detail string) {
+	if t == nil {
+		return
+	}
+	t.Outcome = OutcomeExpanded
+	t.Detail = detail
+}
+
+// recordOnceSkip records that a "once" rule was deduplicated on its action or once_key value
+func (t *RuleTrace) recordOnceSkip(onceKey string) {
+	if t == nil {
+		return
+	}
+	t.Outcome = OutcomeOnceSkipped
+	if onceKey != "" {
+		t.Detail = fmt.Sprintf("once_key %q is already in the plan", onceKey)
+		return
+	}
+	t.Detail = fmt.Sprintf("action %s is already in the plan", t.Action)
+}
+
+// recordStep records the step the rule added to the plan
+func (t *RuleTrace) recordStep(stepID string) {
+	if t == nil {
+		return
+	}
+	t.Outcome = OutcomeAdded
+	t.StepID = stepID
+}
+
+// recordConflictLoss records that the rule's step stepID was removed, or
+// left out, in favor of winnerID
+func (t *RuleTrace) recordConflictLoss(stepID, winnerID, reason string) {
+	if t == nil {
+		return
+	}
+	t.Outcome = OutcomeConflictLost
+	t.StepID = stepID
+	t.Detail = fmt.Sprintf("kept %s instead (%s)", winnerID, reason)
+}
+
+// fail records an error while processing the rule
+func (t *RuleTrace) fail(err error) {
+	if t == nil {
+		return
+	}
+	t.Outcome = OutcomeError
+	t.Detail = err.Error()
+}
+
+// ValidateExplainFormat returns an error unless format is a format that
+// WriteExplanation supports
+func ValidateExplainFormat(format string) error {
+	switch format {
+	case ExplainFormatTree, ExplainFormatJSON, "":
+		return nil
+	default:
+		return fmt.Errorf("unsupported explain format: %s (expected tree or json)", format)
+	}
+}
+
+// WriteExplanation writes a plan explanation as a tree or as JSON
+func WriteExplanation(w io.Writer, explanation *PlanExplanation, format string) error {
+	if err := ValidateExplainFormat(format); err != nil {
+		return err
+	}
+	switch format {
+	case ExplainFormatTree, "":
+		var b strings.Builder
+		fmt.Fprintf(&b, "Rules from %s:\n", explanation.MappingFile)
+		for _, trace := range explanation.Rules {
+			writeRuleTree(&b, trace, "")
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	case ExplainFormatJSON:
+		data, err := json.MarshalIndent(explanation, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling explanation: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+	return nil
+}
+
+// writeRuleTree writes one rule and its children, indented by prefix
+func writeRuleTree(b *strings.Builder, trace *RuleTrace, prefix string) {
+	marker := "✗"
+	switch trace.Outcome {
+	case OutcomeAdded:
+		marker = "✓"
+	case OutcomeExpanded:
+		marker = "↳"
+	case OutcomeOnceSkipped, OutcomeConflictLost:
+		marker = "="
+	case OutcomeError:
+		marker = "!"
+	}
+
+	fmt.Fprintf(b, "%s%s %s: %s\n", prefix, marker, trace.RuleID, describeOutcome(trace))
+
+	detailPrefix := prefix + "    "
+	if trace.Condition != "" {
+		fmt.Fprintf(b, "%scondition: %s => %t\n", detailPrefix, trace.Condition, trace.Matched)
+		for _, name := range sortedKeys(trace.Variables) {
+			fmt.Fprintf(b, "%s  %s = %s\n", detailPrefix, name, formatDiffValue(trace.Variables[name]))
+		}
+		for _, name := range trace.MissingVariables {
+			fmt.Fprintf(b, "%s  %s is not set\n", detailPrefix, name)
+		}
+	}
+
+	for _, child := range trace.Children {
+		writeRuleTree(b, child, prefix+"  ")
+	}
+}
+
+// describeOutcome summarizes a rule's outcome on one line
+func describeOutcome(trace *RuleTrace) string {
+	switch trace.Outcome {
+	case OutcomeAdded:
+		return fmt.Sprintf("added step %s (action %s)", trace.StepID, trace.Action)
+	case OutcomeConditionFalse:
+		return "skipped, condition is false"
+	case OutcomeOnceSkipped:
+		return "skipped (once), " + trace.Detail
+	case OutcomeExpanded:
+		return "expanded " + trace.Detail
+	case OutcomeConflictLost:
+		return fmt.Sprintf("step %s dropped, %s", trace.StepID, trace.Detail)
+	case OutcomeError:
+		return "error: " + trace.Detail
+	default:
+		return trace.Outcome
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
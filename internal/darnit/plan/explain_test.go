@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRemediationPlanWithExplanation(t *testing.T) {
+	testLibrary := setupTestLibrary(t)
+	t.Setenv("DARN_HOME", testLibrary)
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "security-policy"
+    condition: "security_policy == 'missing'"
+    action: "add-security-md"
+    reason: "Add security documentation"
+    parameters:
+      name: "{{.project_name}}"
+      emails: ["{{.security_email}}"]
+  - id: "mfa"
+    condition: "mfa_status == 'disabled' && org_size > 10"
+    action: "enable-mfa"
+    reason: "Enable MFA"
+    parameters:
+      organization: "{{.organization}}"
+  - id: "security-policy-again"
+    action: "add-security-md"
+    once: true
+    reason: "Add security documentation"
+    parameters:
+      name: "{{.project_name}}"
+      emails: ["{{.security_email}}"]
+  - id: "with-ref"
+    condition: "branch_protection == 'partial'"
+    mapping_ref: "sub-mapping.yaml"
+    reason: "Apply additional protections"
+    parameters:
+      project_name: "{{.project_name}}"
+      email: "{{.security_email}}"`)
+
+	report := &darnit.Report{
+		Findings: map[string]any{
+			"security_policy":   "missing",
+			"mfa_status":        "disabled",
+			"org_size":          5,
+			"branch_protection": "partial",
+		},
+	}
+
+	options := darnit.GenerateOptions{
+		MappingsDir: filepath.Join(testLibrary, ".darn", "library", "mappings"),
+		ExtraParams: map[string]any{
+			"project_name":   "Test Project",
+			"organization":   "test-org",
+			"security_email": "security@example.com",
+			"email":          "security@example.com",
+		},
+		SkipDefaults:      true,
+		SkipRepoInference: true,
+		NonInteractive:    true,
+	}
+
+	remediationPlan, explanation, err := plan.GenerateRemediationPlanWithExplanation(report, mappingFile, options)
+	require.NoError(t, err)
+	require.NotNil(t, remediationPlan)
+	require.Len(t, explanation.Rules, 4)
+
+	securityPolicy := explanation.Rules[0]
+	assert.Equal(t, plan.OutcomeAdded, securityPolicy.Outcome)
+	assert.True(t, securityPolicy.Matched)
+	assert.Equal(t, "security-policy", securityPolicy.StepID)
+	assert.Equal(t, map[string]interface{}{"security_policy": "missing"}, securityPolicy.Variables)
+
+	mfa := explanation.Rules[1]
+	assert.Equal(t, plan.OutcomeConditionFalse, mfa.Outcome)
+	assert.False(t, mfa.Matched)
+	assert.Empty(t, mfa.StepID)
+	assert.Equal(t, map[string]interface{}{"mfa_status": "disabled", "org_size": 5}, mfa.Variables)
+
+	again := explanation.Rules[2]
+	assert.Equal(t, plan.OutcomeOnceSkipped, again.Outcome)
+	assert.True(t, again.Matched)
+	assert.Contains(t, again.Detail, "add-security-md")
+
+	withRef := explanation.Rules[3]
+	assert.Equal(t, plan.OutcomeExpanded, withRef.Outcome)
+	assert.Contains(t, withRef.Detail, "sub-mapping.yaml")
+	require.Len(t, withRef.Children, 1)
+	assert.Equal(t, plan.OutcomeAdded, withRef.Children[0].Outcome)
+	assert.Equal(t, "with-ref-sub-step", withRef.Children[0].StepID)
+
+	// Every added step appears in the plan
+	stepIDs := make([]string, 0, len(remediationPlan.Steps))
+	for _, step := range remediationPlan.Steps {
+		stepIDs = append(stepIDs, step.ID)
+	}
+	assert.ElementsMatch(t, []string{"security-policy", "with-ref-sub-step"}, stepIDs)
+
+	var tree bytes.Buffer
+	require.NoError(t, plan.WriteExplanation(&tree, explanation, plan.ExplainFormatTree))
+	assert.Contains(t, tree.String(), "✓ security-policy: added step security-policy (action add-security-md)")
+	assert.Contains(t, tree.String(), "✗ mfa: skipped, condition is false")
+	assert.Contains(t, tree.String(), "condition: mfa_status == 'disabled' && org_size > 10 => false")
+	assert.Contains(t, tree.String(), "org_size = 5")
+	assert.Contains(t, tree.String(), "= security-policy-again: skipped (once)")
+	assert.Contains(t, tree.String(), "  ✓ with-ref-sub-step: added step with-ref-sub-step")
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, plan.WriteExplanation(&jsonOut, explanation, plan.ExplainFormatJSON))
+	var decoded plan.PlanExplanation
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, mappingFile, decoded.MappingFile)
+	assert.Len(t, decoded.Rules, 4)
+}
+
+func TestGenerateRemediationPlanWithExplanationMissingVariable(t *testing.T) {
+	testLibrary := setupTestLibrary(t)
+	t.Setenv("DARN_HOME", testLibrary)
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "mfa"
+    condition: "mfa_status == 'disabled'"
+    action: "enable-mfa"
+    reason: "Enable MFA"
+    parameters:
+      organization: "test-org"`)
+
+	_, explanation, err := plan.GenerateRemediationPlanWithExplanation(&darnit.Report{Findings: map[string]any{}}, mappingFile,
+		darnit.GenerateOptions{SkipDefaults: true, SkipRepoInference: true, NonInteractive: true})
+	require.Error(t, err)
+
+	// The explanation still shows which rule failed and why
+	require.Len(t, explanation.Rules, 1)
+	assert.Equal(t, plan.OutcomeError, explanation.Rules[0].Outcome)
+	assert.Equal(t, []string{"mfa_status"}, explanation.Rules[0].MissingVariables)
+
+	var tree bytes.Buffer
+	require.NoError(t, plan.WriteExplanation(&tree, explanation, plan.ExplainFormatTree))
+	assert.Contains(t, tree.String(), "mfa_status is not set")
+}
+
+func TestValidateExplainFormat(t *testing.T) {
+	assert.NoError(t, plan.ValidateExplainFormat(plan.ExplainFormatTree))
+	assert.NoError(t, plan.ValidateExplainFormat(plan.ExplainFormatJSON))
+	assert.ErrorContains(t, plan.ValidateExplainFormat("yaml"), "unsupported explain format: yaml")
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darn/resolver"
+	. "github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/condition"
+)
+
+// DefaultItemVar is the variable holding the current element of a for_each rule
+const DefaultItemVar = "item"
+
+// itemVariable returns the name the current for_each element is exposed as
+func (r MappingRule) itemVariable() string {
+	if r.ItemVar != "" {
+		return r.ItemVar
+	}
+	return DefaultItemVar
+}
+
+// expandForEach processes a for_each rule once per element of its list. Each
+// element is exposed to the rule's condition and parameters as the item
+// variable (and its position as <item>_index). Step IDs get a suffix from
+// for_each_key or the index, and dependencies between steps of the same
+// element are rewritten to match.
+func expandForEach(rule MappingRule, plan *models.RemediationPlan,
+	combinedData map[string]interface{}, resolver *resolver.Resolver,
+	addedSteps map[string]bool, options GenerateOptions, mappingRefHistory []string,
+	conflicts *conflictSet, trace *RuleTrace) error {
+
+	evaluator, err := condition.NewCELEvaluator()
+	if err != nil {
+		trace.fail(err)
+		return fmt.Errorf("error creating CEL evaluator for for_each: %w", err)
+	}
+
+	items, err := evaluator.EvaluateListExpression(rule.ForEach, combinedData)
+	if err != nil {
+		trace.fail(err)
+		return fmt.Errorf("error evaluating for_each for rule %s: %w", rule.ID, err)
+	}
+	trace.recordExpansion(fmt.Sprintf("for_each over %d items", len(items)))
+
+	if options.VerboseLogging {
+		fmt.Printf("Expanding rule %s over %d items\n", rule.ID, len(items))
+	}
+
+	itemVar := rule.itemVariable()
+	seenKeys := make(map[string]bool)
+
+	for i, item := range items {
+		// Item data: everything from the report plus the current element
+		itemData := make(map[string]interface{}, len(combinedData)+2)
+		for k, v := range combinedData {
+			itemData[k] = v
+		}
+		itemData[itemVar] = item
+		itemData[itemVar+"_index"] = i
+
+		key := strconv.Itoa(i)
+		if rule.ForEachKey != "" {
+			key, err = evaluator.EvaluateStringExpression(rule.ForEachKey, itemData)
+			if err != nil {
+				trace.fail(err)
+				return fmt.Errorf("error evaluating for_each_key for rule %s: %w", rule.ID, err)
+			}
+			key = sanitizeName(key, strconv.Itoa(i))
+		}
+
+		if seenKeys[key] {
+			err := fmt.Errorf("for_each_key produced duplicate key %q", key)
+			trace.fail(err)
+			return fmt.Errorf("error expanding rule %s: %w", rule.ID, err)
+		}
+		seenKeys[key] = true
+
+		itemRule := instantiateForEachRule(rule, key)
+		if err := processMappingRule(itemRule, plan, itemData, resolver,
+			addedSteps, options, mappingRefHistory, conflicts, trace.child()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// instantiateForEachRule returns a copy of a for_each rule for one element.
+// The rule and all of its nested steps get the key appended to their IDs, and
+// dependencies on those IDs are rewritten; dependencies on steps outside the
+// expansion are left alone.
+func instantiateForEachRule(rule MappingRule, key string) MappingRule {
+	internalIDs := make(map[string]bool)
+	collectRuleIDs(rule, internalIDs)
+
+	instance := rewriteRuleIDs(rule, key, internalIDs)
+	instance.ForEach = ""
+	instance.ForEachKey = ""
+	instance.ItemVar = ""
+	return instance
+}
+
+// collectRuleIDs gathers the IDs of a rule and its nested steps
+func collectRuleIDs(rule MappingRule, ids map[string]bool) {
+	if rule.ID != "" {
+		ids[rule.ID] = true
+	}
+	for _, step := range rule.Steps {
+		collectRuleIDs(step, ids)
+	}
+}
+
+// rewriteRuleIDs copies a rule, suffixing its IDs and internal dependencies with key
+func rewriteRuleIDs(rule MappingRule, key string, internalIDs map[string]bool) MappingRule {
+	instance := rule
+
+	if instance.ID != "" {
+		instance.ID = fmt.Sprintf("%s-%s", rule.ID, key)
+	}
+
+	if len(rule.DependsOn) > 0 {
+		instance.DependsOn = make([]string, len(rule.DependsOn))
+		for i, dep := range rule.DependsOn {
+			if internalIDs[dep] {
+				dep = fmt.Sprintf("%s-%s", dep, key)
+			}
+			instance.DependsOn[i] = dep
+		}
+	}
+
+	if len(rule.ConflictsWith) > 0 {
+		instance.ConflictsWith = make([]string, len(rule.ConflictsWith))
+		for i, id := range rule.ConflictsWith {
+			if internalIDs[id] {
+				id = fmt.Sprintf("%s-%s", id, key)
+			}
+			instance.ConflictsWith[i] = id
+		}
+	}
+
+	if len(rule.Steps) > 0 {
+		instance.Steps = make([]MappingRule, len(rule.Steps))
+		for i, step := range rule.Steps {
+			instance.Steps[i] = rewriteRuleIDs(step, key, internalIDs)
+		}
+	}
+
+	return instance
+}
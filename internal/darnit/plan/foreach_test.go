@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func forEachTestOptions() darnit.GenerateOptions {
+	return darnit.GenerateOptions{
+		ExtraParams:       map[string]any{"security_email": "security@example.com"},
+		SkipDefaults:      true,
+		SkipRepoInference: true,
+		NonInteractive:    true,
+	}
+}
+
+func forEachTestReport() *darnit.Report {
+	return &darnit.Report{
+		Findings: map[string]any{
+			"repositories": []any{
+				map[string]any{"name": "api", "org": "acme", "mfa": "disabled"},
+				map[string]any{"name": "web", "org": "acme", "mfa": "enabled"},
+				map[string]any{"name": "cli/tool", "org": "tools", "mfa": "disabled"},
+			},
+		},
+	}
+}
+
+func stepsByID(p *models.RemediationPlan) map[string]models.RemediationStep {
+	steps := make(map[string]models.RemediationStep, len(p.Steps))
+	for _, step := range p.Steps {
+		steps[step.ID] = step
+	}
+	return steps
+}
+
+func TestForEachExpandsByIndex(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "mfa"
+    for_each: "repositories"
+    condition: "item.mfa == 'disabled'"
+    action: "enable-mfa"
+    reason: "Enable MFA for {{.item.name}}"
+    parameters:
+      organization: "{{.item.org}}"`)
+
+	remediationPlan, err := plan.GenerateRemediationPlan(forEachTestReport(), mappingFile, forEachTestOptions())
+	require.NoError(t, err)
+
+	steps := stepsByID(remediationPlan)
+	require.Len(t, steps, 2)
+	assert.Equal(t, "acme", steps["mfa-0"].Params["organization"])
+	assert.Equal(t, "tools", steps["mfa-2"].Params["organization"])
+	assert.NotContains(t, steps, "mfa-1")
+}
+
+func TestForEachKeyAndDependencies(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "baseline"
+    action: "add-security-md"
+    reason: "Add security documentation"
+    parameters:
+      name: "baseline"
+      emails: ["{{.security_email}}"]
+  - id: "repo"
+    for_each: "repositories.filter(r, r.mfa == 'disabled')"
+    for_each_key: "r.name"
+    item_var: "r"
+    steps:
+      - id: "docs"
+        action: "add-security-md"
+        depends_on: ["baseline"]
+        parameters:
+          name: "{{.r.name}}"
+          emails: ["{{.security_email}}"]
+      - id: "mfa"
+        action: "enable-mfa"
+        depends_on: ["docs"]
+        parameters:
+          organization: "{{.r.org}}"`)
+
+	remediationPlan, err := plan.GenerateRemediationPlan(forEachTestReport(), mappingFile, forEachTestOptions())
+	require.NoError(t, err)
+
+	steps := stepsByID(remediationPlan)
+	require.Len(t, steps, 5)
+
+	// Dependencies inside one expansion follow the renamed step, outside ones are kept
+	assert.Equal(t, []string{"baseline"}, steps["docs-api"].DependsOn)
+	assert.Equal(t, []string{"docs-api"}, steps["mfa-api"].DependsOn)
+	assert.Equal(t, "api", steps["docs-api"].Params["name"])
+
+	// Keys are sanitized into valid step IDs
+	assert.Equal(t, []string{"docs-cli-tool"}, steps["mfa-cli-tool"].DependsOn)
+	assert.Equal(t, "tools", steps["mfa-cli-tool"].Params["organization"])
+}
+
+func TestForEachErrors(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	tests := []struct {
+		name    string
+		mapping string
+		errMsg  string
+	}{
+		{
+			name: "not a list",
+			mapping: `mappings:
+  - id: "mfa"
+    for_each: "'acme'"
+    action: "enable-mfa"
+    parameters:
+      organization: "{{.item}}"`,
+			errMsg: "error evaluating for_each for rule mfa",
+		},
+		{
+			name: "duplicate key",
+			mapping: `mappings:
+  - id: "mfa"
+    for_each: "repositories"
+    for_each_key: "item.org"
+    action: "enable-mfa"
+    parameters:
+      organization: "{{.item.org}}"`,
+			errMsg: `duplicate key "acme"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappingFile := setupTestMappingFile(t, tt.mapping)
+			_, err := plan.GenerateRemediationPlan(forEachTestReport(), mappingFile, forEachTestOptions())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestForEachExplanation(t *testing.T) {
+	t.Setenv("DARN_HOME", setupTestLibrary(t))
+
+	mappingFile := setupTestMappingFile(t, `mappings:
+  - id: "mfa"
+    for_each: "repositories"
+    condition: "item.mfa == 'disabled'"
+    action: "enable-mfa"
+    parameters:
+      organization: "{{.item.org}}"`)
+
+	_, explanation, err := plan.GenerateRemediationPlanWithExplanation(forEachTestReport(), mappingFile, forEachTestOptions())
+	require.NoError(t, err)
+
+	require.Len(t, explanation.Rules, 1)
+	rule := explanation.Rules[0]
+	assert.Equal(t, plan.OutcomeExpanded, rule.Outcome)
+	assert.Equal(t, "for_each over 3 items", rule.Detail)
+	require.Len(t, rule.Children, 3)
+	assert.Equal(t, plan.OutcomeAdded, rule.Children[0].Outcome)
+	assert.Equal(t, "mfa-0", rule.Children[0].StepID)
+	assert.Equal(t, plan.OutcomeConditionFalse, rule.Children[1].Outcome)
+}
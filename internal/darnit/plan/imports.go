@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kusari-oss/darn/internal/core/vfs"
+	"github.com/kusari-oss/darn/internal/defaults"
+)
+
+// embeddedMappingsDir holds the mappings embedded in darn, which are looked
+// up after the library's when builtin defaults are in use
+var embeddedMappingsDir = filepath.Join(defaults.EmbeddedLibraryPath, "mappings")
+
+// MappingOverride patches an imported rule, found by ID
+type MappingOverride struct {
+	ID        string  `yaml:"id" json:"id"`
+	Condition *string `yaml:"condition,omitempty" json:"condition,omitempty"` // Replaces the rule's condition ("" removes it)
+	// Merged into the rule's parameters; a null value removes the parameter
+	Parameters map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Disabled   bool                   `yaml:"disabled,omitempty" json:"disabled,omitempty"` // Removes the rule
+}
+
+// ResolveMappingConfig loads a mapping file and merges in the files it
+// imports. Imported rules come first, in import order, followed by the file's
+// own rules; a local rule with the same ID as an imported rule replaces it.
+// Overrides are then applied to the imported rules. Imports are looked up
+// relative to the importing file, then in mappingsDir and then, when builtin
+// is set, in the embedded defaults (see resolver.Resolver.UsesBuiltinDefaults).
+// A filePath that does not exist is looked up the same way.
+func ResolveMappingConfig(filePath, mappingsDir string, builtin bool) (*MappingConfig, error) {
+	resolvedPath, err := findMappingFile(filePath, builtin, "", mappingsDir)
+	if err != nil {
+		return nil, err
+	}
+	return resolveMappingConfig(resolvedPath, mappingsDir, builtin, nil)
+}
+
+// findMappingFile locates a mapping in the first of dirs that has it ("" is
+// the current directory), falling back to the embedded defaults when builtin
+// is set
+func findMappingFile(name string, builtin bool, dirs ...string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	if builtin {
+		dirs = append(dirs, embeddedMappingsDir)
+	}
+
+	var candidates []string
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		if _, err := vfs.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return "", fmt.Errorf("mapping %s not found (looked in %s)", name, strings.Join(candidates, ", "))
+}
+
+// resolveMappingConfig implements ResolveMappingConfig, using stack to detect import cycles
+func resolveMappingConfig(filePath, mappingsDir string, builtin bool, stack []string) (*MappingConfig, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving mapping path %s: %w", filePath, err)
+	}
+	for _, seen := range stack {
+		if seen == absPath {
+			return nil, fmt.Errorf("import cycle detected: %s -> %s", strings.Join(stack, " -> "), absPath)
+		}
+	}
+	stack = append(stack, absPath)
+
+	config, err := LoadMappingConfig(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Imports) == 0 && len(config.Overrides) == 0 {
+		return config, nil
+	}
+
+	var imported []MappingRule
+	for _, importPath := range config.Imports {
+		resolvedPath, err := findMappingImport(importPath, filepath.Dir(filePath), mappingsDir, builtin)
+		if err != nil {
+			return nil, err
+		}
+
+		importedConfig, err := resolveMappingConfig(resolvedPath, mappingsDir, builtin, stack)
+		if err != nil {
+			return nil, fmt.Errorf("error importing %s: %w", importPath, err)
+		}
+		imported = mergeRules(imported, importedConfig.Mappings)
+	}
+
+	for _, override := range config.Overrides {
+		var found bool
+		imported, found = applyOverride(imported, override)
+		if !found {
+			return nil, fmt.Errorf("override for rule %s in %s matches no imported rule", override.ID, filePath)
+		}
+	}
+
+	return &MappingConfig{Mappings: mergeRules(imported, config.Mappings)}, nil
+}
+
+// findMappingImport locates an imported mapping file, looking in the embedded
+// defaults last when builtin is set
+func findMappingImport(importPath, baseDir, mappingsDir string, builtin bool) (string, error) {
+	if filepath.IsAbs(importPath) {
+		return importPath, nil
+	}
+
+	candidates := []string{filepath.Join(baseDir, importPath)}
+	if mappingsDir != "" {
+		candidates = append(candidates, filepath.Join(mappingsDir, importPath))
+	}
+	if builtin {
+		candidates = append(candidates, filepath.Join(embeddedMappingsDir, importPath))
+	}
+	for _, candidate := range candidates {
+		if _, err := vfs.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("imported mapping %s not found (looked in %s)", importPath, strings.Join(candidates, ", "))
+}
+
+// mergeRules appends rules to base, replacing rules in base that have the same ID
+func mergeRules(base, rules []MappingRule) []MappingRule {
+	merged := make([]MappingRule, len(base), len(base)+len(rules))
+	copy(merged, base)
+
+	for _, rule := range rules {
+		replaced := false
+		if rule.ID != "" {
+			for i := range merged {
+				if merged[i].ID == rule.ID {
+					merged[i] = rule
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			merged = append(merged, rule)
+		}
+	}
+
+	return merged
+}
+
+// applyOverride patches the rule with the override's ID, searching nested
+// steps as well. It reports whether a matching rule was found.
+func applyOverride(rules []MappingRule, override MappingOverride) ([]MappingRule, bool) {
+	result := make([]MappingRule, 0, len(rules))
+	found := false
+
+	for _, rule := range rules {
+		if rule.ID == override.ID {
+			found = true
+			if override.Disabled {
+				continue
+			}
+			result = append(result, overrideRule(rule, override))
+			continue
+		}
+
+		if len(rule.Steps) > 0 {
+			steps, foundInSteps := applyOverride(rule.Steps, override)
+			if foundInSteps {
+				found = true
+				rule.Steps = steps
+			}
+		}
+		result = append(result, rule)
+	}
+
+	return result, found
+}
+
+// overrideRule returns a copy of rule with the override applied
+func overrideRule(rule MappingRule, override MappingOverride) MappingRule {
+	if override.Condition != nil {
+		rule.Condition = *override.Condition
+	}
+
+	if len(override.Parameters) > 0 {
+		params := make(map[string]interface{}, len(rule.Parameters)+len(override.Parameters))
+		for k, v := range rule.Parameters {
+			params[k] = v
+		}
+		for k, v := range override.Parameters {
+			if v == nil {
+				delete(params, k)
+				continue
+			}
+			params[k] = v
+		}
+		rule.Parameters = params
+	}
+
+	return rule
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plan_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/format"
+	"github.com/kusari-oss/darn/internal/darnit/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseMapping = `mappings:
+  - id: "security-policy"
+    condition: "security_policy == 'missing'"
+    action: "add-security-md"
+    reason: "Add security documentation"
+    parameters:
+      name: "{{.project_name}}"
+      emails: ["{{.security_email}}"]
+  - id: "mfa"
+    condition: "mfa_status == 'disabled'"
+    action: "enable-mfa"
+    parameters:
+      organization: "{{.organization}}"
+  - id: "workflow"
+    steps:
+      - id: "workflow-docs"
+        action: "add-security-md"
+        parameters:
+          name: "docs"
+          emails: []
+      - id: "workflow-mfa"
+        action: "enable-mfa"
+        parameters:
+          organization: "{{.organization}}"
+`
+
+func TestResolveMappingConfigImportsAndOverrides(t *testing.T) {
+	libraryDir := t.TempDir()
+	writeTestFile(t, filepath.Join(libraryDir, "base.yaml"), baseMapping)
+
+	projectDir := t.TempDir()
+	mappingFile := filepath.Join(projectDir, "team.yaml")
+	writeTestFile(t, mappingFile, `imports:
+  - base.yaml
+overrides:
+  - id: "security-policy"
+    condition: "security_policy != 'present'"
+    parameters:
+      emails: ["security@team.example.com"]
+      name: null
+  - id: "mfa"
+    disabled: true
+  - id: "workflow-mfa"
+    parameters:
+      organization: "team-org"
+mappings:
+  - id: "workflow"
+    action: "enable-mfa"
+    parameters:
+      organization: "replaced"
+  - id: "local"
+    action: "enable-mfa"
+    parameters:
+      organization: "local-org"
+`)
+
+	// Without the library directory the import cannot be found
+	_, err := plan.ResolveMappingConfig(mappingFile, "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "imported mapping base.yaml not found")
+
+	config, err := plan.ResolveMappingConfig(mappingFile, libraryDir, false)
+	require.NoError(t, err)
+	assert.Empty(t, config.Imports)
+	assert.Empty(t, config.Overrides)
+
+	require.Len(t, config.Mappings, 3)
+
+	policy := config.Mappings[0]
+	assert.Equal(t, "security-policy", policy.ID)
+	assert.Equal(t, "security_policy != 'present'", policy.Condition)
+	assert.Equal(t, map[string]interface{}{"emails": []interface{}{"security@team.example.com"}}, policy.Parameters)
+	assert.Equal(t, "Add security documentation", policy.Reason)
+
+	// The local rule replaces the imported rule with the same ID in place
+	assert.Equal(t, "workflow", config.Mappings[1].ID)
+	assert.Equal(t, "enable-mfa", config.Mappings[1].Action)
+	assert.Empty(t, config.Mappings[1].Steps)
+
+	assert.Equal(t, "local", config.Mappings[2].ID)
+}
+
+func TestResolveMappingConfigNestedOverride(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "base.yaml"), baseMapping)
+	mappingFile := filepath.Join(dir, "team.yaml")
+	writeTestFile(t, mappingFile, `imports: ["base.yaml"]
+overrides:
+  - id: "workflow-mfa"
+    parameters:
+      organization: "team-org"
+  - id: "workflow-docs"
+    disabled: true
+mappings: []
+`)
+
+	config, err := plan.ResolveMappingConfig(mappingFile, "", false)
+	require.NoError(t, err)
+	require.Len(t, config.Mappings, 3)
+
+	workflow := config.Mappings[2]
+	require.Len(t, workflow.Steps, 1)
+	assert.Equal(t, "workflow-mfa", workflow.Steps[0].ID)
+	assert.Equal(t, "team-org", workflow.Steps[0].Parameters["organization"])
+
+	// The imported file itself is not modified
+	base, err := plan.LoadMappingConfig(filepath.Join(dir, "base.yaml"))
+	require.NoError(t, err)
+	assert.Len(t, base.Mappings[2].Steps, 2)
+}
+
+func TestResolveMappingConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "base.yaml"), baseMapping)
+	writeTestFile(t, filepath.Join(dir, "a.yaml"), "imports: [\"b.yaml\"]\nmappings: []\n")
+	writeTestFile(t, filepath.Join(dir, "b.yaml"), "imports: [\"a.yaml\"]\nmappings: []\n")
+	writeTestFile(t, filepath.Join(dir, "unknown.yaml"), `imports: ["base.yaml"]
+overrides:
+  - id: "does-not-exist"
+    disabled: true
+mappings: []
+`)
+
+	_, err := plan.ResolveMappingConfig(filepath.Join(dir, "a.yaml"), "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "import cycle detected")
+
+	_, err = plan.ResolveMappingConfig(filepath.Join(dir, "unknown.yaml"), "", false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "override for rule does-not-exist")
+}
+
+func TestResolveMappingConfigEmbeddedFallback(t *testing.T) {
+	// Mappings the library does not have come from the embedded defaults
+	config, err := plan.ResolveMappingConfig("security-md-workflow.yaml", t.TempDir(), true)
+	require.NoError(t, err)
+	require.NotEmpty(t, config.Mappings)
+
+	// The library's mappings come first, and may import embedded ones
+	libraryDir := t.TempDir()
+	writeTestFile(t, filepath.Join(libraryDir, "security-md-workflow.yaml"), "mappings:\n  - id: \"library\"\n    action: \"add-readme\"\n")
+	writeTestFile(t, filepath.Join(libraryDir, "extended.yaml"), "imports: [\"github-security-settings.yaml\"]\nmappings: []\n")
+
+	config, err = plan.ResolveMappingConfig("security-md-workflow.yaml", libraryDir, true)
+	require.NoError(t, err)
+	require.Len(t, config.Mappings, 1)
+	assert.Equal(t, "library", config.Mappings[0].ID)
+
+	config, err = plan.ResolveMappingConfig(filepath.Join(libraryDir, "extended.yaml"), libraryDir, true)
+	require.NoError(t, err)
+	assert.NotEmpty(t, config.Mappings)
+}
+
+func TestResolveMappingConfigWithoutBuiltinDefaults(t *testing.T) {
+	libraryDir := t.TempDir()
+	writeTestFile(t, filepath.Join(libraryDir, "extended.yaml"), "imports: [\"github-security-settings.yaml\"]\nmappings: []\n")
+
+	// Without builtin defaults the embedded mappings are never searched
+	_, err := plan.ResolveMappingConfig("security-md-workflow.yaml", libraryDir, false)
+	assert.ErrorContains(t, err, "mapping security-md-workflow.yaml not found (looked in security-md-workflow.yaml, "+
+		filepath.Join(libraryDir, "security-md-workflow.yaml")+")")
+
+	_, err = plan.ResolveMappingConfig(filepath.Join(libraryDir, "extended.yaml"), libraryDir, false)
+	assert.ErrorContains(t, err, "imported mapping github-security-settings.yaml not found (looked in "+
+		filepath.Join(libraryDir, "github-security-settings.yaml")+", "+filepath.Join(libraryDir, "github-security-settings.yaml")+")")
+}
+
+func TestRenderedMappingReloads(t *testing.T) {
+	libraryDir := t.TempDir()
+	writeTestFile(t, filepath.Join(libraryDir, "base.yaml"), baseMapping)
+
+	mappingFile := filepath.Join(t.TempDir(), "team.yaml")
+	writeTestFile(t, mappingFile, `imports:
+  - base.yaml
+mappings:
+  - id: "local"
+    action: "enable-mfa"
+    depends_on: ["mfa"]
+    conflicts_with: ["workflow"]
+    priority: 5
+    parameters:
+      organization: "local-org"
+`)
+
+	config, err := plan.ResolveMappingConfig(mappingFile, libraryDir, false)
+	require.NoError(t, err)
+
+	// darnit mapping render -o writes JSON or YAML by extension
+	for _, name := range []string{"rendered.json", "rendered.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			renderedFile := filepath.Join(t.TempDir(), name)
+			require.NoError(t, format.WriteFile(renderedFile, config))
+
+			if filepath.Ext(name) == ".json" {
+				data, err := os.ReadFile(renderedFile)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), `"depends_on": [`)
+				assert.NotContains(t, string(data), `"DependsOn"`)
+			}
+
+			reloaded, err := plan.LoadMappingConfig(renderedFile)
+			require.NoError(t, err)
+			assert.Equal(t, config, reloaded)
+		})
+	}
+}
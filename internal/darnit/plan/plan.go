@@ -5,44 +5,57 @@ package plan
 import (
 	"fmt"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/kusari-oss/darn/internal/core/models"
 	"github.com/kusari-oss/darn/internal/core/schema"
+	"github.com/kusari-oss/darn/internal/core/vfs"
 	"github.com/kusari-oss/darn/internal/darn/resolver"
 	. "github.com/kusari-oss/darn/internal/darnit"
 	"github.com/kusari-oss/darn/internal/darnit/condition"
 	"gopkg.in/yaml.v3"
 )
 
-var paramRegex = regexp.MustCompile(`\{\{\.([^}]+)\}\}`)
-
 // MappingRule defines a rule for mapping a finding to an action or other mapping.
 type MappingRule struct {
-	ID            string                 `yaml:"id"`
-	Condition     string                 `yaml:"condition"`
-	MappingRef    string                 `yaml:"mapping_ref,omitempty"`
-	Action        string                 `yaml:"action,omitempty"`
-	Reason        string                 `yaml:"reason"`
-	Labels        map[string][]string    `yaml:"labels,omitempty"`
-	Parameters    map[string]interface{} `yaml:"parameters,omitempty"`
-	DependsOn     []string               `yaml:"depends_on,omitempty"`
-	DependsOnExpr string                 `yaml:"depends_on_expr,omitempty"` // New field
-	Once          bool                   `yaml:"once,omitempty"`
-	Steps         []MappingRule          `yaml:"steps,omitempty"`
+	ID             string                 `yaml:"id" json:"id"`
+	Condition      string                 `yaml:"condition" json:"condition"`
+	MappingRef     string                 `yaml:"mapping_ref,omitempty" json:"mapping_ref,omitempty"`
+	Action         string                 `yaml:"action,omitempty" json:"action,omitempty"`
+	Reason         string                 `yaml:"reason" json:"reason"`
+	Labels         map[string][]string    `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Parameters     map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	DependsOn      []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	DependsOnExpr  string                 `yaml:"depends_on_expr,omitempty" json:"depends_on_expr,omitempty"` // New field
+	Once           bool                   `yaml:"once,omitempty" json:"once,omitempty"`
+	Steps          []MappingRule          `yaml:"steps,omitempty" json:"steps,omitempty"`
+	ForEach        string                 `yaml:"for_each,omitempty" json:"for_each,omitempty"`               // CEL list expression; the rule expands once per element
+	ItemVar        string                 `yaml:"item_var,omitempty" json:"item_var,omitempty"`               // Variable holding the current element (default "item")
+	ForEachKey     string                 `yaml:"for_each_key,omitempty" json:"for_each_key,omitempty"`       // CEL expression for a unique step ID suffix (default: index)
+	OnceKey        string                 `yaml:"once_key,omitempty" json:"once_key,omitempty"`               // CEL expression to deduplicate on instead of the action; implies once
+	Priority       int                    `yaml:"priority,omitempty" json:"priority,omitempty"`               // Higher priority wins conflicts (default 0)
+	ExclusiveGroup string                 `yaml:"exclusive_group,omitempty" json:"exclusive_group,omitempty"` // At most one step per group is kept
+	ConflictsWith  []string               `yaml:"conflicts_with,omitempty" json:"conflicts_with,omitempty"`   // Step IDs that cannot be in the plan together with this one
+}
+
+// onceKeyEntry returns the addedSteps entry for a once_key value, kept apart
+// from the action names recorded there
+func onceKeyEntry(key string) string {
+	return "once_key:" + key
 }
 
 // MappingConfig contains all mapping rules
 type MappingConfig struct {
-	Mappings []MappingRule `yaml:"mappings"`
+	Imports   []string          `yaml:"imports,omitempty" json:"imports,omitempty"`     // Mapping files whose rules are included before this file's rules
+	Overrides []MappingOverride `yaml:"overrides,omitempty" json:"overrides,omitempty"` // Patches applied to imported rules
+	Mappings  []MappingRule     `yaml:"mappings" json:"mappings"`
 }
 
-// LoadMappingConfig loads the mapping configuration from a file
+// LoadMappingConfig loads the mapping configuration from a file without
+// resolving its imports (see ResolveMappingConfig)
 func LoadMappingConfig(filePath string) (*MappingConfig, error) {
 	// Read the mapping file
-	data, err := os.ReadFile(filePath)
+	data, err := vfs.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error reading mapping file: %w", err)
 	}
@@ -62,25 +75,35 @@ func getRequiredParametersFromMappings(mappingConfig *MappingConfig) []string {
 	requiredParams := make(map[string]bool)
 
 	for _, rule := range mappingConfig.Mappings {
+		// addParams adds the keys a template string needs (see schema.ParameterKeys).
+		// Strings that do not parse are reported when the plan is rendered.
+		addParams := func(value string) {
+			keys, err := schema.ParameterKeys(value)
+			if err != nil {
+				return
+			}
+			for _, name := range keys {
+				// for_each rules provide their item variables during expansion
+				if rule.ForEach != "" {
+					root := strings.SplitN(name, ".", 2)[0]
+					if root == rule.itemVariable() || root == rule.itemVariable()+"_index" {
+						continue
+					}
+				}
+				requiredParams[name] = true
+			}
+		}
+
 		// Extract parameters from template strings in rule parameters
 		for _, paramValue := range rule.Parameters {
 			switch v := paramValue.(type) {
 			case string:
-				// Find all {{.param}} patterns
-				for _, match := range paramRegex.FindAllStringSubmatch(v, -1) {
-					if len(match) > 1 {
-						requiredParams[match[1]] = true
-					}
-				}
+				addParams(v)
 			case []interface{}:
 				// Check array items
 				for _, item := range v {
 					if str, ok := item.(string); ok {
-						for _, match := range paramRegex.FindAllStringSubmatch(str, -1) {
-							if len(match) > 1 {
-								requiredParams[match[1]] = true
-							}
-						}
+						addParams(str)
 					}
 				}
 			}
@@ -96,26 +119,60 @@ func getRequiredParametersFromMappings(mappingConfig *MappingConfig) []string {
 	return result
 }
 
+// prefixIDs returns ids prefixed with the ID of the rule whose mapping_ref
+// they were loaded through, as the IDs of that mapping's steps are
+func prefixIDs(prefix string, ids []string) []string {
+	if prefix == "" || len(ids) == 0 {
+		return ids
+	}
+
+	prefixed := make([]string, len(ids))
+	for i, id := range ids {
+		prefixed[i] = fmt.Sprintf("%s-%s", prefix, id)
+	}
+	return prefixed
+}
+
 // ProcessMappingRule processes a single mapping rule and adds it to the plan
 func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 	combinedData map[string]interface{}, resolver *resolver.Resolver,
 	addedSteps map[string]bool, options GenerateOptions, mappingRefHistory []string) error {
+	return processMappingRule(rule, plan, combinedData, resolver, addedSteps, options, mappingRefHistory, nil, nil)
+}
+
+// processMappingRule implements ProcessMappingRule, collecting added steps in
+// conflicts and recording each decision in trace when they are not nil
+func processMappingRule(rule MappingRule, plan *models.RemediationPlan,
+	combinedData map[string]interface{}, resolver *resolver.Resolver,
+	addedSteps map[string]bool, options GenerateOptions, mappingRefHistory []string,
+	conflicts *conflictSet, trace *RuleTrace) error {
+
+	trace.recordRule(rule, combinedData)
 
 	// Check for circular references
 	if rule.MappingRef != "" {
 		for _, ref := range mappingRefHistory {
 			if ref == rule.MappingRef {
-				return fmt.Errorf("circular mapping reference detected: %s -> %s",
+				err := fmt.Errorf("circular mapping reference detected: %s -> %s",
 					strings.Join(mappingRefHistory, " -> "), rule.MappingRef)
+				trace.fail(err)
+				return err
 			}
 		}
 	}
 
+	// Expand for_each rules into one rule per element
+	if rule.ForEach != "" {
+		return expandForEach(rule, plan, combinedData, resolver, addedSteps, options, mappingRefHistory, conflicts, trace)
+	}
+
 	// Check if rule matches using CEL expressions
 	matches, err := EvaluateRuleMatch(rule, combinedData, options)
 	if err != nil {
+		trace.fail(err)
 		return fmt.Errorf("error evaluating rule %s: %w", rule.ID, err)
 	}
+	trace.recordMatch(matches)
 
 	if !matches {
 		return nil // No match, skip this rule
@@ -128,16 +185,19 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 		}
 
 		// Construct the mapping file path
-		mappingPath := rule.MappingRef
-		if !filepath.IsAbs(mappingPath) && options.MappingsDir != "" {
-			mappingPath = filepath.Join(options.MappingsDir, mappingPath)
+		mappingPath, err := findMappingFile(rule.MappingRef, resolver.UsesBuiltinDefaults(), options.MappingsDir)
+		if err != nil {
+			trace.fail(err)
+			return fmt.Errorf("error loading referenced mapping %s: %w", rule.MappingRef, err)
 		}
 
 		// Load the referenced mapping file
-		referencedMapping, err := LoadMappingConfig(mappingPath)
+		referencedMapping, err := ResolveMappingConfig(mappingPath, options.MappingsDir, resolver.UsesBuiltinDefaults())
 		if err != nil {
+			trace.fail(err)
 			return fmt.Errorf("error loading referenced mapping %s: %w", rule.MappingRef, err)
 		}
+		trace.recordExpansion(fmt.Sprintf("mapping_ref %s (%s)", rule.MappingRef, mappingPath))
 
 		// Create new history slice with current reference
 		newHistory := append(mappingRefHistory, rule.MappingRef)
@@ -165,18 +225,12 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 						stepCopy.Parameters[k] = v
 					}
 
+					// Conflicts refer to steps of the same mapping
+					stepCopy.ConflictsWith = prefixIDs(rule.ID, stepCopy.ConflictsWith)
+
 					// Update dependencies for the step
 					if len(stepCopy.DependsOn) > 0 {
-						updatedDeps := make([]string, len(stepCopy.DependsOn))
-						for j, dep := range stepCopy.DependsOn {
-							// Prefix dependency with parent ID if needed
-							if rule.ID != "" {
-								updatedDeps[j] = fmt.Sprintf("%s-%s", rule.ID, dep)
-							} else {
-								updatedDeps[j] = dep
-							}
-						}
-						stepCopy.DependsOn = updatedDeps
+						stepCopy.DependsOn = prefixIDs(rule.ID, stepCopy.DependsOn)
 					} else if i > 0 && rule.ID != "" {
 						// If no explicit dependencies and not the first step,
 						// depend on the previous step from this mapping
@@ -187,8 +241,8 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 					}
 
 					// Process the step recursively
-					if err := ProcessMappingRule(stepCopy, plan, combinedData,
-						resolver, addedSteps, options, newHistory); err != nil {
+					if err := processMappingRule(stepCopy, plan, combinedData,
+						resolver, addedSteps, options, newHistory, conflicts, trace.child()); err != nil {
 						return err
 					}
 				}
@@ -201,10 +255,12 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 			if mapping.Action != "" {
 				actionRule := mapping
 
-				// Generate a unique ID
+				// Generate a unique ID; dependencies and conflicts refer to rules of the same mapping
 				if rule.ID != "" && actionRule.ID != "" {
 					actionRule.ID = fmt.Sprintf("%s-%s", rule.ID, actionRule.ID)
 				}
+				actionRule.DependsOn = prefixIDs(rule.ID, actionRule.DependsOn)
+				actionRule.ConflictsWith = prefixIDs(rule.ID, actionRule.ConflictsWith)
 
 				// Merge parameters
 				if actionRule.Parameters == nil {
@@ -215,8 +271,8 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 				}
 
 				// Process the action rule
-				if err := ProcessMappingRule(actionRule, plan, combinedData,
-					resolver, addedSteps, options, newHistory); err != nil {
+				if err := processMappingRule(actionRule, plan, combinedData,
+					resolver, addedSteps, options, newHistory, conflicts, trace.child()); err != nil {
 					return err
 				}
 			}
@@ -227,11 +283,13 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 
 	// Process sub-steps if available
 	if len(rule.Steps) > 0 {
+		trace.recordExpansion(fmt.Sprintf("%d steps", len(rule.Steps)))
+
 		// Process each sub-step
 		for _, subStep := range rule.Steps {
 			// Process the sub-step (no condition inheritance needed anymore)
-			if err := ProcessMappingRule(subStep, plan, combinedData, resolver,
-				addedSteps, options, mappingRefHistory); err != nil {
+			if err := processMappingRule(subStep, plan, combinedData, resolver,
+				addedSteps, options, mappingRefHistory, conflicts, trace.child()); err != nil {
 				return err
 			}
 		}
@@ -240,27 +298,91 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 
 	// If this is a regular action step, process it
 	if rule.Action == "" {
-		return fmt.Errorf("rule '%s' has no action, steps, or mapping reference", rule.ID)
+		err := fmt.Errorf("rule '%s' has no action, steps, or mapping reference", rule.ID)
+		trace.fail(err)
+		return err
 	}
 
-	// If this is a "once" rule and we've already added it, skip
-	if rule.Once && addedSteps[rule.Action] {
+	// If this is a "once" rule and we've already added it, skip. Rules are
+	// deduplicated on their action unless once_key gives another key.
+	onceKey := ""
+	dedupKey := ""
+	if rule.OnceKey != "" {
+		evaluator, err := condition.NewCELEvaluator()
+		if err != nil {
+			trace.fail(err)
+			return fmt.Errorf("error creating CEL evaluator for once_key: %w", err)
+		}
+		onceKey, err = evaluator.EvaluateStringExpression(rule.OnceKey, combinedData)
+		if err != nil {
+			trace.fail(err)
+			return fmt.Errorf("error evaluating once_key for rule %s: %w", rule.ID, err)
+		}
+		dedupKey = onceKeyEntry(onceKey)
+	} else if rule.Once {
+		dedupKey = rule.Action
+	}
+	duplicate := dedupKey != "" && addedSteps[dedupKey]
+	if duplicate {
 		if options.VerboseLogging {
-			fmt.Printf("Skipping duplicate action '%s' (once: true)\n", rule.Action)
+			if onceKey != "" {
+				fmt.Printf("Skipping duplicate once_key '%s' for rule '%s'\n", onceKey, rule.ID)
+			} else {
+				fmt.Printf("Skipping duplicate action '%s' (once: true)\n", rule.Action)
+			}
+		}
+		trace.recordOnceSkip(onceKey)
+		// The step that took the key may still lose a conflict, so with
+		// conflict resolution the duplicate's step is built and kept aside
+		if conflicts == nil {
+			return nil
 		}
+	}
+
+	step, err := buildStep(rule, combinedData, resolver, options)
+	if err != nil {
+		// A duplicate is already skipped; one that cannot be built stays so
+		if duplicate {
+			return nil
+		}
+		trace.fail(err)
+		return err
+	}
+
+	// The action (and once_key) the step takes, for "once: true" handling
+	onceKeys := []string{rule.Action}
+	if rule.OnceKey != "" {
+		onceKeys = append(onceKeys, onceKeyEntry(onceKey))
+	}
+	if duplicate {
+		conflicts.addDuplicate(rule, trace, step, dedupKey, onceKeys)
 		return nil
 	}
 
+	// Add the step to the plan and mark its keys as added
+	plan.Steps = append(plan.Steps, step)
+	for _, key := range onceKeys {
+		addedSteps[key] = true
+	}
+	conflicts.add(rule, trace, onceKeys)
+	trace.recordStep(rule.ID)
+
+	return nil
+}
+
+// buildStep builds the step for a rule's action, processing its parameters
+// and dependencies
+func buildStep(rule MappingRule, combinedData map[string]interface{}, resolver *resolver.Resolver, options GenerateOptions) (models.RemediationStep, error) {
 	// Get action schema for type-aware parameter processing
 	actionConfig, err := resolver.GetActionConfig(rule.Action)
 	if err != nil {
-		return fmt.Errorf("error getting action config for rule %s: %w", rule.ID, err)
+		return models.RemediationStep{}, fmt.Errorf("error getting action config for rule %s: %w", rule.ID, err)
 	}
 
 	// Process the parameters with schema awareness
 	processedParams, err := schema.ProcessParamsWithSchema(rule.Parameters, combinedData, actionConfig.Schema)
 	if err != nil {
-		return fmt.Errorf("error processing parameters for rule %s: %w", rule.ID, err)
+		return models.RemediationStep{}, fmt.Errorf("error processing parameters for rule %s: %w", rule.ID, err)
 	}
 
 	// Process dynamic dependencies if depends_on_expr is provided
@@ -269,13 +391,13 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 		// Create evaluator
 		evaluator, err := condition.NewCELEvaluator()
 		if err != nil {
-			return fmt.Errorf("error creating CEL evaluator for dynamic dependencies: %w", err)
+			return models.RemediationStep{}, fmt.Errorf("error creating CEL evaluator for dynamic dependencies: %w", err)
 		}
 
 		// Evaluate the expression
 		dynamicDeps, err := evaluator.EvaluateStringArrayExpression(rule.DependsOnExpr, combinedData)
 		if err != nil {
-			return fmt.Errorf("error evaluating depends_on_expr for rule %s: %w", rule.ID, err)
+			return models.RemediationStep{}, fmt.Errorf("error evaluating depends_on_expr for rule %s: %w", rule.ID, err)
 		}
 
 		// Merge static and dynamic dependencies (if both are provided)
@@ -302,19 +424,13 @@ func ProcessMappingRule(rule MappingRule, plan *models.RemediationPlan,
 		}
 	}
 
-	// Add the step to the plan
-	plan.Steps = append(plan.Steps, models.RemediationStep{
+	return models.RemediationStep{
 		ID:         rule.ID,
 		ActionName: rule.Action,
 		Params:     processedParams,
 		Reason:     rule.Reason,
 		DependsOn:  dependsOn,
-	})
-
-	// Mark this action as added (for "once: true" handling)
-	addedSteps[rule.Action] = true
-
-	return nil
+	}, nil
 }
 
 // TODO: See if we should remove this? Originally I had a custom condition evaluator. If we include Rego or similar, this function makes sense.
@@ -337,18 +453,34 @@ func EvaluateRuleMatch(rule MappingRule, data map[string]interface{}, options Ge
 
 // GenerateRemediationPlan creates a remediation plan based on the report and additional sources
 func GenerateRemediationPlan(report *Report, mappingFilePath string, options GenerateOptions) (*models.RemediationPlan, error) {
-	// Load mapping configuration
-	mappingConfig, err := LoadMappingConfig(mappingFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("error loading mapping configuration: %w", err)
-	}
+	return generateRemediationPlan(report, mappingFilePath, options, nil)
+}
+
+// GenerateRemediationPlanWithExplanation creates a remediation plan and a trace
+// of the decision made for every mapping rule. When generation fails, the
+// explanation covers the rules processed up to the failure.
+func GenerateRemediationPlanWithExplanation(report *Report, mappingFilePath string, options GenerateOptions) (*models.RemediationPlan, *PlanExplanation, error) {
+	explanation := &PlanExplanation{MappingFile: mappingFilePath}
+	plan, err := generateRemediationPlan(report, mappingFilePath, options, explanation)
+	return plan, explanation, err
+}
 
+// generateRemediationPlan implements GenerateRemediationPlan, recording rule
+// decisions in explanation when it is not nil
+func generateRemediationPlan(report *Report, mappingFilePath string, options GenerateOptions, explanation *PlanExplanation) (*models.RemediationPlan, error) {
 	// Create action resolver for schema access
-	_, resolver, err := CreateActionResolver(options.RepoPath)
+	_, resolver, err := CreateLibraryActionResolver(options.RepoPath, options.LibraryPath, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating action resolver: %w", err)
 	}
 
+	// Load mapping configuration, falling back to the embedded mappings only
+	// where the resolver would load the embedded actions
+	mappingConfig, err := ResolveMappingConfig(mappingFilePath, options.MappingsDir, resolver.UsesBuiltinDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("error loading mapping configuration: %w", err)
+	}
+
 	// Create combined data from all sources
 	combinedData := make(map[string]interface{})
 
@@ -446,15 +578,26 @@ func GenerateRemediationPlan(report *Report, mappingFilePath string, options Gen
 
 	// Keep track of steps we've already added (for "once: true" steps)
 	addedSteps := make(map[string]bool)
+	conflicts := &conflictSet{}
 
 	// Process each mapping rule
 	for _, rule := range mappingConfig.Mappings {
-		if err := ProcessMappingRule(rule, plan, combinedData, resolver,
-			addedSteps, options, []string{}); err != nil {
+		if err := processMappingRule(rule, plan, combinedData, resolver,
+			addedSteps, options, []string{}, conflicts, explanation.newRuleTrace()); err != nil {
 			return nil, err
 		}
 	}
 
+	// Drop steps that lost to a higher-priority conflicting rule
+	if err := resolveConflicts(plan, conflicts); err != nil {
+		return nil, err
+	}
+	if options.VerboseLogging {
+		for _, conflict := range plan.Conflicts {
+			fmt.Printf("Dropped step %s in favor of %s (%s)\n", conflict.StepID, conflict.WinnerID, conflict.Reason)
+		}
+	}
+
 	// Sort steps based on dependencies
 	if err := sortStepsByDependencies(plan); err != nil {
 		return nil, fmt.Errorf("error sorting steps by dependencies: %w", err)
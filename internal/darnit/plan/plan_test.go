@@ -63,13 +63,6 @@ func setupTestLibrary(t *testing.T) string {
 		"add-security-md.yaml": `# Test action
 name: "add-security-md"
 description: "Add SECURITY.md file to repository"
-parameters:
-  - name: "name"
-    type: "string"
-    required: true
-  - name: "emails"
-    type: "array"
-    required: true
 type: "cli"
 command: "echo"
 args: ["Creating SECURITY.md for {{.name}}"]`,
@@ -77,10 +70,6 @@ args: ["Creating SECURITY.md for {{.name}}"]`,
 		"enable-mfa.yaml": `# Test action
 name: "enable-mfa"
 description: "Enable MFA for organization"
-parameters:
-  - name: "organization"
-    type: "string"
-    required: true
 type: "cli"
 command: "echo"
 args: ["Enabling MFA for {{.organization}}"]`,
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit
+
+import (
+	"fmt"
+
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/models"
+)
+
+// VerifyPlanSignature checks a plan file's detached signature against the
+// trusted keys in the configuration. Without trusted keys it does nothing.
+func VerifyPlanSignature(planFile string) error {
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	verifier, err := cfg.Verifier()
+	if err != nil || verifier == nil {
+		return err
+	}
+
+	if err := verifier.VerifyFile(planFile); err != nil {
+		return fmt.Errorf("refusing to execute plan: %w", err)
+	}
+	return nil
+}
+
+// checkPlanApprovals checks a plan's approvals against the trusted keys in
+// the configuration
+func checkPlanApprovals(plan *models.RemediationPlan, required int) error {
+	cfg, err := config.LoadConfig("", "")
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	verifier, err := cfg.Verifier()
+	if err != nil {
+		return err
+	}
+	return CheckApprovals(plan, required, verifier)
+}
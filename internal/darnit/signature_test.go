@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/core/signing"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trustNewKey generates a key pair, adds its public key to trusted_keys in
+// the global configuration and returns the private key
+func trustNewKey(t *testing.T) []byte {
+	t.Helper()
+	darnDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn")
+	keyPath := filepath.Join(darnDir, "keys", "release.key")
+	_, err := signing.GenerateKey(keyPath)
+	require.NoError(t, err)
+
+	config := "use_global: true\ntrusted_keys:\n  - " + signing.PublicKeyPath(keyPath) + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "config.yaml"), []byte(config), 0644))
+
+	privateKey, err := signing.LoadPrivateKey(keyPath)
+	require.NoError(t, err)
+	return privateKey
+}
+
+func TestVerifyPlanSignature(t *testing.T) {
+	setupExecutionLibrary(t)
+
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, darnit.SavePlanToFile(approvalTestPlan(), planFile))
+
+	// Without trusted keys, signatures are not required
+	require.NoError(t, darnit.VerifyPlanSignature(planFile))
+
+	privateKey := trustNewKey(t)
+	assert.ErrorContains(t, darnit.VerifyPlanSignature(planFile), "refusing to execute plan")
+
+	_, err := signing.SignFile(planFile, privateKey)
+	require.NoError(t, err)
+	assert.NoError(t, darnit.VerifyPlanSignature(planFile))
+}
+
+func TestExecutePlanRequiresSignedLibrary(t *testing.T) {
+	setupExecutionLibrary(t)
+	privateKey := trustNewKey(t)
+	repo := t.TempDir()
+	options := models.ExecutionOptions{WorkingDir: repo, Output: io.Discard}
+
+	err := darnit.ExecutePlan(approvalTestPlan(), options)
+	require.ErrorContains(t, err, "refusing to load actions")
+	assert.NoFileExists(t, filepath.Join(repo, "README.md"))
+
+	libraryDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn", "library")
+	_, err = signing.SignLibrary(libraryDir, privateKey)
+	require.NoError(t, err)
+	require.NoError(t, darnit.ExecutePlan(approvalTestPlan(), options))
+	assert.FileExists(t, filepath.Join(repo, "README.md"))
+}
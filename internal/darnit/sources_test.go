@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package darnit_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusari-oss/darn/internal/core/action"
+	"github.com/kusari-oss/darn/internal/core/config"
+	"github.com/kusari-oss/darn/internal/core/library"
+	"github.com/kusari-oss/darn/internal/core/models"
+	"github.com/kusari-oss/darn/internal/darn/resolver"
+	"github.com/kusari-oss/darn/internal/darnit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupLibrarySources adds a corp library that shadows add-readme and
+// configures it before the default library
+func setupLibrarySources(t *testing.T) *config.Config {
+	t.Helper()
+	setupExecutionLibrary(t)
+	darnDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn")
+
+	files := map[string]string{
+		"corp/templates/readme.tmpl":   "# Corp {{.name}}\n",
+		"corp/actions/add-readme.yaml": "name: add-readme\ntype: file\ntemplate_path: readme.tmpl\ntarget_path: README.md\n",
+		"config.yaml": `use_global: true
+library_sources:
+  - path: ~/.darn/corp
+    namespace: corp
+  - path: ~/.darn/library
+    namespace: default
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(darnDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	cfg, err := config.LoadConfig("", "")
+	require.NoError(t, err)
+	return cfg
+}
+
+func TestExecutePlanWithNamespacedActions(t *testing.T) {
+	setupLibrarySources(t)
+
+	tests := []struct {
+		action string
+		readme string
+	}{
+		{"add-readme", "# Corp API\n"},
+		{"corp/add-readme", "# Corp API\n"},
+		{"default/add-readme", "# API\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			repo := t.TempDir()
+			plan := &models.RemediationPlan{
+				ProjectName: "api",
+				Steps: []models.RemediationStep{
+					{ID: "readme", ActionName: tt.action, Params: map[string]interface{}{"name": "API"}},
+					{ID: "marker", ActionName: "touch-marker"}, // Only in the default library
+				},
+			}
+			require.NoError(t, darnit.ExecutePlan(plan, models.ExecutionOptions{WorkingDir: repo, Output: io.Discard}))
+
+			readme, err := os.ReadFile(filepath.Join(repo, "README.md"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.readme, string(readme))
+			assert.FileExists(t, filepath.Join(repo, "marker.txt"))
+		})
+	}
+
+	plan := &models.RemediationPlan{
+		ProjectName: "api",
+		Steps:       []models.RemediationStep{{ID: "readme", ActionName: "other/add-readme"}},
+	}
+	err := darnit.ExecutePlan(plan, models.ExecutionOptions{WorkingDir: t.TempDir(), Output: io.Discard})
+	assert.ErrorContains(t, err, "unknown library namespace 'other'")
+}
+
+func TestListActionSources(t *testing.T) {
+	cfg := setupLibrarySources(t)
+	workingDir := t.TempDir()
+
+	factory := action.NewFactory(action.ActionContext{WorkingDir: workingDir})
+	factory.RegisterDefaultTypes()
+	r := resolver.NewResolver(factory, workingDir, cfg.UseLocal, cfg.UseGlobal, cfg.GlobalFirst, cfg.ActionsDir, cfg.LibraryPath).
+		WithSources(cfg.LibrarySources)
+
+	sources, err := r.ListActionSources()
+	require.NoError(t, err)
+
+	readme := sources["add-readme"]
+	require.Len(t, readme, 2)
+	assert.Equal(t, "corp/add-readme", readme[0].QualifiedName())
+	assert.Equal(t, "default/add-readme", readme[1].QualifiedName())
+	require.Len(t, sources["touch-marker"], 1)
+	assert.Equal(t, "default", sources["touch-marker"][0].Namespace)
+
+	actions, err := r.ListAvailableActions()
+	require.NoError(t, err)
+	assert.Len(t, actions, 3)
+
+	// The built-in defaults are listed after the configured libraries
+	sources, err = r.WithBuiltinDefaults().ListActionSources()
+	require.NoError(t, err)
+	require.Len(t, sources["add-security-md"], 1)
+	assert.Equal(t, "builtin/add-security-md", sources["add-security-md"][0].QualifiedName())
+}
+
+func TestExecutePlanWithEmbeddedDefaults(t *testing.T) {
+	// Nothing is installed: the actions and templates come from the binary
+	t.Setenv("DARN_HOME", t.TempDir())
+
+	repo := t.TempDir()
+	plan := &models.RemediationPlan{
+		ProjectName: "api",
+		Steps: []models.RemediationStep{
+			{ID: "security", ActionName: "add-security-md", Params: map[string]interface{}{"name": "API", "emails": []interface{}{"sec@example.com"}}},
+			{ID: "notes", ActionName: "builtin/create-file", Params: map[string]interface{}{"filename": "NOTES.md", "content": "notes\n", "directory": "."}},
+		},
+	}
+	require.NoError(t, darnit.ExecutePlan(plan, models.ExecutionOptions{WorkingDir: repo, Output: io.Discard}))
+
+	security, err := os.ReadFile(filepath.Join(repo, "SECURITY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(security), "# Security Policy for API")
+	assert.FileExists(t, filepath.Join(repo, "NOTES.md"))
+}
+
+func TestExecutePlanWithEmbeddedTemplateFallback(t *testing.T) {
+	setupExecutionLibrary(t)
+	darnDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn")
+
+	// A library action may use a template only the embedded defaults have
+	action := "name: add-policy\ntype: file\ntemplate_path: security.md.tmpl\ntarget_path: POLICY.md\n"
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "library", "actions", "add-policy.yaml"), []byte(action), 0644))
+
+	repo := t.TempDir()
+	plan := &models.RemediationPlan{
+		ProjectName: "api",
+		Steps:       []models.RemediationStep{{ID: "policy", ActionName: "add-policy", Params: map[string]interface{}{"name": "API", "emails": []interface{}{"sec@example.com"}}}},
+	}
+	options := models.ExecutionOptions{WorkingDir: repo, Output: io.Discard}
+
+	// With a library installed, the built-in defaults must be enabled
+	require.ErrorContains(t, darnit.ExecutePlan(plan, options), "template 'security.md.tmpl' not found")
+
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "config.yaml"), []byte("use_global: true\nuse_builtin: true\n"), 0644))
+	plan.Steps[0].Status = ""
+	require.NoError(t, darnit.ExecutePlan(plan, options))
+
+	policy, err := os.ReadFile(filepath.Join(repo, "POLICY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(policy), "# Security Policy for API")
+}
+
+func TestBuiltinDefaultsRefusedWhenChecked(t *testing.T) {
+	setupExecutionLibrary(t)
+	darnDir := filepath.Join(os.Getenv("DARN_HOME"), ".darn")
+	newPlan := func() *models.RemediationPlan {
+		return &models.RemediationPlan{
+			ProjectName: "api",
+			Steps:       []models.RemediationStep{{ID: "security", ActionName: "add-security-md", Params: map[string]interface{}{"name": "API", "emails": []interface{}{"sec@example.com"}}}},
+		}
+	}
+
+	// A locked project only uses its locked libraries
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "config.yaml"), []byte("use_global: true\nuse_builtin: true\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(darnDir, "library", "library.yaml"), []byte("name: default\nversion: 1.0.0\n"), 0644))
+	repo := t.TempDir()
+	lock, err := library.NewLock(filepath.Join(darnDir, "library"), "dev", "")
+	require.NoError(t, err)
+	_, err = lock.Save(repo)
+	require.NoError(t, err)
+
+	err = darnit.ExecutePlan(newPlan(), models.ExecutionOptions{WorkingDir: repo, Output: io.Discard})
+	assert.ErrorContains(t, err, "refusing to load built-in actions: they are not part of the locked libraries")
+
+	// With trusted keys, only signed actions are used
+	trustNewKey(t)
+	configPath := filepath.Join(darnDir, "config.yaml")
+	config, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(configPath, append(config, "use_builtin: true\n"...), 0644))
+
+	repo = t.TempDir()
+	err = darnit.ExecutePlan(newPlan(), models.ExecutionOptions{WorkingDir: repo, Output: io.Discard})
+	assert.ErrorContains(t, err, "refusing to load built-in actions: they are not signed by a trusted key")
+	assert.NoFileExists(t, filepath.Join(repo, "SECURITY.md"))
+}
@@ -15,11 +15,19 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/kusari-oss/darn/internal/core/vfs"
 )
 
-//go:embed actions/* templates/* configs/* mappings/*
+//go:embed actions/* templates/* configs/* mappings/* library.yaml
 var embeddedFiles embed.FS
 
+// EmbeddedLibraryPath is the root of the embedded defaults, served as a
+// read-only library under the "embedded" scheme (see vfs), as in
+// "embedded:/actions/add-security-md.yaml". Libraries fall back to it when
+// none is installed or the configuration enables the built-in defaults.
+var EmbeddedLibraryPath = vfs.Mount("embedded", embeddedFiles)
+
 // DefaultsConfig stores configuration for where to fetch defaults
 type DefaultsConfig struct {
 	// Base URL for remote defaults
@@ -82,6 +90,16 @@ func (m *Manager) CopyDefaults(templatesDir, actionsDir, configsDir, mappingsDir
 	return usedRemote, nil
 }
 
+// CopyManifest writes the embedded library manifest (library.yaml) to the
+// library root unless the library already has one
+func (m *Manager) CopyManifest(libraryDir string) error {
+	dstPath := filepath.Join(libraryDir, "library.yaml")
+	if _, err := os.Stat(dstPath); err == nil {
+		return nil
+	}
+	return m.copyEmbeddedFile("library.yaml", dstPath)
+}
+
 // copyEmbeddedDefaults copies files from embedded filesystem
 func (m *Manager) copyEmbeddedDefaults(templatesDir, actionsDir, configsDir, mappingsDir string) error {
 	// Copy templates